@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// a track this far off the album loudness stands out when the album plays
+// through; the spread warning allows for intentionally quiet interludes
+const (
+	albumOutlierLU = 4.0
+	albumSpreadLU  = 8.0
+)
+
+// analyzeAlbum analyzes every track, then measures the album as one
+// programme: ebur128 over the tracks played back to back, which gates the
+// whole album like a listener hears it rather than averaging track values
+func analyzeAlbum(cfg *Config, files []string, jobs int) (*AlbumReport, error) {
+	as, err := analyzeFiles(cfg, files, jobs)
+	if err != nil {
+		return nil, err
+	}
+	r := &AlbumReport{When: time.Now().Format(time.RFC3339)}
+	channels := as[0].Probe.Channels // shared by every track, else 0
+	for _, a := range as {
+		if a.Probe.Channels != channels {
+			channels = 0
+		}
+		t := AlbumTrack{File: a.File, Duration: a.Probe.Duration, TruePeakDBTP: a.Level.TruePeakDBTP}
+		if a.Loudness != nil {
+			v := a.Loudness.Integrated
+			t.Integrated = &v
+		}
+		if a.ReplayGain != nil {
+			g := a.ReplayGain.TrackGainDB
+			t.TrackGainDB = &g
+		}
+		r.Tracks = append(r.Tracks, t)
+		r.TruePeakDBTP = maxPeak([]*float64{r.TruePeakDBTP, t.TruePeakDBTP})
+	}
+	if l, err := ffmpegAlbumLoudness(cfg, files, channels); err == nil {
+		i, lra, g := l.Integrated, l.Range, replayGainRefLUFS-l.Integrated
+		r.Integrated, r.Range, r.AlbumGainDB = &i, &lra, &g
+	} else {
+		warnf("album loudness: %v", err)
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i := range r.Tracks {
+		t := &r.Tracks[i]
+		if t.Integrated == nil {
+			continue
+		}
+		lo, hi = math.Min(lo, *t.Integrated), math.Max(hi, *t.Integrated)
+		if r.Integrated == nil {
+			continue
+		}
+		d := *t.Integrated - *r.Integrated
+		t.DeviationLU = &d
+		if math.Abs(d) > albumOutlierLU {
+			r.Notes = append(r.Notes, newNote(SevWarn, "ALBUM_TRACK_OUTLIER", "%s is %+.1f LU off the album loudness; it will jump out when the album plays through.", filepath.Base(t.File), d))
+		}
+	}
+	if hi >= lo {
+		s := hi - lo
+		r.SpreadLU = &s
+		if s > albumSpreadLU {
+			r.Notes = append(r.Notes, newNote(SevWarn, "ALBUM_SPREAD", "Tracks span %.1f LU from quietest to loudest; fine for interludes, otherwise the levels need matching.", s))
+		}
+	}
+	if r.AlbumGainDB != nil && r.TruePeakDBTP != nil {
+		if tp := *r.TruePeakDBTP + *r.AlbumGainDB; tp > 0 {
+			r.Notes = append(r.Notes, newNote(SevWarn, "REPLAYGAIN_CLIPS", "Album gain %+.2f dB would put the loudest true peak at %+.2f dBTP; players without peak protection will clip.", *r.AlbumGainDB, tp))
+		}
+	}
+	r.Notes = filterNotes(r.Notes, cfg.MinSeverity)
+	return r, nil
+}
+
+// concatInputs plays inputs 0..n-1 back to back as one audio stream
+func concatInputs(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "[%d:a]", i)
+	}
+	fmt.Fprintf(&b, "concat=n=%d:v=0:a=1", n)
+	return b.String()
+}
+
+// ffmpegAlbumLoudness measures the tracks back to back with the same chain
+// a single track gets: channels (0 when the tracks differ) enables dualmono
+// for mono albums, and -mono sums stereo ones first
+func ffmpegAlbumLoudness(cfg *Config, files []string, channels int) (LUFS, error) {
+	ac := *cfg
+	if channels == 1 {
+		ac.Mono = false // already mono; nothing to sum
+	}
+	args := []string{"-hide_banner", "-nostats"}
+	for _, f := range files {
+		args = append(args, "-i", f)
+	}
+	args = append(args, "-vn", "-filter_complex", concatInputs(len(files))+","+ebur128Chain(&ac, channels), "-f", "null", "-")
+	out, _ := runCmd(cfg.FFmpegBin, rangeArgs(cfg, args)...)
+	return parseEBUR128(out)
+}
+
+func renderAlbum(cfg *Config, r *AlbumReport) (string, error) {
+	p := cfg.Precision
+	var b strings.Builder
+	switch strings.ToLower(cfg.Report) {
+	case "json", "yaml":
+		return renderJSON(cfg, r)
+	case "md":
+		fmt.Fprintf(&b, "# Album (%d tracks)\n\n", len(r.Tracks))
+		fmt.Fprintf(&b, "| Track | Duration (s) | LUFS | True Peak (dBTP) | vs album (LU) | Track gain (dB) |\n|---|---:|---:|---:|---:|---:|\n")
+		for _, t := range r.Tracks {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", filepath.Base(t.File), p.sec(t.Duration),
+				fmtOpt(t.Integrated, p.lufs), fmtOpt(t.TruePeakDBTP, p.db), fmtOpt(t.DeviationLU, p.lu), fmtOpt(t.TrackGainDB, p.lu))
+		}
+		fmt.Fprintf(&b, "\n- Album integrated: `%s LUFS` (LRA `%s LU`)\n- Album true peak: `%s dBTP`\n- Album gain: `%s dB` (ref `%.0f LUFS`)\n- Track spread: `%s LU`\n",
+			fmtOpt(r.Integrated, p.lufs), fmtOpt(r.Range, p.lufs), fmtOpt(r.TruePeakDBTP, p.db), fmtOpt(r.AlbumGainDB, p.lu), replayGainRefLUFS, fmtOpt(r.SpreadLU, p.lufs))
+		fmt.Fprintf(&b, "\n")
+		writeNotesMD(&b, r.Notes)
+		return b.String(), nil
+	}
+	fmt.Fprintf(&b, "ALBUM: %d tracks\nWhen: %s\n\n", len(r.Tracks), r.When)
+	for _, t := range r.Tracks {
+		fmt.Fprintf(&b, "  %-30s : %8ss | LUFS %8s | TP %7s dBTP | vs album %7s LU | gain %7s dB\n", filepath.Base(t.File), p.sec(t.Duration),
+			fmtOpt(t.Integrated, p.lufs), fmtOpt(t.TruePeakDBTP, p.db), fmtOpt(t.DeviationLU, p.lu), fmtOpt(t.TrackGainDB, p.lu))
+	}
+	fmt.Fprintf(&b, "\nAlbum: %s LUFS | LRA %s LU | TruePeak %s dBTP | Spread %s LU\n",
+		fmtOpt(r.Integrated, p.lufs), fmtOpt(r.Range, p.lufs), fmtOpt(r.TruePeakDBTP, p.db), fmtOpt(r.SpreadLU, p.lufs))
+	fmt.Fprintf(&b, "Album gain: %s dB (ref %.0f LUFS)\n", fmtOpt(r.AlbumGainDB, p.lu), replayGainRefLUFS)
+	writeNotesTXT(&b, r.Notes)
+	return b.String(), nil
+}
@@ -2,27 +2,78 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+// peak at or below this is treated as an all-silent file
+const silentFloorDB = -90.0
+
 func analyzeFile(cfg *Config, in string) (*Analysis, error) {
-	if _, err := os.Stat(in); err != nil {
-		return nil, err
+	t0 := time.Now()
+	if !isURL(in) {
+		if _, err := os.Stat(in); err != nil {
+			return nil, err
+		}
 	}
 	probe, err := ffprobeInfo(cfg, in)
 	if err != nil {
 		return nil, err
 	}
+	if probe.AudioStreams > 1 {
+		return analyzeStream(cfg, in, probe)
+	}
+	if cfg.Mono && probe.Channels == 1 {
+		mc := *cfg
+		mc.Mono = false
+		cfg = &mc
+	}
+	var rng *TimeRange
+	if hasRange(cfg) {
+		if cfg.Start >= probe.Duration && probe.Duration > 0 {
+			return nil, fmt.Errorf("start %gs is past the end (%gs)", cfg.Start, probe.Duration)
+		}
+		probe.Duration = rangeDuration(cfg, probe.Duration)
+		rng = &TimeRange{cfg.Start, cfg.Start + probe.Duration}
+	}
+	if len(cfg.Channels) > 0 {
+		if err := checkChannels(cfg.Channels, probe.Layout, probe.Channels); err != nil {
+			return nil, err
+		}
+	}
 
-	peak, rms, _ := ffmpegVolumedetect(cfg, in)
-	astatsMap, _ := ffmpegAstatsOverall(cfg, in, cfg.AstatsWin)
+	sp, err := ffmpegSinglePass(cfg, in, probe)
+	if err != nil {
+		return nil, fmt.Errorf("level pass: %w", err)
+	}
+	decoded, decErrs, rates := sp.Decoded, sp.DecodeErrors, sp.SampleRates
+	peak, rms := sp.PeakDB, sp.RMSDB
+	if peak <= silentFloorDB {
+		// nothing downstream can measure digital silence; say so instead of
+		// reporting a cascade of parse failures
+		notes := []Note{newNote(SevWarn, "FILE_SILENT", "File is silent (peak below %.0f dBFS); measurements skipped.", silentFloorDB)}
+		notes = filterNotes(append(notes, videoNotes(probe)...), cfg.MinSeverity)
+		return &Analysis{
+			File: in, When: time.Now().Format(time.RFC3339), Range: rng, Probe: probe, Metadata: parseMetadata(probe.Tags), Silent: true,
+			Level:   LevelStats{PeakDB: math.Max(peak, silentFloorDB), RMSDB: math.Max(rms, silentFloorDB)},
+			Decoded: decoded, DecodeErrors: decErrs, Notes: notes,
+			Filters: measurementChains(cfg, in, probe, true),
+			Elapsed: time.Since(t0),
+		}, nil
+	}
+	astatsMap, astatsCh := sp.Astats, sp.AstatsCh
 	lv := LevelStats{
 		PeakDB: peak, RMSDB: rms, CrestDB: peak - rms,
 		DCOffset: astatsMap["dc_offset"], ZeroXRate: astatsMap["zero_crossings_rate"],
 		NoiseFloor: astatsMap["noise_floor"],
 	}
+	if v, ok := astatsMap["bit_depth"]; ok && v > 0 {
+		lv.EffectiveBits = &v
+	}
 	if v, ok := astatsMap["number_of_clipped_samples"]; ok {
 		c := int64(v)
 		lv.ClipSamples = &c
@@ -33,30 +84,207 @@ func analyzeFile(cfg *Config, in string) (*Analysis, error) {
 		}
 	}
 	lv.HeadroomDB = 0 - lv.PeakDB
+	if r := pinnedRatio(astatsCh); r != nil {
+		lv.PinnedRatio = r
+		lv.Brickwalled = *r > brickwallRatio && lv.PeakDB > -1.5
+	}
+	lv.PerChannel = channelStats(astatsCh)
+
+	// every pass below is an independent decode of the input; run them all
+	// at once and let -max-procs bound how many ffmpeg/aubio children that
+	// actually means. Each goroutine writes only its own variables.
+	var wg sync.WaitGroup
+	run := func(f func()) {
+		wg.Add(1)
+		go func() { defer wg.Done(); f() }()
+	}
 
-	var lufs *LUFS
-	if cfg.UseEBUR128 {
-		if v, err := ffmpegEBUR128(cfg, in); err == nil {
-			lufs = &v
-			if v.TruePeak != nil {
-				lv.TruePeakDBTP = v.TruePeak
+	var chanTPs []*float64
+	run(func() { chanTPs, _ = ffmpegChannelTruePeaks(cfg, in, probe.SampleRate) })
+	var drWins []WindowStat
+	run(func() { drWins, _ = windowedAstats(cfg, in, probe.SampleRate, drWindowSec) })
+	var windows []WindowStat
+	if cfg.AstatsWin > 0 {
+		run(func() { windows, _ = windowedAstats(cfg, in, probe.SampleRate, cfg.AstatsWin) })
+	}
+	// -clicks grades the events of the defect scan; the Defects section
+	// itself is only reported with -defects
+	var defects *Defects
+	if cfg.Defects || cfg.UseClicks {
+		run(func() {
+			var err error
+			if defects, err = scanDefects(cfg, in, probe.SampleRate, probe.Channels); err != nil {
+				warnf("%s: defects: %v", in, err)
+			}
+		})
+	}
+	lufs := sp.Loudness
+	var dialog *float64
+	if cfg.UseEBUR128 && cfg.DialogGate {
+		run(func() {
+			if d, err := ffmpegDialogLoudness(cfg, in, probe.Channels); err == nil {
+				dialog = &d
 			}
+		})
+	}
+	var spec SpectralStats
+	run(func() { spec, _ = ffmpegSpectral(cfg, in) })
+	var ltSpec []float64
+	run(func() { ltSpec, _ = longTermSpectrum(cfg, in, 0, spectrumFFT) })
+	var hum *Hum
+	run(func() {
+		if db, err := longTermSpectrum(cfg, in, humRate, humFFT); err == nil {
+			hum = detectHum(db)
 		}
+	})
+	var content *Content
+	run(func() {
+		var err error
+		if content, err = classifyContent(cfg, in); err != nil {
+			warnf("%s: classify: %v", in, err)
+		}
+	})
+	var tilt *float64
+	if cfg.TiltFreq > 0 {
+		run(func() { tilt, _ = ffmpegTilt(cfg, in, cfg.TiltFreq) })
+	}
+	var subset *SubsetStats
+	if len(cfg.Channels) > 0 {
+		run(func() { subset, _ = ffmpegChannelSubset(cfg, in, cfg.Channels) })
 	}
+	var st StereoStats
+	var monoPeak *float64
+	var corrPts []CorrPoint
+	var scope *PhaseScope
+	// the stereo passes address FL/FR, so they only run on two-channel
+	// input; 3+ channels get the surround figures and a downmix pass instead
+	names := channelNames(probe.Layout, probe.Channels)
+	surround := !cfg.Mono && probe.Channels > 2
+	var downmix *Downmix
+	if !cfg.Mono && probe.Channels == 2 {
+		run(func() { st, _ = ffmpegStereoStuff(cfg, in) })
+		if cfg.CorrWin > 0 {
+			run(func() { corrPts, _ = corrTimeline(cfg, in, probe.SampleRate, cfg.CorrWin) })
+		}
+		run(func() {
+			if pk, err := ffmpegMonoSumPeak(cfg, in); err == nil {
+				monoPeak = &pk
+			}
+		})
+		if cfg.PhaseScope {
+			run(func() { scope, _ = phaseScope(cfg, in, phaseScopeBins) })
+		}
+	}
+	if surround && names != nil {
+		run(func() { downmix, _ = ffmpegDownmix(cfg, in, names) })
+	}
+	var bands []BandStat
+	if cfg.UseBands && len(cfg.Bands) > 0 {
+		run(func() { bands, _ = ffmpegBands(cfg, in, cfg.Bands, probe.Channels) })
+	}
+	sil := sp.Silences
 
-	spec, _ := ffmpegSpectral(cfg, in)
-	st, _ := ffmpegStereoStuff(cfg, in)
+	var (
+		series []float64
+		bpmErr error
+		onr    *float64
+		events int
+		ps     *PitchStats
+		key    *KeyInfo
+		useAub = strings.ToLower(cfg.BPMEngine) == "aubio"
+	)
+	ain := in
+	if hasRange(cfg) && (mustHave(cfg.AubioBin) == nil || cfg.Fingerprint) {
+		if f, remove, err := cutRange(cfg, in); err == nil {
+			defer remove()
+			ain = f
+		}
+	}
+	if useAub {
+		run(func() { series, bpmErr = aubioBPMSeries(cfg, ain) })
+		run(func() { onr, events, _ = aubioOnsetRate(cfg, ain, probe.Duration) })
+	}
+	run(func() { ps, _ = aubioPitchStats(cfg, ain) })
+	var fp *Fingerprint
+	if cfg.Fingerprint {
+		run(func() { fp = fingerprint(cfg, ain) })
+	}
+	run(func() {
+		if k, err := aubioKey(cfg, ain); err == nil {
+			key = k
+		}
+	})
+	wg.Wait()
 
-	var bands []BandStat
-	if cfg.UseBands {
-		for _, b := range cfg.Bands {
-			if p, r, err := ffmpegBandLoudness(cfg, in, b); err == nil {
-				bands = append(bands, BandStat{Band: b, PeakDB: p, RMSDB: r})
-			}
+	for i := range lv.PerChannel {
+		if i < len(chanTPs) {
+			lv.PerChannel[i].TruePeakDBTP = chanTPs[i]
+		}
+		if !cfg.Mono && i < len(names) {
+			lv.PerChannel[i].Name = names[i]
+		}
+	}
+	if cfg.UseClicks && defects != nil {
+		n := int64(defects.clickEvents)
+		lv.Clicks = &n
+		if dur := rangeDuration(cfg, probe.Duration); dur > 0 {
+			cpm := float64(n) / (dur / 60.0)
+			g := clickGrade(cpm)
+			lv.ClicksPerMin, lv.ClickGrade = &cpm, &g
 		}
 	}
+	if !cfg.Defects {
+		defects = nil
+	}
+	var sections []SectionLoudness
+	var structure []Section
+	if v := lufs; v != nil {
+		v.Standard = cfg.LoudnessStd
+		if cfg.LUFSTarget != 0 {
+			t := cfg.LUFSTarget
+			rel := v.Integrated - t
+			v.Target, v.Relative = &t, &rel
+		}
+		v.Dialog = dialog
+		v.Timeline, v.MaxMomentary, v.MaxShortTerm = loudnessTimeline(v.frames)
+		lv.SustainedPeakRatio = sustainedRatio(v.frames, 1.0)
+		sections = sectionLoudness(v.frames, probe.Duration, cfg.HeadFrac, cfg.TailFrac)
+		structure = structureSections(v.frames, cfg.StructLU)
+		if v.TruePeak != nil {
+			lv.TruePeakDBTP, lv.TruePeakEngine = v.TruePeak, "ebur128"
+		}
+	}
+	// -no-ebur128, a peak mode without true peak, or an ffmpeg without the
+	// filter: fall back to the oversampled per-channel estimate
+	if lv.TruePeakDBTP == nil {
+		if tp := maxPeak(chanTPs); tp != nil {
+			lv.TruePeakDBTP, lv.TruePeakEngine = tp, fmt.Sprintf("oversample%dx", cfg.Oversample)
+		}
+	}
+	if lv.TruePeakDBTP != nil {
+		tc := *lv.TruePeakDBTP - lv.RMSDB
+		lv.TrueCrestDB = &tc
+	}
+	var sur *Surround
+	if surround {
+		sur = surroundStats(probe.Layout, names, lv.PerChannel, downmix, lufs)
+	}
+	peakToLoudness(&lv, lufs)
+	lv.DR = drScore(drWins)
+	spec.TiltDB = tilt
+	if ltSpec != nil {
+		spec.CutoffHz = spectralCutoff(ltSpec, probe.SampleRate)
+	}
+	res := resolution(probe, lv, spec.CutoffHz)
+	st.MonoPeakDB = monoPeak
+	if !cfg.Mono && probe.Channels == 2 && len(lv.PerChannel) == 2 {
+		b := lv.PerChannel[0].RMSDB - lv.PerChannel[1].RMSDB
+		st.BalanceDB = &b
+		st.Fault = stereoFault(st, lv.PerChannel)
+	}
+	corrSummary(&st, corrPts, cfg.CorrWin, cfg.CorrThresh)
+	monoSafety := monoSafetyGrade(bands)
 
-	sil, _ := detectSilences(cfg, in)
 	var silRatio *float64
 	var silTotal *float64
 	if len(sil) > 0 {
@@ -72,42 +300,292 @@ func analyzeFile(cfg *Config, in string) (*Analysis, error) {
 	}
 
 	var tempo *TempoStats
-	if strings.ToLower(cfg.BPMEngine) == "aubio" {
-		if series, err := aubioBPMSeries(cfg, in); err == nil {
-			med := series[len(series)/2]
-			mu := mean(series)
-			sd := stddev(series, mu)
-			onr, events, _ := aubioOnsetRate(cfg, in, probe.Duration)
-			tempo = &TempoStats{
-				BPMMedian: &med, BPMMean: &mu, BPMStd: &sd, Events: events, OnsetPerMin: onr,
-			}
+	if useAub && bpmErr == nil {
+		med := series[len(series)/2]
+		mu := mean(series)
+		sd := stddev(series, mu)
+		tempo = &TempoStats{
+			BPMMedian: &med, BPMMean: &mu, BPMStd: &sd, Events: events, OnsetPerMin: onr,
 		}
 	}
 
-	ps, _ := aubioPitchStats(cfg, in)
-	var key *KeyInfo
-	if k, err := aubioKey(cfg, in); err == nil {
-		key = k
-	}
-
-	var notes []string
+	var notes []Note
 	if lv.ClipSamples != nil && *lv.ClipSamples > 0 {
-		notes = append(notes, fmt.Sprintf("Clipping detected: %d samples (%.3f%%)", *lv.ClipSamples, derefFloat(lv.ClipPercent)))
+		notes = append(notes, newNote(SevWarn, "CLIPPING", "Clipping detected: %d samples (%.3f%%)", *lv.ClipSamples, derefFloat(lv.ClipPercent)))
 	}
-	if lv.TruePeakDBTP != nil && *lv.TruePeakDBTP > -1.0 {
-		notes = append(notes, fmt.Sprintf("True peak dangerously high (%.2f dBTP). Consider -1.5 dBTP ceiling.", *lv.TruePeakDBTP))
+	if ceil, _ := deliveryCeiling(cfg); lv.TruePeakDBTP != nil && *lv.TruePeakDBTP > ceil {
+		msg := truePeakAdvice(cfg, *lv.TruePeakDBTP)
+		if over := overChannels(lv.PerChannel, ceil); len(over) > 0 && len(over) < len(lv.PerChannel) {
+			msg += fmt.Sprintf(" Over on channel(s) %s.", strings.Join(over, ", "))
+		}
+		notes = append(notes, newNote(SevWarn, "TRUE_PEAK_HIGH", "%s", msg))
 	}
 	if spec.Flatness != nil && *spec.Flatness > 0.5 {
-		notes = append(notes, "High spectral flatness → noise-like content.")
+		notes = append(notes, newNote(SevInfo, "NOISE_LIKE", "High spectral flatness → noise-like content."))
+	}
+	notes = append(notes, stereoFaultNotes(st)...)
+	if st.Correlation != nil && *st.Correlation < 0.2 && st.Fault == "" {
+		notes = append(notes, newNote(SevWarn, "LOW_CORRELATION", "Low L/R correlation → wide or phasey stereo."))
+	}
+	if st.Fault == "" {
+		notes = append(notes, corrNotes(st, cfg.CorrThresh)...)
+	}
+	if b := st.BalanceDB; b != nil && cfg.BalanceDB > 0 && math.Abs(*b) > cfg.BalanceDB && !strings.HasSuffix(st.Fault, "-silent") {
+		side := "left"
+		if *b < 0 {
+			side = "right"
+		}
+		notes = append(notes, newNote(SevWarn, "LR_IMBALANCE", "Mix leans %s: L/R RMS differ by %.1f dB (threshold %.1f).", side, math.Abs(*b), cfg.BalanceDB))
+	}
+	if st.MonoPeakDB != nil && *st.MonoPeakDB > 0 {
+		notes = append(notes, newNote(SevWarn, "MONO_OVERS", "Mono sum (L+R) peaks at %+.2f dBFS and clips; check before mono playback (phones, club subs, AM).", *st.MonoPeakDB))
+	}
+	if lv.Brickwalled {
+		notes = append(notes, newNote(SevWarn, "BRICKWALLED", "Brickwall limiting: %.3f%% of samples pinned at the %.2f dBFS ceiling.", *lv.PinnedRatio*100, lv.PeakDB))
+	}
+	if lufs != nil {
+		if n, ok := normalizedNote(lufs.Integrated, lv.TruePeakDBTP); ok {
+			notes = append(notes, n)
+		}
+	}
+	if g := probe.OpusOutputGainDB; g != nil && *g != 0 {
+		notes = append(notes, newNote(SevInfo, "OPUS_OUTPUT_GAIN", "Opus header output gain %+.2f dB is applied on decode; loudness above includes it.", *g))
 	}
-	if st.Correlation != nil && *st.Correlation < 0.2 {
-		notes = append(notes, "Low L/R correlation → wide or phasey stereo.")
+	rg := replayGain(lufs, lv)
+	notes = append(notes, replayGainNotes(rg)...)
+	pt := platformTarget(cfg, lufs, lv)
+	notes = append(notes, platformNotes(pt)...)
+	notes = append(notes, surroundNotes(sur, names != nil)...)
+	notes = append(notes, sectionNotes(sections)...)
+	if monoSafety == "unsafe" {
+		notes = append(notes, newNote(SevWarn, "MONO_UNSAFE_LOWS", "Low bands are poorly correlated; bass will cancel in mono (vinyl cutting, club systems)."))
 	}
+	notes = append(notes, bandWidthNotes(bands)...)
+	notes = append(notes, bitDepthNotes(probe, lv)...)
+	notes = append(notes, dcNotes(lv.PerChannel, cfg.DCThreshold)...)
+	notes = append(notes, lossyNotes(probe, spec.CutoffHz)...)
+	notes = append(notes, resolutionNotes(res)...)
+	notes = append(notes, humNotes(hum)...)
+	notes = append(notes, defectNotes(defects)...)
+	notes = append(notes, videoNotes(probe)...)
+	if decoded != nil && probe.Duration > 0 {
+		if gap := probe.Duration - *decoded; gap > math.Max(0.5, 0.01*probe.Duration) {
+			notes = append(notes, newNote(SevError, "TRUNCATED", "Decoded only %.2fs of declared %.2fs; file looks truncated or corrupt.", *decoded, probe.Duration))
+		}
+	}
+	if len(rates) > 1 {
+		notes = append(notes, newNote(SevWarn, "SAMPLE_RATE_CHANGES", "Sample rate changes midstream (%s Hz); levels and loudness across the change may be unreliable.", joinInts(rates, " → ")))
+	}
+	if decErrs > 0 {
+		notes = append(notes, newNote(SevWarn, "DECODE_ERRORS", "Decoder reported %d errors; parts of the file may be damaged.", decErrs))
+	}
+	notes = filterNotes(notes, cfg.MinSeverity)
 
 	return &Analysis{
-		File: in, When: time.Now().Format(time.RFC3339),
-		Probe: probe, Level: lv, Loudness: lufs, Stereo: st, Spectral: spec,
-		Bands: bands, Tempo: tempo, Pitch: ps, Key: key,
-		Silence: sil, SilenceRatio: silRatio, SilenceTotal: silTotal, Notes: notes,
+		File: in, When: time.Now().Format(time.RFC3339), Range: rng,
+		Probe: probe, Metadata: parseMetadata(probe.Tags), Mono: cfg.Mono, Level: lv, Loudness: lufs, ReplayGain: rg, Target: pt, Sections: sections, Structure: structure, Subset: subset, Stereo: st, Surround: sur, PhaseScope: scope, Spectral: spec,
+		Bands: bands, MonoSafety: monoSafety, Tempo: tempo, Pitch: ps, Key: key, Fingerprint: fp, Resolution: res, Hum: hum, Defects: defects, Content: content,
+		Windows: windows, Silence: sil, SilenceRatio: silRatio, SilenceTotal: silTotal, Notes: notes,
+		Decoded: decoded, DecodeErrors: decErrs, SampleRates: rates,
+		Filters: measurementChains(cfg, in, probe, false),
+		Elapsed: time.Since(t0),
 	}, nil
 }
+
+// share of samples at the ceiling above which a master counts as brickwalled
+const brickwallRatio = 0.0005
+
+// pinnedRatio is the share of samples sitting at each channel's min/max level,
+// from astats' per-channel "Peak count" and "Number of samples"
+func pinnedRatio(chans []map[string]float64) *float64 {
+	var peaks, total float64
+	for _, ch := range chans {
+		pc, ok1 := ch["peak_count"]
+		n, ok2 := ch["number_of_samples"]
+		if !ok1 || !ok2 {
+			continue
+		}
+		peaks += pc
+		total += n
+	}
+	if total <= 0 {
+		return nil
+	}
+	r := peaks / total
+	return &r
+}
+
+// sustainedRatio is the share of frames whose short-term loudness is within
+// tolDB of the loudest short-term frame; gated frames (< -70 LUFS) are ignored
+func sustainedRatio(frames []loudnessFrame, tolDB float64) *float64 {
+	maxS := math.Inf(-1)
+	var valid []float64
+	for _, f := range frames {
+		if f.S > -70 {
+			valid = append(valid, f.S)
+			maxS = math.Max(maxS, f.S)
+		}
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	n := 0
+	for _, v := range valid {
+		if v >= maxS-tolDB {
+			n++
+		}
+	}
+	r := float64(n) / float64(len(valid))
+	return &r
+}
+
+// rough vinyl transfer grade from click density
+func clickGrade(perMin float64) string {
+	switch {
+	case perMin < 5:
+		return "clean"
+	case perMin < 30:
+		return "light"
+	default:
+		return "heavy"
+	}
+}
+
+var losslessCodecs = map[string]bool{
+	"flac": true, "alac": true, "wavpack": true, "ape": true, "tta": true, "mlp": true, "truehd": true,
+}
+
+// common delivery targets (streaming -14/-16, broadcast -23/-24)
+var normTargets = []float64{-14, -16, -23, -24}
+
+// normalizedNote flags integrated loudness sitting within 0.3 LU of a known
+// target while the true peak is held at or under -1 dBTP (with 0.1 dB slack),
+// the combination a normalizer+limiter leaves behind
+func normalizedNote(integrated float64, tp *float64) (Note, bool) {
+	if tp == nil || *tp > -0.9 {
+		return Note{}, false
+	}
+	for _, t := range normTargets {
+		if math.Abs(integrated-t) <= 0.3 {
+			return newNote(SevInfo, "ALREADY_NORMALIZED", "Integrated %.1f LUFS with true peak %.2f dBTP looks already normalized to %.0f LUFS; avoid normalizing again.", integrated, *tp, t), true
+		}
+	}
+	return Note{}, false
+}
+
+// bands ending at or below this are "low" for the mono safety grade
+const monoSafetyMaxHz = 250
+
+// monoSafetyGrade grades the worst low-band correlation: bass should be
+// near +1 to survive vinyl cutting and mono club rigs; "" when unmeasured
+func monoSafetyGrade(bands []BandStat) string {
+	worst, seen := 1.0, false
+	for _, b := range bands {
+		if b.Band.Hi <= monoSafetyMaxHz && b.Correlation != nil {
+			worst, seen = math.Min(worst, *b.Correlation), true
+		}
+	}
+	switch {
+	case !seen:
+		return ""
+	case worst >= 0.9:
+		return "safe"
+	case worst >= 0.5:
+		return "caution"
+	}
+	return "unsafe"
+}
+
+// a band whose side is this loud against its mid is wider than a
+// speaker pair can reproduce without folding down badly in mono
+const bandWideDB = 0.0
+
+// bandWidthNotes flags the bands above the mono-safety range that carry as
+// much side as mid; low bands are graded by monoSafetyGrade already
+func bandWidthNotes(bands []BandStat) []Note {
+	var notes []Note
+	for _, b := range bands {
+		if b.Band.Hi > monoSafetyMaxHz && b.SideMidDB != nil && *b.SideMidDB >= bandWideDB {
+			notes = append(notes, newNote(SevWarn, "BAND_TOO_WIDE", "%.0f-%.0f Hz is excessively wide: side %+.1f dB against mid, correlation %s.",
+				b.Band.Lo, b.Band.Hi, *b.SideMidDB, fmtOpt(b.Correlation, func(v float64) string { return fmt.Sprintf("%.2f", v) })))
+		}
+	}
+	return notes
+}
+
+// channels whose true peak exceeds limit, as 1-based numbers
+func overChannels(chs []ChannelStats, limit float64) []string {
+	var over []string
+	for _, ch := range chs {
+		if ch.TruePeakDBTP != nil && *ch.TruePeakDBTP > limit {
+			over = append(over, strconv.Itoa(ch.Channel))
+		}
+	}
+	return over
+}
+
+// per-channel peak/RMS from the astats channel sections
+func channelStats(chans []map[string]float64) []ChannelStats {
+	// astats prints -inf for a silent channel, which parseAstats drops
+	level := func(ch map[string]float64, key string) float64 {
+		if v, ok := ch[key]; ok {
+			return math.Max(v, silentFloorDB)
+		}
+		return silentFloorDB
+	}
+	var out []ChannelStats
+	for i, ch := range chans {
+		out = append(out, ChannelStats{
+			Channel:  i + 1,
+			PeakDB:   level(ch, "peak_level_db"),
+			RMSDB:    level(ch, "rms_level_db"),
+			DCOffset: ch["dc_offset"],
+		})
+	}
+	return out
+}
+
+func isLossless(codec string) bool {
+	return losslessCodecs[codec] || strings.HasPrefix(codec, "pcm_")
+}
+
+// audio/video length difference worth flagging; packetization alone
+// accounts for a frame or two
+const avMismatchSec = 0.1
+
+// videoNotes flags an analyzed audio track that doesn't run as long as the
+// picture, which delivery QC rejects
+func videoNotes(p ProbeInfo) []Note {
+	if p.Video == nil || p.Video.Duration == nil || p.Stream >= len(p.Tracks) || p.Tracks[p.Stream].Duration == nil {
+		return nil
+	}
+	ad, vd := *p.Tracks[p.Stream].Duration, *p.Video.Duration
+	if d := ad - vd; math.Abs(d) > avMismatchSec {
+		return []Note{newNote(SevWarn, "AV_DURATION_MISMATCH", "Audio track a:%d runs %.2fs but the video %.2fs (%+.2fs); check sync and the delivery spec.", p.Stream, ad, vd, d)}
+	}
+	return nil
+}
+
+// bitDepthNotes flags declared depth/codec combinations that don't look like
+// a plausible lossless source
+func bitDepthNotes(p ProbeInfo, lv LevelStats) []Note {
+	if !isLossless(p.CodecName) {
+		return nil
+	}
+	var notes []Note
+	if p.BitDepth > 0 && p.BitDepth <= 8 {
+		notes = append(notes, newNote(SevWarn, "LOSSLESS_LOW_DEPTH", "Lossless %s at only %d-bit depth; likely a low-quality or mislabeled source.", p.CodecName, p.BitDepth))
+	}
+	if lv.EffectiveBits == nil {
+		return notes
+	}
+	eff := *lv.EffectiveBits
+	isFloat := strings.HasPrefix(p.SampleFmt, "flt") || strings.HasPrefix(p.SampleFmt, "dbl")
+	switch {
+	case isFloat && eff <= 16:
+		notes = append(notes, newNote(SevWarn, "FLOAT_UNDERUSED", "Float %s file uses only %.0f bits of range; likely 16-bit content in a float container.", p.CodecName, eff))
+	case p.BitDepth > 16 && eff <= 16:
+		notes = append(notes, newNote(SevWarn, "DEPTH_PADDED", "Declared %d-bit but only %.0f bits used; likely padded lower-resolution content.", p.BitDepth, eff))
+	}
+	return notes
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// volumedetect reads digital silence as -91 dB and astats as -inf; either
+// way the level pass must land at or below the silent floor
+func TestSinglePassSilent(t *testing.T) {
+	out, err := os.ReadFile("testdata/silent.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp, err := parseSinglePass(defaultConfig(), string(out), 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.PeakDB > silentFloorDB {
+		t.Errorf("peak %v dB, want <= %v", sp.PeakDB, silentFloorDB)
+	}
+	if _, ok := sp.Astats["peak_level_db"]; ok {
+		t.Errorf("astats kept a -inf peak: %v", sp.Astats)
+	}
+	if len(sp.Silences) != 1 || sp.Silences[0] != (SilenceSpan{0, 1}) {
+		t.Errorf("silences: %v", sp.Silences)
+	}
+	if sp.Decoded == nil || *sp.Decoded != 1 {
+		t.Errorf("decoded %v, want 1s", sp.Decoded)
+	}
+}
+
+// testdata/silent.wav is one second of digital zero, 8 kHz mono s16
+func TestAnalyzeSilentFile(t *testing.T) {
+	cfg := needFFmpeg(t)
+	if _, err := exec.LookPath(cfg.FFprobeBin); err != nil {
+		t.Skip("ffprobe not found")
+	}
+	a, err := analyzeFile(cfg, "testdata/silent.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Silent || a.Level.PeakDB != silentFloorDB {
+		t.Fatalf("silent %v, peak %v", a.Silent, a.Level.PeakDB)
+	}
+	found := false
+	for _, n := range a.Notes {
+		found = found || n.Code == "FILE_SILENT"
+	}
+	if !found {
+		t.Errorf("no FILE_SILENT note in %v", a.Notes)
+	}
+	if txt := renderTXT(cfg, a); !strings.Contains(txt, "Silent:") || strings.Contains(txt, "Levels:") {
+		t.Errorf("txt report:\n%s", txt)
+	}
+	if _, err := json.Marshal(a); err != nil {
+		t.Errorf("json: %v", err)
+	}
+}
@@ -11,11 +11,23 @@ import (
 	"strings"
 )
 
+// aubioArgs builds "<sub> -i in" plus -B/-H when buffer/hop sizes are set
+func aubioArgs(cfg *Config, sub, in string) []string {
+	args := []string{sub, "-i", in}
+	if cfg.AubioBufSize > 0 {
+		args = append(args, "-B", strconv.Itoa(cfg.AubioBufSize))
+	}
+	if cfg.AubioHopSize > 0 {
+		args = append(args, "-H", strconv.Itoa(cfg.AubioHopSize))
+	}
+	return args
+}
+
 func aubioBPMSeries(cfg *Config, in string) ([]float64, error) {
 	if err := mustHave(cfg.AubioBin); err != nil {
 		return nil, errors.New("aubio not found")
 	}
-	out, err := runCmd(cfg.AubioBin, "tempo", "-i", in)
+	out, err := runCmd(cfg.AubioBin, aubioArgs(cfg, "tempo", in)...)
 	if err != nil && out == "" {
 		return nil, fmt.Errorf("aubio tempo failed: %v", err)
 	}
@@ -37,7 +49,7 @@ func aubioOnsetRate(cfg *Config, in string, durSec float64) (*float64, int, erro
 	if err := mustHave(cfg.AubioBin); err != nil {
 		return nil, 0, errors.New("aubio not found")
 	}
-	out, _ := runCmd(cfg.AubioBin, "onset", "-i", in)
+	out, _ := runCmd(cfg.AubioBin, aubioArgs(cfg, "onset", in)...)
 	var count int
 	sc := bufio.NewScanner(strings.NewReader(out))
 	for sc.Scan() {
@@ -60,7 +72,7 @@ func aubioPitchStats(cfg *Config, in string) (*PitchStats, error) {
 	if err := mustHave(cfg.AubioBin); err != nil {
 		return nil, errors.New("aubio not found")
 	}
-	out, _ := runCmd(cfg.AubioBin, "pitch", "-i", in)
+	out, _ := runCmd(cfg.AubioBin, aubioArgs(cfg, "pitch", in)...)
 	re := regexp.MustCompile(`([0-9]+(\.[0-9]+)?)`)
 	var Hz []float64
 	sc := bufio.NewScanner(strings.NewReader(out))
@@ -98,7 +110,7 @@ func aubioKey(cfg *Config, in string) (*KeyInfo, error) {
 	if err := mustHave(cfg.AubioBin); err != nil {
 		return nil, errors.New("aubio not found")
 	}
-	out, _ := runCmd(cfg.AubioBin, "key", "-i", in)
+	out, _ := runCmd(cfg.AubioBin, aubioArgs(cfg, "key", in)...)
 	re := regexp.MustCompile(`([A-G][#b]?)\s+(major|minor|dorian|mixolydian|lydian|phrygian|locrian)?`)
 	key, scale := (*string)(nil), (*string)(nil)
 	if m := re.FindStringSubmatch(strings.ToLower(out)); len(m) >= 2 {
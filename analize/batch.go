@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+var errInterrupted = errors.New("interrupted")
+
+// runBatch calls fn for items 0..n-1 with up to workers in flight. On
+// SIGINT/SIGTERM it stops handing out items (a second signal kills as
+// usual); on an item error it stops too. done marks the items that
+// finished cleanly so callers can write a partial result either way.
+func runBatch(n, workers int, fn func(i int) error) (done []bool, err error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() { <-ctx.Done(); stop() }()
+
+	done = make([]bool, n)
+	errs := make([]error, n)
+	next := make(chan int)
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for range max(1, min(workers, n)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if ctx.Err() != nil || failed.Load() {
+					continue // after a signal the children died too; their output is garbage
+				}
+				if errs[i] = fn(i); errs[i] != nil {
+					failed.Store(true)
+				}
+				done[i] = errs[i] == nil && ctx.Err() == nil
+			}
+		}()
+	}
+	for i := 0; i < n && ctx.Err() == nil && !failed.Load(); i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return done, errInterrupted
+	}
+	for _, e := range errs {
+		if e != nil {
+			return done, e
+		}
+	}
+	return done, nil
+}
+
+func countDone(done []bool) int {
+	c := 0
+	for _, d := range done {
+		if d {
+			c++
+		}
+	}
+	return c
+}
+
+// analyzeFiles analyzes ins with up to jobs files in flight. It returns the
+// files that completed, in input order, even when err is non-nil.
+func analyzeFiles(cfg *Config, ins []string, jobs int) ([]*Analysis, error) {
+	all := make([]*Analysis, len(ins))
+	done, err := runBatch(len(ins), jobs, func(i int) error {
+		a, err := analyzeFile(cfg, ins[i])
+		if err != nil {
+			return fmt.Errorf("%s: %w", ins[i], err)
+		}
+		recordAnalysis(cfg, a)
+		all[i] = a
+		return nil
+	})
+	var as []*Analysis
+	for i, a := range all {
+		if done[i] {
+			as = append(as, a)
+		}
+	}
+	return as, err
+}
+
+// analyzeStreams analyzes every audio stream of in, up to jobs at once
+func analyzeStreams(cfg *Config, in string, jobs int) ([]*Analysis, error) {
+	probe, err := ffprobeInfo(cfg, in)
+	if err != nil {
+		return nil, err
+	}
+	if probe.AudioStreams == 0 {
+		return nil, fmt.Errorf("%s: no audio streams", in)
+	}
+	as := make([]*Analysis, probe.AudioStreams)
+	_, err = runBatch(len(as), jobs, func(i int) error {
+		sc := *cfg
+		sc.Stream = i
+		a, err := analyzeFile(&sc, in)
+		if err != nil {
+			return fmt.Errorf("stream %d: %w", i, err)
+		}
+		as[i] = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return as, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// collectAudio lists audio files in dir (subdirectories too if recursive)
+// whose base name matches glob ("" matches all), sorted by path
+func collectAudio(dir string, recursive bool, glob string) ([]string, error) {
+	if glob != "" {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("bad -glob %q: %w", glob, err)
+		}
+	}
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isAudioFile(p) {
+			return nil
+		}
+		if ok, _ := filepath.Match(glob, d.Name()); glob == "" || ok {
+			files = append(files, p)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// reportExt is the file extension for a per-file report in format report
+func reportExt(report string) string {
+	switch report {
+	case "json", "yaml", "md", "csv", "ndjson":
+		return "." + report
+	}
+	return ".txt"
+}
+
+// batchReportPath mirrors in's place under dir inside outDir, so two
+// "mix.wav" in different subfolders get different reports
+func batchReportPath(dir, outDir, in, report string) string {
+	rel, err := filepath.Rel(dir, in)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(in)
+	}
+	return filepath.Join(outDir, strings.TrimSuffix(rel, filepath.Ext(rel))+reportExt(report))
+}
+
+// analyzeBatch analyzes files jobs at a time, writing each file's report as
+// soon as it is done so an interrupted run keeps what it finished; with
+// -report ndjson each analysis (or failure) is also appended to -o as it
+// completes. A file that fails is recorded and the run goes on; only a
+// signal stops it. Returns the completed analyses and the failures, both in
+// input order.
+func analyzeBatch(cfg *Config, dir string, files []string, outDir string, jobs int) ([]*Analysis, []FileError, error) {
+	all := make([]*Analysis, len(files))
+	errs := make([]error, len(files))
+	stream := strings.ToLower(cfg.Report) == "ndjson"
+	done, err := runBatch(len(files), jobs, func(i int) error {
+		a, err := analyzeFile(cfg, files[i])
+		if err == nil {
+			out := batchReportPath(dir, outDir, files[i], cfg.Report)
+			if err = writeReport(cfg, a, out); err != nil {
+				err = fmt.Errorf("write %s: %w", out, err)
+			} else {
+				wrote(out)
+				recordAnalysis(cfg, a)
+			}
+		}
+		if stream {
+			var line any = a
+			if err != nil {
+				line = FileError{File: files[i], Error: err.Error()}
+			}
+			if aerr := appendNDJSON(cfg.OutPath, line); aerr != nil && err == nil {
+				err = fmt.Errorf("write %s: %w", cfg.OutPath, aerr)
+			}
+		}
+		if err != nil {
+			warnf("%s: %v", files[i], err)
+			errs[i] = err
+			return nil
+		}
+		all[i] = a
+		return nil
+	})
+	var as []*Analysis
+	var failed []FileError
+	for i, a := range all {
+		switch {
+		case !done[i]:
+		case errs[i] != nil:
+			failed = append(failed, FileError{File: files[i], Error: errs[i].Error()})
+		default:
+			as = append(as, a)
+		}
+	}
+	return as, failed, err
+}
+
+// expandInputs replaces each directory argument with its audio files (per
+// -recursive/-glob) and keeps file arguments as given
+func expandInputs(args []string, recursive bool, glob string) ([]string, error) {
+	var files []string
+	for _, in := range args {
+		if st, err := os.Stat(in); err == nil && st.IsDir() {
+			dir, err := collectAudio(in, recursive, glob)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, dir...)
+			continue
+		}
+		files = append(files, in)
+	}
+	return files, nil
+}
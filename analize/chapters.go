@@ -0,0 +1,147 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// chapterStarts turns the silences at least gap seconds long into track
+// starts: one at 0 and one where each such silence ends, unless it runs to
+// the end of the file
+func chapterStarts(spans []SilenceSpan, dur, gap float64) []float64 {
+	starts := []float64{0}
+	for _, s := range spans {
+		if s.End-s.Start >= gap && s.Start > trimEdgeSec && s.End < dur-trimEdgeSec {
+			starts = append(starts, s.End)
+		}
+	}
+	return starts
+}
+
+// parseMarkers reads comma-separated positions in the file (seconds or
+// [h:]m:ss) as starts relative to offset; the first track always starts at 0
+func parseMarkers(s string, offset float64) ([]float64, error) {
+	starts := []float64{0}
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		t, err := parseClock(part)
+		if err != nil {
+			return nil, err
+		}
+		if t -= offset; t > 0 {
+			starts = append(starts, t)
+		}
+	}
+	sort.Float64s(starts)
+	return starts, nil
+}
+
+// chapters numbers the starts from first and names them from a template:
+// {n} is the number (two digits), {start} the position as m:ss
+func chapters(starts []float64, dur float64, first int, name string) []Chapter {
+	var cs []Chapter
+	for i, s := range starts {
+		if s >= dur || (i > 0 && s == starts[i-1]) {
+			continue
+		}
+		if n := len(cs); n > 0 {
+			cs[n-1].End = s
+		}
+		num := first + len(cs)
+		title := strings.NewReplacer("{n}", fmt.Sprintf("%02d", num), "{start}", clock(s)).Replace(name)
+		cs = append(cs, Chapter{Number: num, Title: title, Start: s, End: dur})
+	}
+	return cs
+}
+
+// clock formats seconds as m:ss (h:mm:ss past an hour)
+func clock(t float64) string {
+	s := int(t)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// cueTime is a cue sheet position: mm:ss:ff, 75 frames per second
+func cueTime(t float64) string {
+	f := int(math.Round(t * 75))
+	return fmt.Sprintf("%02d:%02d:%02d", f/(75*60), f/75%60, f%75)
+}
+
+func cueQuote(s string) string { return `"` + strings.ReplaceAll(s, `"`, "'") + `"` }
+
+// renderCue writes a cue sheet indexing in; times are absolute in the file,
+// so an analyzed -start range is added back
+func renderCue(in string, md *Metadata, cs []Chapter, offset float64) string {
+	var b strings.Builder
+	if md != nil {
+		if md.Artist != "" {
+			fmt.Fprintf(&b, "PERFORMER %s\n", cueQuote(md.Artist))
+		}
+		if t := cmp.Or(md.Album, md.Title); t != "" {
+			fmt.Fprintf(&b, "TITLE %s\n", cueQuote(t))
+		}
+	}
+	typ := "WAVE"
+	switch strings.ToLower(filepath.Ext(in)) {
+	case ".mp3":
+		typ = "MP3"
+	case ".aif", ".aiff":
+		typ = "AIFF"
+	}
+	fmt.Fprintf(&b, "FILE %s %s\n", cueQuote(filepath.Base(in)), typ)
+	for _, c := range cs {
+		fmt.Fprintf(&b, "  TRACK %02d AUDIO\n    TITLE %s\n    INDEX 01 %s\n", c.Number, cueQuote(c.Title), cueTime(offset+c.Start))
+	}
+	return b.String()
+}
+
+// renderFFMetadata writes an ffmpeg metadata file with one chapter per
+// track, for muxing with -i in -i chapters.txt -map_metadata 1
+func renderFFMetadata(cs []Chapter, offset float64) string {
+	esc := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+	ms := func(t float64) string { return strconv.FormatInt(int64(math.Round((offset+t)*1000)), 10) }
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for _, c := range cs {
+		fmt.Fprintf(&b, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%s\nEND=%s\ntitle=%s\n", ms(c.Start), ms(c.End), esc.Replace(c.Title))
+	}
+	return b.String()
+}
+
+// buildChapters indexes in from user markers, or from its silences of at
+// least gap seconds, and renders the result as a cue sheet or ffmetadata
+func buildChapters(cfg *Config, in, format, markers string, gap float64, first int, name string) (string, error) {
+	probe, err := ffprobeInfo(cfg, in)
+	if err != nil {
+		return "", err
+	}
+	var starts []float64
+	var dur float64
+	if markers != "" {
+		if starts, err = parseMarkers(markers, cfg.Start); err != nil {
+			return "", err
+		}
+		dur = rangeDuration(cfg, probe.Duration)
+		if d, _, _, err := ffmpegDecodedDuration(cfg, in); err == nil {
+			dur = d
+		}
+	} else {
+		var spans []SilenceSpan
+		spans, dur = silenceSpans(cfg, in, probe)
+		starts = chapterStarts(spans, dur, gap)
+	}
+	cs := chapters(starts, dur, first, name)
+	if format == "ffmetadata" {
+		return renderFFMetadata(cs, cfg.Start), nil
+	}
+	return renderCue(in, parseMetadata(probe.Tags), cs, cfg.Start), nil
+}
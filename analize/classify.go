@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+)
+
+// speech/music discrimination on a 16 kHz mono decode, in 20 ms frames
+// grouped into 1 s segments. Speech alternates syllables with short pauses
+// (many frames well below the segment's average energy) and voiced with
+// unvoiced sounds (bursts of high zero-crossing rate); music is steadier on
+// both. The two ratios are the low short-term energy ratio (LSTER) and high
+// zero-crossing rate ratio (HZCRR) of Lu, Zhang and Jiang (2002).
+const (
+	classifyRate     = 16000
+	classifyFrameSec = 0.02
+	classifySegSec   = 1.0
+	classifyLSTER    = 0.15  // share of frames below half the average energy
+	classifyHZCRR    = 0.05  // share of frames above 1.5x the average ZCR
+	classifyFloorDB  = -50.0 // a quieter segment is silence
+	classifySpeechP  = 0.8   // speech share of the labelled segments for "speech"...
+	classifyMusicP   = 0.2   // ...and at most this for "music"; between is "mixed"
+)
+
+// classifyContent labels every segment of the file speech, music or
+// silence, smooths single-segment flips and labels the file from the
+// speech share of its non-silent segments. nil: nothing but silence.
+func classifyContent(cfg *Config, in string) (*Content, error) {
+	cmd := exec.Command(cfg.FFmpegBin, append([]string{"-hide_banner", "-nostats", "-loglevel", "error"}, spectrumArgs(cfg, in, classifyRate)...)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	acquireProc()
+	defer releaseProc()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	frameN := int(classifyFrameSec * classifyRate)
+	perSeg := int(classifySegSec / classifyFrameSec)
+	buf := make([]byte, 4*frameN)
+	r := bufio.NewReaderSize(stdout, 1<<16)
+	var labels []string
+	var es, zs []float64
+	for {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			break
+		}
+		var e float64
+		zc := 0
+		prev := float32(0)
+		for i := 0; i < frameN; i++ {
+			x := math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+			e += float64(x) * float64(x)
+			if i > 0 && (x >= 0) != (prev >= 0) {
+				zc++
+			}
+			prev = x
+		}
+		es, zs = append(es, e/float64(frameN)), append(zs, float64(zc)/float64(frameN))
+		if len(es) == perSeg {
+			labels = append(labels, segmentLabel(es, zs))
+			es, zs = es[:0], zs[:0]
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg decode: %w", err)
+	}
+	// a trailing part segment counts if it is at least half a segment
+	if len(es) >= perSeg/2 {
+		labels = append(labels, segmentLabel(es, zs))
+	}
+	return contentSummary(labels, classifySegSec), nil
+}
+
+// segmentLabel applies the LSTER/HZCRR rule to one segment's frame
+// energies (mean square) and zero-crossing rates
+func segmentLabel(es, zs []float64) string {
+	me, mz := mean(es), mean(zs)
+	if me <= 0 || 10*math.Log10(me) < classifyFloorDB {
+		return "silence"
+	}
+	var low, high int
+	for i := range es {
+		if es[i] < 0.5*me {
+			low++
+		}
+		if zs[i] > 1.5*mz {
+			high++
+		}
+	}
+	n := float64(len(es))
+	if float64(low)/n >= classifyLSTER && float64(high)/n >= classifyHZCRR {
+		return "speech"
+	}
+	return "music"
+}
+
+// contentSummary smooths the segment labels (a lone segment between two
+// that agree takes their label), merges runs into spans and labels the file
+func contentSummary(labels []string, segSec float64) *Content {
+	sm := append([]string(nil), labels...)
+	for i := 1; i+1 < len(labels); i++ {
+		if labels[i-1] == labels[i+1] && labels[i] != labels[i-1] {
+			sm[i] = labels[i-1]
+		}
+	}
+	c := &Content{}
+	var speech, voiced int
+	for i, l := range sm {
+		switch l {
+		case "speech":
+			speech++
+			voiced++
+		case "music":
+			voiced++
+		}
+		t := float64(i) * segSec
+		if n := len(c.Spans); n > 0 && c.Spans[n-1].Label == l {
+			c.Spans[n-1].End = t + segSec
+			continue
+		}
+		c.Spans = append(c.Spans, ContentSpan{Start: t, End: t + segSec, Label: l})
+	}
+	if voiced == 0 {
+		return nil
+	}
+	c.SpeechPct = 100 * float64(speech) / float64(voiced)
+	switch {
+	case c.SpeechPct >= 100*classifySpeechP:
+		c.Label = "speech"
+	case c.SpeechPct <= 100*classifyMusicP:
+		c.Label = "music"
+	default:
+		c.Label = "mixed"
+	}
+	return c
+}
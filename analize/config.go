@@ -1,6 +1,7 @@
 package main
 
 import (
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -10,35 +11,105 @@ type Bandspec struct{ Lo, Hi float64 }
 type Config struct {
 	// IO / tools
 	OutPath    string
-	Report     string // txt|json|md
+	Report     string // txt|json|yaml|md|csv|ndjson
 	FFmpegBin  string
 	FFprobeBin string
 	AubioBin   string
+	FpcalcBin  string // chromaprint fpcalc (-fingerprint)
+	SQLiteBin  string
+	DBPath     string // -db: also store every analysis here
 
 	// engines
-	BPMEngine  string // aubio|none
-	UseBands   bool
-	Bands      []Bandspec
-	UseEBUR128 bool
+	BPMEngine   string // aubio|none
+	UseBands    bool
+	Bands       []Bandspec
+	UseEBUR128  bool
+	EBUPeak     string   // ebur128 peak mode: true|sample|sample+true|none
+	EBUDualMono string   // auto|on|off
+	LoudnessStd string   // ebu (R128, LUFS) | atsc (A/85, LKFS)
+	Delivery    string   // ""|cd|streaming|vinyl|broadcast: true-peak ceiling advice
+	Platform    string   // -target: ""|spotify|youtube|apple|tidal|broadcast normalization check
+	UseClicks   bool     // click events from the defect scan, graded per minute
+	DialogGate  bool     // extra ebur128 pass on the speech band
+	Mono        bool     // measure the mono sum; stereo section skipped
+	Channels    []string // -channels: extra measurement of just these (FL, FR, LFE, ...)
+	Fingerprint bool     // chromaprint fingerprint via fpcalc
+	AcoustIDKey string   // AcoustID client key: look the fingerprint up (network)
+	PhaseScope  bool     // L/R histogram + width % (raw sample pass)
+	Defects     bool     // timestamped click/dropout/glitch scan (raw sample pass)
 
 	// tuning
-	AstatsWin  float64
-	SilThresDB float64
+	AubioBufSize int // aubio -B (0=aubio default)
+	AubioHopSize int // aubio -H (0=aubio default)
+	AstatsWin    float64
+	Oversample   int     // oversampling factor of the per-channel true-peak pass
+	TiltFreq     float64 // spectral tilt crossover Hz (0=off)
+	SilThresDB   float64
+	HeadFrac     float64 // -sections: head/tail share of duration (0 = off)
+	TailFrac     float64
+	StructLU     float64 // level change (LU) that starts a new structure section (0 = off)
+	DCThreshold  float64 // per-channel |DC offset| flagged, and corrected by fix-dc
+	CorrWin      float64 // correlation timeline window, seconds (0 = off)
+	CorrThresh   float64 // correlation below this marks a low-correlation section
+	BalanceDB    float64 // |L-R RMS| above this is an off-center mix (0 = off)
+	LUFSTarget   float64 // report integrated relative to this (0=off)
+	Start, End   float64 // -start/-end: analyze only this section, seconds (End 0 = to the end)
+	Stream       int     // -stream: audio stream to analyze (0-based, ffmpeg 0:a:N)
+
+	// output
+	MinSeverity Severity // drop notes below this
+	JSONCompact bool
+	Explain     bool   // interpret metrics in the txt report
+	SortBy      string // summary order: lufs|peak|bpm|name|duration ("" = input order)
+	SortReverse bool
+	Precision   Precision
 }
 
 func defaultConfig() *Config {
 	return &Config{
-		OutPath:    "out.log",
-		Report:     "txt",
-		FFmpegBin:  "ffmpeg",
-		FFprobeBin: "ffprobe",
-		AubioBin:   "aubio",
-		BPMEngine:  "none",
-		UseBands:   true,
-		Bands:      parseBands("20-60,60-120,120-250,250-500,500-2000,2000-5000,5000-10000,10000-20000"),
-		UseEBUR128: true,
-		AstatsWin:  0,
-		SilThresDB: -45,
+		OutPath:     "out.log",
+		Report:      "txt",
+		FFmpegBin:   "ffmpeg",
+		FFprobeBin:  "ffprobe",
+		AubioBin:    "aubio",
+		FpcalcBin:   "fpcalc",
+		SQLiteBin:   "sqlite3",
+		BPMEngine:   "none",
+		UseBands:    true,
+		Bands:       parseBands("20-60,60-120,120-250,250-500,500-2000,2000-5000,5000-10000,10000-20000"),
+		UseEBUR128:  true,
+		EBUPeak:     "true",
+		EBUDualMono: "auto",
+		LoudnessStd: "ebu",
+		AstatsWin:   0,
+		Oversample:  4,
+		SilThresDB:  -45,
+		HeadFrac:    0.1,
+		TailFrac:    0.1,
+		StructLU:    3,
+		DCThreshold: 0.01,
+		CorrWin:     1,
+		BalanceDB:   1.5,
+		MinSeverity: SevInfo,
+		Precision:   defaultPrecision(),
+	}
+}
+
+// report format implied by an output path's extension
+func reportFromExt(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".md":
+		return "md"
+	case ".csv":
+		return "csv"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return "txt"
 	}
 }
 
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// corrTimelineChain measures L, R, mid and side RMS per window; the same
+// identity as the band correlation turns each window into a Pearson value
+func corrTimelineChain(sampleRate int, windowSec float64) string {
+	n := max(1, int(math.Round(float64(sampleRate)*windowSec)))
+	chain := fmt.Sprintf("%s,asetnsamples=n=%d:p=0,astats=metadata=1:reset=1:measure_overall=none", bandCorrPan, n)
+	for ch := 1; ch <= 4; ch++ {
+		chain += fmt.Sprintf(",ametadata=mode=print:key=lavfi.astats.%d.RMS_level", ch)
+	}
+	return chain
+}
+
+// corrTimeline is L/R correlation per window of windowSec seconds. Windows
+// silent in either channel have no correlation and are left out.
+func corrTimeline(cfg *Config, in string, sampleRate int, windowSec float64) ([]CorrPoint, error) {
+	if windowSec <= 0 || sampleRate <= 0 {
+		return nil, fmt.Errorf("correlation timeline needs a window and sample rate")
+	}
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-af", corrTimelineChain(sampleRate, windowSec), "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	ps := parseCorrTimeline(out)
+	if len(ps) == 0 {
+		return nil, fmt.Errorf("no correlation windows parsed")
+	}
+	return ps, nil
+}
+
+// same layout as parseWindowedAstats: a pts_time header per print, the
+// four chained prints of one window share it
+func parseCorrTimeline(out string) []CorrPoint {
+	reT := regexp.MustCompile(`pts_time:\s*([-\d\.]+)`)
+	reKV := regexp.MustCompile(`lavfi\.astats\.([1-4])\.RMS_level=(-?inf|nan|[-\d\.]+)`)
+	var times []string
+	wins := map[string][]map[string]float64{}
+	t := ""
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if m := reT.FindStringSubmatch(line); len(m) == 2 {
+			t = m[1]
+			if _, ok := wins[t]; !ok {
+				times = append(times, t)
+				wins[t] = []map[string]float64{{}, {}, {}, {}}
+			}
+			continue
+		}
+		m := reKV.FindStringSubmatch(line)
+		if len(m) != 3 || t == "" || strings.Contains(m[2], "inf") || m[2] == "nan" {
+			continue
+		}
+		wins[t][int(m[1][0]-'1')]["rms_level_db"] = parseFloat(m[2])
+	}
+	var ps []CorrPoint
+	for _, t := range times {
+		if c, err := bandCorrelation(wins[t]); err == nil {
+			ps = append(ps, CorrPoint{Time: parseFloat(t), Corr: *c})
+		}
+	}
+	return ps
+}
+
+// corrSummary fills the timeline figures: min, mean and the stretches of
+// consecutive windows below threshold
+func corrSummary(st *StereoStats, ps []CorrPoint, windowSec, threshold float64) {
+	if len(ps) == 0 {
+		return
+	}
+	lo, sum := ps[0].Corr, 0.0
+	for i, p := range ps {
+		lo, sum = math.Min(lo, p.Corr), sum+p.Corr
+		if p.Corr >= threshold {
+			continue
+		}
+		if n := len(st.LowCorr); n > 0 && i > 0 && ps[i-1].Corr < threshold && st.LowCorr[n-1].End >= p.Time-windowSec/2 {
+			st.LowCorr[n-1].End = p.Time + windowSec
+			st.LowCorr[n-1].Min = math.Min(st.LowCorr[n-1].Min, p.Corr)
+			continue
+		}
+		st.LowCorr = append(st.LowCorr, CorrSpan{Start: p.Time, End: p.Time + windowSec, Min: p.Corr})
+	}
+	avg := sum / float64(len(ps))
+	st.CorrMin, st.CorrAvg, st.CorrTimeline = &lo, &avg, ps
+}
+
+// corrNotes points at the out-of-phase stretches; a short phasey intro
+// barely moves the overall correlation
+func corrNotes(st StereoStats, threshold float64) []Note {
+	if len(st.LowCorr) == 0 {
+		return nil
+	}
+	var total float64
+	for _, s := range st.LowCorr {
+		total += s.End - s.Start
+	}
+	f := st.LowCorr[0]
+	return []Note{newNote(SevWarn, "LOW_CORRELATION_SECTIONS", "L/R correlation below %.2f for %.1fs in %d section(s), down to %.2f; first %.1fs-%.1fs.",
+		threshold, total, len(st.LowCorr), *st.CorrMin, f.Start, f.End)}
+}
+
+// stereo delivery faults: a side (or mid) this far below the other is an
+// exact or near-exact copy, as are correlations this close to ±1
+const (
+	faultCopyDB = 60.0
+	faultCorr   = 0.999
+)
+
+// stereoFault names a classic two-channel delivery error: "dual-mono" (both
+// channels the same), "inverted" (one the polarity-flipped other, so the
+// mono sum cancels) or "left-silent"/"right-silent". "" is a real stereo
+// signal. A silent channel comes first; it makes mid and side equal.
+func stereoFault(st StereoStats, chans []ChannelStats) string {
+	if len(chans) == 2 {
+		l, r := chans[0].PeakDB <= silentFloorDB, chans[1].PeakDB <= silentFloorDB
+		switch {
+		case l && !r:
+			return "left-silent"
+		case r && !l:
+			return "right-silent"
+		}
+	}
+	c := st.Correlation
+	switch {
+	case st.SideMidRatioDB <= -faultCopyDB || (c != nil && *c >= faultCorr):
+		return "dual-mono"
+	case st.SideMidRatioDB >= faultCopyDB || (c != nil && *c <= -faultCorr):
+		return "inverted"
+	}
+	return ""
+}
+
+func stereoFaultNotes(st StereoStats) []Note {
+	switch st.Fault {
+	case "dual-mono":
+		return []Note{newNote(SevWarn, "DUAL_MONO", "Both channels carry the same signal (side/mid %.1f dB); deliver as mono or check the stereo source.", st.SideMidRatioDB)}
+	case "inverted":
+		return []Note{newNote(SevError, "POLARITY_INVERTED", "One channel is the polarity-inverted copy of the other (side/mid %+.1f dB); the mono sum cancels. Flip one channel.", st.SideMidRatioDB)}
+	case "left-silent", "right-silent":
+		side := strings.TrimSuffix(st.Fault, "-silent")
+		return []Note{newNote(SevError, "CHANNEL_SILENT", "The %s channel is silent while the other carries audio; check the routing or channel mapping.", side)}
+	}
+	return nil
+}
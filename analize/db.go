@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Analyses are stored through the sqlite3 command line tool, the same way
+// every other backend here is an external binary. Schema:
+//
+//	files(id, path)                      one row per analyzed path (path#a:N per stream)
+//	runs(id, file_id, analyzed_at, report)  one row per analysis, full JSON
+//	metrics(run_id, name, value)         registry metrics, for filtering
+const dbSchema = `CREATE TABLE IF NOT EXISTS files(id INTEGER PRIMARY KEY, path TEXT UNIQUE NOT NULL);
+CREATE TABLE IF NOT EXISTS runs(id INTEGER PRIMARY KEY, file_id INTEGER NOT NULL REFERENCES files(id), analyzed_at TEXT, report TEXT);
+CREATE TABLE IF NOT EXISTS metrics(run_id INTEGER NOT NULL REFERENCES runs(id), name TEXT NOT NULL, value REAL, PRIMARY KEY(run_id, name));
+CREATE INDEX IF NOT EXISTS metrics_name_value ON metrics(name, value);
+`
+
+// one writer at a time; parallel -jobs would otherwise hit SQLITE_BUSY
+var dbMu sync.Mutex
+
+func sqlQuote(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+
+func runSQLite(cfg *Config, script string, args ...string) (string, error) {
+	cmd := exec.Command(cfg.SQLiteBin, append(args, cfg.DBPath)...)
+	cmd.Stdin = strings.NewReader(script)
+	acquireProc()
+	defer releaseProc()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("sqlite3: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// storeAnalysis appends a as a new run of its file
+func storeAnalysis(cfg *Config, a *Analysis) error {
+	report, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString(".bail on\nBEGIN;\n" + dbSchema)
+	fmt.Fprintf(&b, "INSERT OR IGNORE INTO files(path) VALUES(%s);\n", sqlQuote(streamName(a)))
+	fmt.Fprintf(&b, "INSERT INTO runs(file_id, analyzed_at, report) VALUES((SELECT id FROM files WHERE path=%s), %s, %s);\n",
+		sqlQuote(streamName(a)), sqlQuote(a.When), sqlQuote(string(report)))
+	for _, m := range metricRegistry {
+		if m.Value == nil {
+			continue
+		}
+		v, ok := m.Value(a)
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		fmt.Fprintf(&b, "INSERT INTO metrics VALUES((SELECT max(id) FROM runs), %s, %s);\n", sqlQuote(m.Name), strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	b.WriteString("COMMIT;\n")
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	_, err = runSQLite(cfg, b.String())
+	return err
+}
+
+// recordAnalysis stores a when -db is set; a failed store is a warning,
+// the report itself was still produced
+func recordAnalysis(cfg *Config, a *Analysis) {
+	if cfg.DBPath == "" {
+		return
+	}
+	if err := storeAnalysis(cfg, a); err != nil {
+		warnf("db %s: %s: %v", cfg.DBPath, a.File, err)
+	}
+}
+
+var reCond = regexp.MustCompile(`^\s*([a-z_0-9]+)\s*(<=|>=|!=|=|<|>)\s*(-?[0-9.]+)\s*$`)
+
+// querySQL turns "lufs_integrated>-9,bpm_median>=120" (ANDed) into a query
+// over each file's latest run, one column per numeric metric
+func querySQL(where string) (string, error) {
+	var names, cols []string
+	for _, m := range metricRegistry {
+		if m.Value != nil {
+			names = append(names, m.Name)
+			cols = append(cols, fmt.Sprintf("(SELECT value FROM metrics WHERE run_id=r.id AND name=%s) AS %s", sqlQuote(m.Name), m.Name))
+		}
+	}
+	var conds []string
+	for _, c := range strings.Split(where, ",") {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		m := reCond.FindStringSubmatch(c)
+		if m == nil {
+			return "", fmt.Errorf("bad condition %q (want metric<op>number, e.g. lufs_integrated>-9)", c)
+		}
+		if !slices.Contains(names, m[1]) {
+			return "", fmt.Errorf("unknown metric %q (see `analit metrics`)", m[1])
+		}
+		if _, err := strconv.ParseFloat(m[3], 64); err != nil {
+			return "", fmt.Errorf("bad number in %q", c)
+		}
+		conds = append(conds, fmt.Sprintf("EXISTS (SELECT 1 FROM metrics WHERE run_id=r.id AND name=%s AND value %s %s)", sqlQuote(m[1]), m[2], m[3]))
+	}
+	q := "SELECT f.path, r.analyzed_at, " + strings.Join(cols, ", ") +
+		"\nFROM files f JOIN runs r ON r.id = (SELECT max(id) FROM runs WHERE file_id=f.id)"
+	if len(conds) > 0 {
+		q += "\nWHERE " + strings.Join(conds, " AND ")
+	}
+	return q + "\nORDER BY f.path;\n", nil
+}
+
+// queryDB runs a filter against the database and returns CSV with a header
+func queryDB(cfg *Config, where string) (string, error) {
+	q, err := querySQL(where)
+	if err != nil {
+		return "", err
+	}
+	return runSQLite(cfg, dbSchema+q, "-csv", "-header")
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// dcNotes flags each channel whose offset passes -dc-threshold; the overall
+// DC averages the channels, so one bad side of a stereo pair halves in it
+func dcNotes(chans []ChannelStats, threshold float64) []Note {
+	if threshold <= 0 {
+		return nil
+	}
+	var notes []Note
+	for _, ch := range chans {
+		if math.Abs(ch.DCOffset) > threshold {
+			notes = append(notes, newNote(SevWarn, "DC_OFFSET_CHANNEL", "Channel %s has a DC offset of %+.4f (threshold %.4f); fix-dc writes a corrected copy.", chanLabel(ch), ch.DCOffset, threshold))
+		}
+	}
+	return notes
+}
+
+func chanLabel(ch ChannelStats) string {
+	if ch.Name != "" {
+		return ch.Name
+	}
+	return fmt.Sprint(ch.Channel)
+}
+
+// channelDC measures every channel's DC offset with one astats pass
+func channelDC(cfg *Config, in string) ([]ChannelStats, error) {
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-map", streamSpec(cfg), "-af", "astats=measure_overall=none:reset=0", "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	_, chans := parseAstats(out)
+	if len(chans) == 0 {
+		return nil, fmt.Errorf("no per-channel astats parsed")
+	}
+	return channelStats(chans), nil
+}
+
+// fixDCChain removes the offset of the channels over threshold. "shift"
+// subtracts each measured offset (dcshift only moves all channels at once,
+// so it is aeval per channel); "highpass" runs a 5 Hz high-pass on those
+// channels, which also follows an offset that drifts. Without channel
+// names (unknown layout) the high-pass takes every channel.
+func fixDCChain(chans []ChannelStats, threshold float64, method string) string {
+	exprs := make([]string, len(chans))
+	var hp []string
+	for i, ch := range chans {
+		exprs[i] = fmt.Sprintf("val(%d)", i)
+		if math.Abs(ch.DCOffset) <= threshold {
+			continue
+		}
+		exprs[i] = fmt.Sprintf("val(%d)-(%.8f)", i, ch.DCOffset)
+		hp = append(hp, ch.Name)
+	}
+	if method == "highpass" {
+		if slices.Contains(hp, "") {
+			return "highpass=f=5:poles=2"
+		}
+		return "highpass=f=5:poles=2:channels=" + strings.Join(hp, "+")
+	}
+	return "aeval=" + strings.Join(exprs, "|") + ":channel_layout=same"
+}
+
+// fixDC writes out with the offset of every channel over threshold removed
+// and measures it again. No channel over threshold: nothing is written.
+func fixDC(cfg *Config, in, out, method string) (*DCFixReport, error) {
+	probe, err := ffprobeInfo(cfg, in)
+	if err != nil {
+		return nil, err
+	}
+	before, err := channelDC(cfg, in)
+	if err != nil {
+		return nil, err
+	}
+	names := channelNames(probe.Layout, probe.Channels)
+	r := &DCFixReport{In: in, Out: out, Method: method, Threshold: cfg.DCThreshold}
+	for i := range before {
+		if i < len(names) {
+			before[i].Name = names[i]
+		}
+		ch := before[i]
+		r.Channels = append(r.Channels, DCFixChannel{Channel: ch.Channel, Name: ch.Name, Before: ch.DCOffset,
+			Fixed: math.Abs(ch.DCOffset) > cfg.DCThreshold})
+	}
+	fix := false
+	for _, c := range r.Channels {
+		fix = fix || c.Fixed
+	}
+	if !fix {
+		return r, nil
+	}
+	r.Filter = fixDCChain(before, cfg.DCThreshold, method)
+	if err := ensureParent(out); err != nil {
+		return nil, err
+	}
+	args := rangeArgs(cfg, []string{"-y", "-hide_banner", "-nostats", "-loglevel", "error", "-i", in, "-vn", "-map", streamSpec(cfg), "-af", r.Filter})
+	args = append(append(args, pcmCodec(out, probe)...), out)
+	if o, err := runCmd(cfg.FFmpegBin, args...); err != nil {
+		return nil, fmt.Errorf("ffmpeg fix-dc: %w\n%s", err, o)
+	}
+	wrote(out)
+	r.Written = true
+	// the output holds just the fixed stream and range
+	qc := *cfg
+	qc.Start, qc.End, qc.Stream = 0, 0, 0
+	after, err := channelDC(&qc, out)
+	if err != nil {
+		return nil, fmt.Errorf("re-measure %s: %w", out, err)
+	}
+	for i := range r.Channels {
+		if i < len(after) {
+			v := after[i].DCOffset
+			r.Channels[i].After = &v
+		}
+	}
+	return r, nil
+}
+
+func renderDCFix(cfg *Config, r *DCFixReport) (string, error) {
+	var b strings.Builder
+	after := func(v *float64) string { return fmtOpt(v, func(v float64) string { return fmt.Sprintf("%+.4f", v) }) }
+	switch strings.ToLower(cfg.Report) {
+	case "json", "yaml":
+		return renderJSON(cfg, r)
+	case "md":
+		fmt.Fprintf(&b, "# DC fix: %s\n\n- Method: `%s`\n- Threshold: `%.4f`\n", filepath.Base(r.In), r.Method, r.Threshold)
+		if r.Written {
+			fmt.Fprintf(&b, "- Output: `%s`\n", r.Out)
+		} else {
+			fmt.Fprintf(&b, "- Output: none, no channel over the threshold\n")
+		}
+		fmt.Fprintf(&b, "\n| Channel | Name | DC before | DC after | Fixed |\n|---:|---|---:|---:|---|\n")
+		for _, c := range r.Channels {
+			fmt.Fprintf(&b, "| %d | %s | %+.4f | %s | %t |\n", c.Channel, orNA(c.Name), c.Before, after(c.After), c.Fixed)
+		}
+	default:
+		if r.Written {
+			fmt.Fprintf(&b, "FIX-DC: %s -> %s (%s, threshold %.4f)\n\n", r.In, r.Out, r.Method, r.Threshold)
+		} else {
+			fmt.Fprintf(&b, "FIX-DC: %s: no channel over threshold %.4f, nothing written\n\n", r.In, r.Threshold)
+		}
+		fmt.Fprintf(&b, "%-8s %10s %10s\n", "channel", "before", "after")
+		for _, c := range r.Channels {
+			mark := ""
+			if c.Fixed {
+				mark = " *"
+			}
+			fmt.Fprintf(&b, "%-8s %+10.4f %10s%s\n", chanLabel(ChannelStats{Channel: c.Channel, Name: c.Name}), c.Before, after(c.After), mark)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// clipped sections are found on this grid: windows whose peak sits at the
+// file's ceiling, padded by one window either side so adeclip sees the
+// unclipped signal around each run
+const (
+	declipWindowSec = 0.5
+	declipCeilingDB = 0.05 // a window within this of the file peak is at the ceiling
+)
+
+// clipSections merges the windows that reach the ceiling into time ranges
+func clipSections(ws []WindowStat, ceilingDB, win, dur float64) []TimeRange {
+	var rs []TimeRange
+	for _, w := range ws {
+		if w.PeakDB < ceilingDB-declipCeilingDB {
+			continue
+		}
+		s, e := max(0, w.Time-win), w.Time+2*win
+		if dur > 0 {
+			e = min(dur, e)
+		}
+		if n := len(rs); n > 0 && s <= rs[n-1].End {
+			rs[n-1].End = max(rs[n-1].End, e)
+			continue
+		}
+		rs = append(rs, TimeRange{Start: s, End: e})
+	}
+	return rs
+}
+
+// declipChain lowers the whole file by headroom dB, so the reconstructed
+// peaks have somewhere to go, and runs adeclip (and adeclick) only inside
+// the sections through their timeline enable option
+func declipChain(sections []TimeRange, headroom float64, declick bool) string {
+	var en []string
+	for _, r := range sections {
+		en = append(en, fmt.Sprintf("between(t,%.3f,%.3f)", r.Start, r.End))
+	}
+	enable := "'" + strings.Join(en, "+") + "'"
+	chain := fmt.Sprintf("volume=%.2fdB,adeclip=enable=%s", -headroom, enable)
+	if declick {
+		chain += ",adeclick=enable=" + enable
+	}
+	return chain
+}
+
+// ceilingSamples counts the samples, over all channels, within
+// declipCeilingDB of levelDB or above it. Output and input are counted
+// the same way, the output at the old ceiling less the headroom it was
+// lowered by, so the gain alone doesn't read as a repair.
+func ceilingSamples(cfg *Config, in string, levelDB float64) (int64, error) {
+	cmd := exec.Command(cfg.FFmpegBin, rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-loglevel", "error",
+		"-i", in, "-vn", "-map", streamSpec(cfg), "-af", defectsChain, "-f", "f32le", "-acodec", "pcm_f32le", "-"})...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	acquireProc()
+	defer releaseProc()
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	at := math.Pow(10, (levelDB-declipCeilingDB)/20)
+	var n int64
+	r := bufio.NewReaderSize(stdout, 1<<16)
+	var buf [4]byte
+	for {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			break
+		}
+		if math.Abs(float64(math.Float32frombits(binary.LittleEndian.Uint32(buf[:])))) >= at {
+			n++
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return 0, fmt.Errorf("ffmpeg decode: %w", err)
+	}
+	return n, nil
+}
+
+// pcmCodec keeps a WAV output at the source's resolution; ffmpeg would
+// otherwise write 16-bit whatever came in. Other containers use their
+// default encoder.
+func pcmCodec(out string, p ProbeInfo) []string {
+	if strings.ToLower(filepath.Ext(out)) != ".wav" {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(p.SampleFmt, "flt") || strings.HasPrefix(p.SampleFmt, "dbl"):
+		return []string{"-c:a", "pcm_f32le"}
+	case p.BitDepth > 16:
+		return []string{"-c:a", "pcm_s24le"}
+	}
+	return []string{"-c:a", "pcm_s16le"}
+}
+
+// declip repairs in into out and measures both. The analysis passes that
+// have nothing to say about clipping (aubio, fingerprinting) are skipped.
+func declip(cfg *Config, in, out string, headroom float64, declick bool) (*DeclipReport, error) {
+	qc := *cfg
+	qc.BPMEngine, qc.Fingerprint = "none", false
+	before, err := analyzeFile(&qc, in)
+	if err != nil {
+		return nil, err
+	}
+	if before.Silent || before.Level.ClipSamples == nil || *before.Level.ClipSamples == 0 {
+		return nil, fmt.Errorf("%s: no clipped samples detected; nothing to repair", in)
+	}
+	ws, err := windowedAstats(&qc, in, before.Probe.SampleRate, declipWindowSec)
+	if err != nil {
+		return nil, err
+	}
+	dur := before.Probe.Duration
+	if before.Decoded != nil {
+		dur = *before.Decoded
+	}
+	sections := clipSections(ws, before.Level.PeakDB, declipWindowSec, dur)
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("%s: clipping reported but no window reaches the ceiling", in)
+	}
+	chain := declipChain(sections, headroom, declick)
+	if err := ensureParent(out); err != nil {
+		return nil, err
+	}
+	args := rangeArgs(cfg, []string{"-y", "-hide_banner", "-nostats", "-loglevel", "error", "-i", in, "-vn", "-map", streamSpec(cfg), "-af", chain})
+	args = append(append(args, pcmCodec(out, before.Probe)...), out)
+	if o, err := runCmd(cfg.FFmpegBin, args...); err != nil {
+		return nil, fmt.Errorf("ffmpeg declip: %w\n%s", err, o)
+	}
+	wrote(out)
+	// the output holds just the repaired stream and range
+	qc.Start, qc.End, qc.Stream = 0, 0, 0
+	after, err := analyzeFile(&qc, out)
+	if err != nil {
+		return nil, fmt.Errorf("re-analyze %s: %w", out, err)
+	}
+	r := &DeclipReport{In: in, Out: out, Filter: chain, Sections: sections, HeadroomDB: headroom, CeilingDB: before.Level.PeakDB,
+		Before: declipStats(before, 0), After: declipStats(after, headroom)}
+	// clipped samples are counted at the ceiling, not at full scale, which
+	// the lowered output never reaches
+	if n, err := ceilingSamples(cfg, in, r.CeilingDB); err == nil {
+		r.Before.ClipSamples = &n
+	} else {
+		warnf("%s: clipped samples: %v", in, err)
+	}
+	if n, err := ceilingSamples(&qc, out, r.CeilingDB-headroom); err == nil {
+		r.After.ClipSamples = &n
+	} else {
+		warnf("%s: clipped samples: %v", out, err)
+	}
+	return r, nil
+}
+
+// declipStats takes a's levels with gain dB added back; clipped samples
+// are counted separately, by ceilingSamples
+func declipStats(a *Analysis, gain float64) DeclipStats {
+	st := DeclipStats{PeakDB: a.Level.PeakDB + gain, Clicks: a.Level.Clicks}
+	if tp := a.Level.TruePeakDBTP; tp != nil {
+		v := *tp + gain
+		st.TruePeakDBTP = &v
+	}
+	return st
+}
+
+func renderDeclip(cfg *Config, r *DeclipReport) (string, error) {
+	p := cfg.Precision
+	clips := func(v *int64) string {
+		if v == nil {
+			return "n/a"
+		}
+		return fmt.Sprintf("%d", *v)
+	}
+	var b strings.Builder
+	switch strings.ToLower(cfg.Report) {
+	case "json", "yaml":
+		return renderJSON(cfg, r)
+	case "md":
+		fmt.Fprintf(&b, "# Declip: %s\n\n- Output: `%s`\n- Sections: `%d`\n- Headroom: `%s dB`, applied to the output\n\n", filepath.Base(r.In), r.Out, len(r.Sections), p.db(r.HeadroomDB))
+		fmt.Fprintf(&b, "After is measured with the %s dB added back; clipped samples are those at the input's ceiling (%s dBFS).\n\n", p.db(r.HeadroomDB), p.db(r.CeilingDB))
+		fmt.Fprintf(&b, "| | Before | After |\n|---|---:|---:|\n")
+		fmt.Fprintf(&b, "| Clipped samples | %s | %s |\n", clips(r.Before.ClipSamples), clips(r.After.ClipSamples))
+		fmt.Fprintf(&b, "| Peak (dBFS) | %s | %s |\n", p.db(r.Before.PeakDB), p.db(r.After.PeakDB))
+		fmt.Fprintf(&b, "| True peak (dBTP) | %s | %s |\n", fmtOpt(r.Before.TruePeakDBTP, p.db), fmtOpt(r.After.TruePeakDBTP, p.db))
+		if r.Before.Clicks != nil || r.After.Clicks != nil {
+			fmt.Fprintf(&b, "| Clicks | %s | %s |\n", clips(r.Before.Clicks), clips(r.After.Clicks))
+		}
+	default:
+		fmt.Fprintf(&b, "DECLIP: %s -> %s\n\n", r.In, r.Out)
+		fmt.Fprintf(&b, "Sections (%d):", len(r.Sections))
+		for _, s := range r.Sections {
+			fmt.Fprintf(&b, " %ss-%ss", p.sec(s.Start), p.sec(s.End))
+		}
+		fmt.Fprintf(&b, "\nHeadroom: %s dB, applied to the output; after is measured with it added back\n", p.db(r.HeadroomDB))
+		fmt.Fprintf(&b, "Clipped samples are those at the input's ceiling, %s dBFS\n\n", p.db(r.CeilingDB))
+		fmt.Fprintf(&b, "%-18s %12s %12s\n", "", "before", "after")
+		fmt.Fprintf(&b, "%-18s %12s %12s\n", "Clipped samples", clips(r.Before.ClipSamples), clips(r.After.ClipSamples))
+		fmt.Fprintf(&b, "%-18s %12s %12s\n", "Peak dBFS", p.db(r.Before.PeakDB), p.db(r.After.PeakDB))
+		fmt.Fprintf(&b, "%-18s %12s %12s\n", "True peak dBTP", fmtOpt(r.Before.TruePeakDBTP, p.db), fmtOpt(r.After.TruePeakDBTP, p.db))
+		if r.Before.Clicks != nil || r.After.Clicks != nil {
+			fmt.Fprintf(&b, "%-18s %12s %12s\n", "Clicks", clips(r.Before.Clicks), clips(r.After.Clicks))
+		}
+	}
+	return b.String(), nil
+}
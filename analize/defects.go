@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// defect scan thresholds. A click is a second-difference spike far above
+// its recent average; dropouts (exact digital zero) and glitches (a stalled
+// buffer repeating one non-zero value) must last defectMinSec and sit
+// between audio above defectLevelDB, so gaps between tracks and fade-outs
+// are left to silencedetect.
+const (
+	clickRatio     = 12.0  // spike over the running RMS of the second difference
+	clickMinAmp    = 0.02  // and at least this big (-34 dBFS), so noise-floor ticks pass
+	clickAvgSec    = 0.02  // running RMS time constant
+	clickMergeSec  = 0.005 // spikes this close are one click
+	defectMinSec   = 0.001
+	defectCtxSec   = 0.05 // level measured this long before and after a gap
+	defectLevelDB  = -50.0
+	defectMaxList  = 200  // listed events; counts keep going
+	holdMaxAbs     = 0.98 // a repeated value this loud is clipping, not a glitch
+	defectErrorAmp = 0.25 // clicks above -12 dBFS, gaps above 50 ms: error
+	defectErrorSec = 0.05
+)
+
+const defectsChain = "aformat=sample_fmts=flt"
+
+// channelScan is one channel's running state
+type channelScan struct {
+	x1, x2      float64 // previous two samples
+	ms          float64 // running mean square of the second difference
+	lastClick   int64   // sample of the last click, for merging
+	pre         float64 // running mean square of the signal, the "before" level
+	run         int64   // length of the current run of equal samples
+	runV        float64
+	runAt       int64
+	pending     *Defect // gap waiting for its "after" level
+	mute        int64   // no clicks before this sample: warm-up, and the edge after a gap
+	post, postN float64
+}
+
+// scanDefects decodes every channel to float and walks the samples once,
+// reporting clicks, dropouts and glitches with the time and channel they
+// happen on. Times are relative to the start of the analyzed range.
+func scanDefects(cfg *Config, in string, sampleRate, channels int) (*Defects, error) {
+	if sampleRate <= 0 || channels <= 0 {
+		return nil, fmt.Errorf("unknown sample rate or channel count")
+	}
+	cmd := exec.Command(cfg.FFmpegBin, rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-loglevel", "error",
+		"-i", in, "-vn", "-map", streamSpec(cfg), "-af", defectsChain, "-f", "f32le", "-acodec", "pcm_f32le", "-"})...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	acquireProc()
+	defer releaseProc()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	sr := float64(sampleRate)
+	alphaClick, alphaPre := 1/(clickAvgSec*sr), 1/(defectCtxSec*sr)
+	minRun, ctxN := int64(math.Ceil(defectMinSec*sr)), defectCtxSec*sr
+	merge := int64(clickMergeSec * sr)
+	floor := math.Pow(10, defectLevelDB/10) // mean square of a -50 dBFS RMS signal
+
+	d := &Defects{}
+	lastEvent := int64(-1) // last click on any channel, for clickEvents
+	add := func(e Defect) {
+		switch e.Kind {
+		case "click":
+			d.Clicks++
+		case "dropout":
+			d.Dropouts++
+		default:
+			d.Glitches++
+		}
+		if len(d.Events) < defectMaxList {
+			d.Events = append(d.Events, e)
+		}
+	}
+	// a run of equal samples ended at sample n: keep it if long enough and
+	// loud before; the "after" level is checked over the next ctx samples
+	endRun := func(s *channelScan, ch int, n int64) {
+		if s.run < minRun || s.pre < floor || (s.runV != 0 && math.Abs(s.runV) >= holdMaxAbs) {
+			return
+		}
+		kind := "glitch"
+		if s.runV == 0 {
+			kind = "dropout"
+		}
+		dur := float64(s.run) / sr
+		s.pending = &Defect{Time: float64(s.runAt) / sr, Channel: ch + 1, Kind: kind, Duration: dur, LevelDB: 10 * math.Log10(s.pre)}
+		s.post, s.postN = 0, 0
+	}
+
+	scans := make([]channelScan, channels)
+	warm := int64(clickAvgSec * sr)
+	for i := range scans {
+		scans[i].mute = warm
+	}
+	r := bufio.NewReaderSize(stdout, 1<<16)
+	frame := make([]byte, 4*channels)
+	var n int64
+	for ; ; n++ {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			break
+		}
+		for ch := range scans {
+			s := &scans[ch]
+			x := float64(math.Float32frombits(binary.LittleEndian.Uint32(frame[4*ch:])))
+			if s.pending != nil {
+				s.post += x * x
+				if s.postN++; s.postN >= ctxN {
+					if s.post/s.postN >= floor {
+						s.pending.Severity = gapSeverity(s.pending.Duration)
+						add(*s.pending)
+					}
+					s.pending = nil
+				}
+			}
+			if n > 0 && x == s.runV {
+				s.run++
+			} else {
+				if s.run >= minRun {
+					s.mute = n + warm
+				}
+				endRun(s, ch, n)
+				s.run, s.runV, s.runAt = 1, x, n
+				s.pre += alphaPre * (x*x - s.pre)
+			}
+			if n >= 2 {
+				c := x - 2*s.x1 + s.x2
+				if a := math.Abs(c); n >= s.mute && a > clickMinAmp && a > clickRatio*math.Sqrt(s.ms) && (s.lastClick == 0 || n-s.lastClick > merge) {
+					sev := SevWarn
+					if a >= defectErrorAmp {
+						sev = SevError
+					}
+					add(Defect{Time: float64(n) / sr, Channel: ch + 1, Kind: "click", Severity: sev, LevelDB: 20 * math.Log10(a)})
+					s.lastClick = n
+					if lastEvent < 0 || n-lastEvent > merge {
+						d.clickEvents++
+					}
+					lastEvent = n
+				}
+				s.ms += alphaClick * (c*c - s.ms)
+			}
+			s.x1, s.x2 = x, s.x1
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg decode: %w", err)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("no samples decoded")
+	}
+	// gaps are added once their "after" context is in, behind later clicks
+	sort.SliceStable(d.Events, func(i, j int) bool { return d.Events[i].Time < d.Events[j].Time })
+	return d, nil
+}
+
+func gapSeverity(dur float64) Severity {
+	if dur >= defectErrorSec {
+		return SevError
+	}
+	return SevWarn
+}
+
+// defectNotes summarizes the scan; the timestamps are in the Defects section
+func defectNotes(d *Defects) []Note {
+	if d == nil || d.Clicks+d.Dropouts+d.Glitches == 0 {
+		return nil
+	}
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		what string
+	}{{d.Clicks, "clicks"}, {d.Dropouts, "dropouts"}, {d.Glitches, "glitches (stalled buffer)"}} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.what))
+		}
+	}
+	sev := SevWarn
+	for _, e := range d.Events {
+		if e.Severity == SevError {
+			sev = SevError
+			break
+		}
+	}
+	return []Note{newNote(sev, "DEFECTS", "Defects found: %s; first at %.3fs.", strings.Join(parts, ", "), d.Events[0].Time)}
+}
@@ -1,18 +1,69 @@
 package main
 
+import "math"
+
+// metric keys shared by compare and stability, in report order
+var diffKeys = func() []string {
+	var ks []string
+	for _, m := range metricRegistry {
+		if m.Compare {
+			ks = append(ks, m.Name)
+		}
+	}
+	return ks
+}()
+
+func metricValues(a *Analysis) map[string]float64 {
+	m := map[string]float64{}
+	for _, def := range metricRegistry {
+		if !def.Compare {
+			continue
+		}
+		if v, ok := def.Value(a); ok {
+			m[def.Name] = v
+		}
+	}
+	return m
+}
+
 func compare(a, b *Analysis) *Diff {
 	d := &Diff{A: a, B: b, Delta: map[string]float64{}}
-	d.Delta["peak_db"] = b.Level.PeakDB - a.Level.PeakDB
-	d.Delta["rms_db"] = b.Level.RMSDB - a.Level.RMSDB
-	d.Delta["crest_db"] = b.Level.CrestDB - a.Level.CrestDB
-	if a.Loudness != nil && b.Loudness != nil {
-		d.Delta["lufs_integrated"] = b.Loudness.Integrated - a.Loudness.Integrated
-		d.Delta["lufs_range"] = b.Loudness.Range - a.Loudness.Range
+	ma, mb := metricValues(a), metricValues(b)
+	for k, va := range ma {
+		if vb, ok := mb[k]; ok {
+			d.Delta[k] = vb - va
+		}
 	}
-	d.Delta["stereo_side_mid_db"] = b.Stereo.SideMidRatioDB - a.Stereo.SideMidRatioDB
-	if a.Tempo != nil && b.Tempo != nil && a.Tempo.BPMMedian != nil && b.Tempo.BPMMedian != nil {
-		d.Delta["bpm_median"] = *b.Tempo.BPMMedian - *a.Tempo.BPMMedian
-	}
-	d.Delta["duration_s"] = b.Probe.Duration - a.Probe.Duration
 	return d
 }
+
+// stability reports the spread of each metric across repeated captures
+func stability(as []*Analysis) *Stability {
+	s := &Stability{}
+	vals := make([]map[string]float64, len(as))
+	for i, a := range as {
+		s.Files = append(s.Files, a.File)
+		s.Rows = append(s.Rows, summaryRow(a))
+		vals[i] = metricValues(a)
+	}
+	for _, k := range diffKeys {
+		var xs []float64
+		for _, m := range vals {
+			if v, ok := m[k]; ok && !math.IsNaN(v) && !math.IsInf(v, 0) {
+				xs = append(xs, v)
+			}
+		}
+		if len(xs) < 2 {
+			continue
+		}
+		mu := mean(xs)
+		ms := MetricSpread{Name: k, Mean: mu, Std: stddev(xs, mu), Min: xs[0], Max: xs[0]}
+		for _, v := range xs[1:] {
+			ms.Min = math.Min(ms.Min, v)
+			ms.Max = math.Max(ms.Max, v)
+		}
+		ms.Range = ms.Max - ms.Min
+		s.Metrics = append(s.Metrics, ms)
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// DR (TT Dynamic Range meter style) works on 3 s blocks: the second
+// loudest block peak over the RMS of the loudest 20% of blocks, with RMS
+// scaled by √2 so a full-scale sine reads 0 dB crest
+const (
+	drWindowSec = 3.0
+	drTopShare  = 0.2
+)
+
+// drScore is the DR14-style dynamic range of a 3 s peak/RMS envelope,
+// measured on the overall (all-channel) levels rather than per channel;
+// nil without windows
+func drScore(ws []WindowStat) *int {
+	if len(ws) == 0 {
+		return nil
+	}
+	peaks := make([]float64, len(ws))
+	rms := make([]float64, len(ws))
+	for i, w := range ws {
+		peaks[i] = w.PeakDB
+		rms[i] = math.Pow(10, w.RMSDB/20) * math.Sqrt2
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(peaks)))
+	sort.Sort(sort.Reverse(sort.Float64Slice(rms)))
+	peak := peaks[0]
+	if len(peaks) > 1 {
+		peak = peaks[1]
+	}
+	n := max(1, int(float64(len(rms))*drTopShare))
+	var sum float64
+	for _, r := range rms[:n] {
+		sum += r * r
+	}
+	top := math.Sqrt(sum / float64(n))
+	if top <= 0 {
+		return nil
+	}
+	dr := int(math.Round(peak - 20*math.Log10(top)))
+	return &dr
+}
+
+// peakToLoudness fills PLR (peak minus integrated loudness) and PSR (peak
+// minus the loudest short-term loudness); both use true peak when measured
+func peakToLoudness(lv *LevelStats, lufs *LUFS) {
+	if lufs == nil {
+		return
+	}
+	peak := lv.PeakDB
+	if lv.TruePeakDBTP != nil {
+		peak = *lv.TruePeakDBTP
+	}
+	plr := peak - lufs.Integrated
+	lv.PLR = &plr
+	if lufs.MaxShortTerm != nil {
+		psr := peak - *lufs.MaxShortTerm
+		lv.PSR = &psr
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// -explain: plain-language readings of the txt report's numbers. The
+// thresholds are rules of thumb for music/speech masters, not standards:
+//
+//	crest          <8 dB heavily compressed, <12 compressed, <18 natural, else very dynamic
+//	true peak      > -1 dBTP risks inter-sample clipping after lossy encoding
+//	noise floor    > -60 dBFS audible hiss/noise, < -90 dBFS very clean
+//	DC offset      |dc| > 0.01 worth removing
+//	integrated     > -9 very loud, > -14 loud, > -18 streaming range, > -24 broadcast range, else quiet
+//	loudness range <4 LU very even, <8 typical pop, <15 moderate, else wide (film/classical)
+//	correlation    <0 out of phase, <0.2 very wide/phasey, <0.7 wide, else mono-safe
+//	side/mid       < -20 dB practically mono
+//	centroid       <1500 Hz dark/warm, <3000 Hz balanced, else bright
+//	flatness       >0.5 noise-like, <0.1 tonal
+//	BPM std        >5 BPM drifting or rubato tempo
+func explainCrest(v float64) string {
+	switch {
+	case v < 8:
+		return "heavily compressed/limited"
+	case v < 12:
+		return "compressed"
+	case v < 18:
+		return "natural dynamics"
+	}
+	return "very dynamic"
+}
+
+func explainTruePeak(v float64) string {
+	if v > -1 {
+		return "may clip after lossy encoding"
+	}
+	return "safe for lossy encoding"
+}
+
+func explainNoiseFloor(v float64) string {
+	switch {
+	case v > -60:
+		return "audible noise/hiss"
+	case v < -90:
+		return "very clean"
+	}
+	return "typical"
+}
+
+func explainIntegrated(v float64) string {
+	switch {
+	case v > -9:
+		return "very loud master"
+	case v > -14:
+		return "loud; streaming services will turn it down"
+	case v > -18:
+		return "streaming range"
+	case v > -24:
+		return "broadcast range"
+	}
+	return "quiet"
+}
+
+func explainLRA(v float64) string {
+	switch {
+	case v < 4:
+		return "very even level"
+	case v < 8:
+		return "typical pop/rock"
+	case v < 15:
+		return "moderate dynamics"
+	}
+	return "wide dynamics (film/classical)"
+}
+
+func explainCorrelation(v float64) string {
+	switch {
+	case v < 0:
+		return "out of phase; collapses badly in mono"
+	case v < 0.2:
+		return "very wide or phasey"
+	case v < 0.7:
+		return "wide stereo"
+	}
+	return "mono-compatible"
+}
+
+func explainCentroid(v float64) string {
+	switch {
+	case v < 1500:
+		return "dark/warm"
+	case v < 3000:
+		return "balanced"
+	}
+	return "bright"
+}
+
+func explainFlatness(v float64) string {
+	switch {
+	case v > 0.5:
+		return "noise-like"
+	case v < 0.1:
+		return "tonal"
+	}
+	return "mixed tonal/noisy"
+}
+
+// writeExplainTXT appends one indented reading per metric of a section
+func writeExplainTXT(b *strings.Builder, p Precision, a *Analysis, section string) {
+	var lines []string
+	add := func(format string, args ...any) { lines = append(lines, fmt.Sprintf(format, args...)) }
+	switch section {
+	case "levels":
+		add("Crest %s dB: %s", p.db(a.Level.CrestDB), explainCrest(a.Level.CrestDB))
+		if a.Level.TruePeakDBTP != nil {
+			add("TruePeak %s dBTP: %s", p.db(*a.Level.TruePeakDBTP), explainTruePeak(*a.Level.TruePeakDBTP))
+		}
+		add("NoiseFloor %s dBFS: %s", p.db(a.Level.NoiseFloor), explainNoiseFloor(a.Level.NoiseFloor))
+		if math.Abs(a.Level.DCOffset) > 0.01 {
+			add("DC %.4f: offset worth removing", a.Level.DCOffset)
+		}
+	case "loudness":
+		if a.Loudness == nil {
+			return
+		}
+		add("Integrated %s %s: %s", p.lufs(a.Loudness.Integrated), loudnessUnit(a.Loudness), explainIntegrated(a.Loudness.Integrated))
+		add("Range %s LU: %s", p.lufs(a.Loudness.Range), explainLRA(a.Loudness.Range))
+	case "stereo":
+		if a.Stereo.Correlation != nil {
+			add("Corr %s: %s", p.corr(*a.Stereo.Correlation), explainCorrelation(*a.Stereo.Correlation))
+		}
+		if a.Stereo.SideMidRatioDB < -20 {
+			add("Side/Mid %s dB: practically mono", p.db(a.Stereo.SideMidRatioDB))
+		}
+	case "spectral":
+		if a.Spectral.Centroid != nil {
+			add("Centroid %s Hz: %s", p.hz(*a.Spectral.Centroid), explainCentroid(*a.Spectral.Centroid))
+		}
+		if a.Spectral.Flatness != nil {
+			add("Flatness %s: %s", p.shape(*a.Spectral.Flatness), explainFlatness(*a.Spectral.Flatness))
+		}
+	case "tempo":
+		if a.Tempo != nil && a.Tempo.BPMStd != nil && *a.Tempo.BPMStd > 5 {
+			add("BPM std %s: drifting or rubato tempo", p.bpm(*a.Tempo.BPMStd))
+		}
+	}
+	for _, l := range lines {
+		fmt.Fprintf(b, "  ↳ %s\n", l)
+	}
+}
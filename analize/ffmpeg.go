@@ -4,28 +4,43 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
 func ffprobeInfo(cfg *Config, in string) (ProbeInfo, error) {
-	args := []string{"-v", "error", "-show_format", "-show_streams", "-of", "json", in}
+	args := []string{"-v", "error", "-show_format", "-show_streams", "-show_data", "-of", "json", in}
 	out, err := runCmd(cfg.FFprobeBin, args...)
 	if err != nil {
 		return ProbeInfo{}, fmt.Errorf("ffprobe: %v", err)
 	}
 	type ffFmt struct {
 		Format struct {
-			FormatName string `json:"format_name"`
-			Duration   string `json:"duration"`
-			BitRate    string `json:"bit_rate"`
+			FormatName string            `json:"format_name"`
+			Duration   string            `json:"duration"`
+			BitRate    string            `json:"bit_rate"`
+			Tags       map[string]string `json:"tags"`
 		} `json:"format"`
 		Streams []struct {
 			CodecType        string `json:"codec_type"`
+			CodecName        string `json:"codec_name"`
+			SampleFmt        string `json:"sample_fmt"`
 			SampleRate       string `json:"sample_rate"`
 			Channels         int    `json:"channels"`
+			ChannelLayout    string `json:"channel_layout"`
 			BitsPerRawSample string `json:"bits_per_raw_sample"`
 			BitsPerSample    int    `json:"bits_per_sample"`
+			StartTime        string `json:"start_time"`
+			Duration         string `json:"duration"`
+			Extradata        string `json:"extradata"`
+			Width            int    `json:"width"`
+			Height           int    `json:"height"`
+			Disposition      struct {
+				AttachedPic int `json:"attached_pic"`
+			} `json:"disposition"`
+			Tags map[string]string `json:"tags"`
 		} `json:"streams"`
 	}
 	var ff ffFmt
@@ -37,64 +52,362 @@ func ffprobeInfo(cfg *Config, in string) (ProbeInfo, error) {
 		Duration:   parseFloat(ff.Format.Duration),
 		BitRate:    int64(parseInt(ff.Format.BitRate)),
 	}
+	// Matroska leaves stream durations to a DURATION tag
+	streamDur := func(d string, tags map[string]string) *float64 {
+		v := parseFloat(d)
+		if v <= 0 {
+			v, _ = parseClock(tags["DURATION"])
+		}
+		if v <= 0 {
+			return nil
+		}
+		return &v
+	}
+	var tracks []AudioTrack
+	p.Tags = map[string]string{}
+	for k, v := range ff.Format.Tags {
+		p.Tags[strings.ToLower(k)] = v
+	}
 	for _, s := range ff.Streams {
-		if s.CodecType == "audio" {
+		// cover art shows up as a one-frame video stream
+		if s.CodecType == "video" && s.Disposition.AttachedPic == 0 && p.Video == nil {
+			p.Video = &VideoInfo{Codec: s.CodecName, Width: s.Width, Height: s.Height, Duration: streamDur(s.Duration, s.Tags)}
+		}
+		if s.CodecType != "audio" {
+			continue
+		}
+		tracks = append(tracks, AudioTrack{Stream: len(tracks), Codec: s.CodecName, Language: s.Tags["language"],
+			Channels: s.Channels, Layout: s.ChannelLayout, Duration: streamDur(s.Duration, s.Tags)})
+		if len(tracks)-1 == cfg.Stream {
+			for k, v := range s.Tags {
+				p.Tags[strings.ToLower(k)] = v
+			}
+			p.CodecName = s.CodecName
+			p.SampleFmt = s.SampleFmt
 			p.SampleRate = parseInt(s.SampleRate)
+			// containers with video report the longest stream; prefer the audio one
+			if d := streamDur(s.Duration, s.Tags); d != nil {
+				p.Duration = *d
+			}
 			p.Channels = s.Channels
+			p.Layout = s.ChannelLayout
 			if s.BitsPerSample > 0 {
 				p.BitDepth = s.BitsPerSample
 			} else if s.BitsPerRawSample != "" {
 				p.BitDepth = parseInt(s.BitsPerRawSample)
 			}
-			break
+			p.EncoderDelay, p.EncoderPadding = encoderDelayPadding(ff.Format.Tags, s.Tags, s.StartTime, p.SampleRate)
+			if s.CodecName == "opus" {
+				p.OpusOutputGainDB, p.OpusMappingFamily = opusHead(parseHexdump(s.Extradata))
+			}
 		}
 	}
+	n := len(tracks)
+	if cfg.Stream >= n && n > 0 {
+		return ProbeInfo{}, fmt.Errorf("no audio stream %d (file has %d)", cfg.Stream, n)
+	}
+	p.Stream, p.AudioStreams = cfg.Stream, n
+	if p.Video != nil || n > 1 {
+		p.Tracks = tracks
+	}
 	return p, nil
 }
 
+// output gain (Q7.8 dB) and mapping family from an OpusHead packet:
+// magic(8) version(1) channels(1) pre-skip(2) rate(4) gain(2) family(1)
+func opusHead(b []byte) (gainDB *float64, family *int) {
+	if len(b) < 19 || string(b[:8]) != "OpusHead" {
+		return nil, nil
+	}
+	g := float64(int16(uint16(b[16])|uint16(b[17])<<8)) / 256
+	f := int(b[18])
+	return &g, &f
+}
+
+// bytes from ffprobe's -show_data hexdump ("00000000: 4f70 7573 ...  OpusHead")
+func parseHexdump(s string) []byte {
+	var out []byte
+	for _, line := range strings.Split(s, "\n") {
+		_, rest, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		if i := strings.Index(rest, "  "); i >= 0 {
+			rest = rest[:i] // drop the ascii column
+		}
+		for _, grp := range strings.Fields(rest) {
+			for j := 0; j+1 < len(grp); j += 2 {
+				v, err := strconv.ParseUint(grp[j:j+2], 16, 8)
+				if err != nil {
+					return out
+				}
+				out = append(out, byte(v))
+			}
+		}
+	}
+	return out
+}
+
+// encoder delay/padding (samples) from iTunSMPB, falling back to the
+// stream start_time that ffmpeg derives from the LAME/Xing header
+func encoderDelayPadding(fmtTags, streamTags map[string]string, startTime string, sr int) (delay, padding *int) {
+	for _, tags := range []map[string]string{streamTags, fmtTags} {
+		for k, v := range tags {
+			if !strings.EqualFold(k, "iTunSMPB") {
+				continue
+			}
+			f := strings.Fields(v)
+			if len(f) < 3 {
+				continue
+			}
+			d, err1 := strconv.ParseInt(f[1], 16, 64)
+			pd, err2 := strconv.ParseInt(f[2], 16, 64)
+			if err1 == nil && err2 == nil {
+				di, pi := int(d), int(pd)
+				return &di, &pi
+			}
+		}
+	}
+	if st := parseFloat(startTime); st > 0 && sr > 0 {
+		d := int(math.Round(st * float64(sr)))
+		return &d, nil
+	}
+	return nil, nil
+}
+
+func volumedetectChain(cfg *Config) string { return sumFilter(cfg, "volumedetect") }
+
 func ffmpegVolumedetect(cfg *Config, in string) (peakDB, rmsDB float64, err error) {
-	args := []string{"-hide_banner", "-nostats", "-vn", "-i", in, "-af", "volumedetect", "-f", "null", "-"}
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-af", volumedetectChain(cfg), "-f", "null", "-"})
 	out, _ := runCmd(cfg.FFmpegBin, args...)
-	reMax := regexp.MustCompile(`max_volume:\s*([-\d\.]+)\s*dB`)
-	reMean := regexp.MustCompile(`mean_volume:\s*([-\d\.]+)\s*dB`)
-	m1 := reMax.FindStringSubmatch(out)
-	m2 := reMean.FindStringSubmatch(out)
-	if len(m1) < 2 || len(m2) < 2 {
-		return 0, 0, fmt.Errorf("volumedetect parse failed")
+	return parseVolumedetect(out)
+}
+
+func astatsChain(cfg *Config) string { return sumFilter(cfg, "astats=measure_overall=1:reset=0") }
+
+// parseAstats splits astats log output into the overall section and one map
+// per channel. Keys are lowercased with spaces as underscores ("rms_level_db").
+// Both "Overall Key: v" lines and the "Overall" section header are accepted.
+func parseAstats(out string) (overall map[string]float64, channels []map[string]float64) {
+	reSec := regexp.MustCompile(`\]\s*(Channel:\s*\d+|Overall)\s*$`)
+	reKV := regexp.MustCompile(`\]\s*(Overall )?([A-Za-z0-9 /\-]+):\s*(-?inf|nan|[-\d\.]+)`)
+	overall = map[string]float64{}
+	var cur map[string]float64
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.Contains(line, "astats") {
+			continue
+		}
+		if m := reSec.FindStringSubmatch(line); len(m) == 2 {
+			if m[1] == "Overall" {
+				cur = overall
+			} else {
+				cur = map[string]float64{}
+				channels = append(channels, cur)
+			}
+			continue
+		}
+		m := reKV.FindStringSubmatch(line)
+		if len(m) != 4 {
+			continue
+		}
+		key := strings.TrimSpace(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(m[2]), " ", "_")))
+		// skip -inf/nan (e.g. noise floor of digital silence): they can't be
+		// marshalled to JSON and mean "not measurable" anyway
+		if strings.Contains(m[3], "inf") || m[3] == "nan" {
+			continue
+		}
+		v := parseFloat(m[3])
+		switch {
+		case m[1] != "":
+			overall[key] = v
+		case cur != nil:
+			cur[key] = v
+		}
 	}
-	return parseFloat(m1[1]), parseFloat(m2[1]), nil
+	return overall, channels
 }
 
-// generic astats parser (overall)
-func ffmpegAstatsOverall(cfg *Config, in string, windowSec float64) (map[string]float64, error) {
-	filter := "astats=measure_overall=1:reset=0"
-	if windowSec > 0 {
-		filter = fmt.Sprintf("astats=measure_overall=1:metadata=1:reset=1:window=%0.2f", windowSec)
+// parseAstatsPeak is the overall "Peak level dB" of an astats log. astats
+// works on float samples as they are, so unlike volumedetect (s16 only) a
+// peak above full scale reads > 0 dBFS. Digital silence (-inf, dropped by
+// parseAstats) reads as the silent floor.
+func parseAstatsPeak(out string) (float64, error) {
+	ov, chans := parseAstats(out)
+	if len(ov) == 0 && len(chans) == 0 {
+		return 0, fmt.Errorf("astats parse failed")
+	}
+	if v, ok := ov["peak_level_db"]; ok {
+		return v, nil
 	}
-	args := []string{"-hide_banner", "-nostats", "-vn", "-i", in, "-af", filter, "-f", "null", "-"}
+	return silentFloorDB, nil
+}
+
+// fixed-size frames of windowSec, astats reset on every frame, and the
+// per-frame overall peak/RMS printed to the log
+func windowedAstatsChain(cfg *Config, sampleRate int, windowSec float64) string {
+	n := max(1, int(math.Round(float64(sampleRate)*windowSec)))
+	return sumFilter(cfg, fmt.Sprintf("asetnsamples=n=%d:p=0,astats=metadata=1:reset=1,"+
+		"ametadata=mode=print:key=lavfi.astats.Overall.Peak_level,"+
+		"ametadata=mode=print:key=lavfi.astats.Overall.RMS_level", n))
+}
+
+// windowedAstats returns peak/RMS per window of windowSec seconds of the
+// -stream audio stream; the envelope behind DR, crest-over-time and similar
+// measurements
+func windowedAstats(cfg *Config, in string, sampleRate int, windowSec float64) ([]WindowStat, error) {
+	if windowSec <= 0 || sampleRate <= 0 {
+		return nil, fmt.Errorf("windowed astats needs a window and sample rate")
+	}
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-map", streamSpec(cfg), "-af", windowedAstatsChain(cfg, sampleRate, windowSec), "-f", "null", "-"})
 	out, _ := runCmd(cfg.FFmpegBin, args...)
-	re := regexp.MustCompile(`Overall ([A-Za-z0-9 /\-]+):\s*([-\d\.]+)`)
-	stats := map[string]float64{}
+	ws := parseWindowedAstats(out)
+	if len(ws) == 0 {
+		return nil, fmt.Errorf("no astats windows parsed")
+	}
+	return ws, nil
+}
+
+// each ametadata print is a "frame:N pts:P pts_time:T" header followed by
+// key=value lines; the two chained prints share pts_time per window
+func parseWindowedAstats(out string) []WindowStat {
+	reT := regexp.MustCompile(`pts_time:\s*([-\d\.]+)`)
+	reKV := regexp.MustCompile(`lavfi\.astats\.Overall\.(Peak_level|RMS_level)=(-?inf|nan|[-\d\.]+)`)
+	var ws []WindowStat
+	byT := map[string]int{}
+	t := ""
 	sc := bufio.NewScanner(strings.NewReader(out))
 	for sc.Scan() {
 		line := sc.Text()
-		if m := re.FindStringSubmatch(line); len(m) == 3 {
-			key := strings.TrimSpace(strings.ToLower(strings.ReplaceAll(m[1], " ", "_")))
-			stats[key] = parseFloat(m[2])
+		if m := reT.FindStringSubmatch(line); len(m) == 2 {
+			t = m[1]
+			if _, ok := byT[t]; !ok {
+				byT[t] = len(ws)
+				ws = append(ws, WindowStat{Time: parseFloat(t), PeakDB: silentFloorDB, RMSDB: silentFloorDB})
+			}
+			continue
+		}
+		m := reKV.FindStringSubmatch(line)
+		if len(m) != 3 || t == "" {
+			continue
+		}
+		v := silentFloorDB // -inf: digital silence in this window
+		if !strings.Contains(m[2], "inf") && m[2] != "nan" {
+			v = math.Max(parseFloat(m[2]), silentFloorDB)
+		}
+		if m[1] == "Peak_level" {
+			ws[byT[t]].PeakDB = v
+		} else {
+			ws[byT[t]].RMSDB = v
 		}
 	}
-	if len(stats) == 0 {
-		return stats, fmt.Errorf("no astats parsed")
+	return ws
+}
+
+// float, oversampled astats: per-channel peaks approximate true peak the
+// way BS.1770 does (4x), but per channel instead of ebur128's single maximum,
+// and without needing the ebur128 filter at all; -tp-oversample 8 tightens
+// the estimate for material near the Nyquist frequency
+func truePeakChain(cfg *Config, sampleRate int) string {
+	return sumFilter(cfg, fmt.Sprintf("aformat=sample_fmts=flt,aresample=%d,astats=reset=0", cfg.Oversample*sampleRate))
+}
+
+func ffmpegChannelTruePeaks(cfg *Config, in string, sampleRate int) ([]*float64, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("unknown sample rate")
 	}
-	return stats, nil
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-af", truePeakChain(cfg, sampleRate), "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	_, chans := parseAstats(out)
+	if len(chans) == 0 {
+		return nil, fmt.Errorf("no per-channel astats parsed")
+	}
+	tps := make([]*float64, len(chans))
+	for i, ch := range chans {
+		if v, ok := ch["peak_level_db"]; ok {
+			tps[i] = &v
+		}
+	}
+	return tps, nil
+}
+
+// maxPeak is the highest of the per-channel peaks; nil if none measured
+func maxPeak(tps []*float64) *float64 {
+	var m *float64
+	for _, tp := range tps {
+		if tp != nil && (m == nil || *tp > *m) {
+			m = tp
+		}
+	}
+	return m
+}
+
+// prefix a measurement chain with a mono downmix when -mono is set: pan
+// sums 0.5*L+0.5*R, matching a single phone speaker. Surround is folded to
+// Lo/Ro first; a mono source is its own sum, and analyzeFile turns -mono
+// off for it.
+func sumFilter(cfg *Config, f string) string {
+	if !cfg.Mono {
+		return f
+	}
+	return "aformat=channel_layouts=stereo,pan=mono|c0=0.5*c0+0.5*c1," + f
+}
+
+// ebur128 filter string; channels is the input channel count (0=unknown),
+// used to enable dualmono automatically for mono sources
+func ebur128Filter(cfg *Config, channels int) string {
+	f := "ebur128=peak=" + cfg.EBUPeak
+	if cfg.LoudnessStd == "atsc" {
+		// A/85 references BS.1770 with the same gating; only target differs
+		f += ":target=-24"
+	}
+	switch cfg.EBUDualMono {
+	case "on":
+		f += ":dualmono=true"
+	case "auto":
+		if channels == 1 {
+			f += ":dualmono=true"
+		}
+	}
+	return f
+}
+
+func ebur128Chain(cfg *Config, channels int) string {
+	return sumFilter(cfg, ebur128Filter(cfg, channels))
 }
 
-func ffmpegEBUR128(cfg *Config, in string) (LUFS, error) {
-	args := []string{"-hide_banner", "-nostats", "-vn", "-i", in, "-filter_complex", "ebur128=peak=true", "-f", "null", "-"}
+func ffmpegEBUR128(cfg *Config, in string, channels int) (LUFS, error) {
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-filter_complex", ebur128Chain(cfg, channels), "-f", "null", "-"})
 	out, _ := runCmd(cfg.FFmpegBin, args...)
-	reI := regexp.MustCompile(`Integrated loudness:\s*([-\d\.]+)\s*LUFS`)
-	reR := regexp.MustCompile(`Loudness range:\s*([-\d\.]+)\s*LU`)
-	reTP := regexp.MustCompile(`True peak:\s*([-\d\.]+)\s*dBTP`)
+	return parseEBUR128(out)
+}
+
+// speech band for -dialog-gate
+var dialogBand = Bandspec{300, 3400}
+
+// dialogChain band-passes to the speech band before ebur128 so music and
+// effects outside it barely move the gated loudness; peaks are skipped as
+// they mean nothing after the filter
+func dialogChain(cfg *Config, channels int) string {
+	dc := *cfg
+	dc.EBUPeak = "none"
+	return sumFilter(cfg, bandFilter(dialogBand)+","+ebur128Filter(&dc, channels))
+}
+
+func ffmpegDialogLoudness(cfg *Config, in string, channels int) (float64, error) {
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-filter_complex", dialogChain(cfg, channels), "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	v, err := parseEBUR128(out)
+	return v.Integrated, err
+}
+
+func parseEBUR128(out string) (LUFS, error) {
+	// summary block is "Integrated loudness:\n    I: -23.0 LUFS" etc.
+	reI := regexp.MustCompile(`Integrated loudness:\s*(?:I:\s*)?([-\d\.]+)\s*LUFS`)
+	reR := regexp.MustCompile(`Loudness range:\s*(?:LRA:\s*)?([-\d\.]+)\s*LU`)
+	reTP := regexp.MustCompile(`True peak:\s*(?:Peak:\s*)?([-\d\.]+)\s*dB(?:TP|FS)`)
+	reSP := regexp.MustCompile(`Sample peak:\s*(?:Peak:\s*)?([-\d\.]+)\s*dBFS`)
 	mI := reI.FindStringSubmatch(out)
 	mR := reR.FindStringSubmatch(out)
 	l := LUFS{}
@@ -110,42 +423,331 @@ func ffmpegEBUR128(cfg *Config, in string) (LUFS, error) {
 		v := parseFloat(m[1])
 		l.TruePeak = &v
 	}
+	if m := reSP.FindStringSubmatch(out); len(m) >= 2 {
+		v := parseFloat(m[1])
+		l.SamplePeak = &v
+	}
+	l.frames = parseEBUR128Frames(out)
 	return l, nil
 }
 
-func ffmpegBandLoudness(cfg *Config, in string, b Bandspec) (peakDB, rmsDB float64, err error) {
-	filter := fmt.Sprintf("highpass=f=%g,lowpass=f=%g,volumedetect", b.Lo, b.Hi)
-	args := []string{"-hide_banner", "-nostats", "-vn", "-i", in, "-af", filter, "-f", "null", "-"}
-	out, _ := runCmd(cfg.FFmpegBin, args...)
-	reMax := regexp.MustCompile(`max_volume:\s*([-\d\.]+)\s*dB`)
-	reMean := regexp.MustCompile(`mean_volume:\s*([-\d\.]+)\s*dB`)
+// per-100ms ebur128 log lines: "t: 1.2 TARGET:-23 LUFS M: -20.1 S: -21.3 ..."
+func parseEBUR128Frames(out string) []loudnessFrame {
+	re := regexp.MustCompile(`t:\s*([\d\.]+)\s.*?M:\s*(-inf|-?[\d\.]+)\s+S:\s*(-inf|-?[\d\.]+)`)
+	var frames []loudnessFrame
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		if m := re.FindStringSubmatch(sc.Text()); len(m) == 4 {
+			frames = append(frames, loudnessFrame{T: parseFloat(m[1]), M: parseDB(m[2]), S: parseDB(m[3])})
+		}
+	}
+	return frames
+}
+
+func parseVolumedetect(out string) (peakDB, rmsDB float64, err error) {
+	reMax := regexp.MustCompile(`max_volume:\s*(-inf|[-\d\.]+)\s*dB`)
+	reMean := regexp.MustCompile(`mean_volume:\s*(-inf|[-\d\.]+)\s*dB`)
 	m1 := reMax.FindStringSubmatch(out)
 	m2 := reMean.FindStringSubmatch(out)
 	if len(m1) < 2 || len(m2) < 2 {
-		return 0, 0, fmt.Errorf("band parse failed")
+		return 0, 0, fmt.Errorf("volumedetect parse failed")
+	}
+	return parseDB(m1[1]), parseDB(m2[1]), nil
+}
+
+// the band-pass chain shared by band measurement and -preview-band
+func bandFilter(b Bandspec) string {
+	return fmt.Sprintf("aformat=sample_fmts=flt,highpass=f=%g,lowpass=f=%g", b.Lo, b.Hi)
+}
+
+// writes the band-passed signal to a float WAV so a band measurement can be
+// auditioned; seconds<=0 renders the whole file
+func ffmpegBandPreview(cfg *Config, in string, b Bandspec, out string, seconds float64) error {
+	if err := ensureParent(out); err != nil {
+		return err
 	}
-	return parseFloat(m1[1]), parseFloat(m2[1]), nil
+	args := rangeArgs(cfg, []string{"-y", "-hide_banner", "-nostats", "-i", in, "-vn", "-map", streamSpec(cfg)})
+	if seconds > 0 {
+		args = append(args, "-t", fmt.Sprintf("%f", seconds))
+	}
+	args = append(args, "-af", sumFilter(cfg, bandFilter(b)), "-c:a", "pcm_f32le", out)
+	if o, err := runCmd(cfg.FFmpegBin, args...); err != nil {
+		return fmt.Errorf("ffmpeg preview: %w\n%s", err, o)
+	}
+	return nil
+}
+
+// band-passed L, R, mid and side as four channels for astats
+const bandCorrPan = "pan=4.0|c0=c0|c1=c1|c2=0.5*c0+0.5*c1|c3=0.5*c0-0.5*c1"
+
+// Pearson L/R correlation from astats of the L, R, M, S channels. With
+// M=(L+R)/2 and S=(L-R)/2, E[LR] = E[M²]-E[S²], so per-channel RMS is enough.
+// Channels astats reports as -inf (digital silence) count as zero power.
+func bandCorrelation(chans []map[string]float64) (*float64, error) {
+	if len(chans) < 4 {
+		return nil, fmt.Errorf("astats: want 4 channels, got %d", len(chans))
+	}
+	pw := func(ch map[string]float64) float64 {
+		if v, ok := ch["rms_level_db"]; ok {
+			return math.Pow(10, v/10)
+		}
+		return 0
+	}
+	l, r, m, sd := pw(chans[0]), pw(chans[1]), pw(chans[2]), pw(chans[3])
+	if l == 0 || r == 0 {
+		return nil, fmt.Errorf("band silent in one channel")
+	}
+	c := math.Max(-1, math.Min(1, (m-sd)/math.Sqrt(l*r)))
+	return &c, nil
+}
+
+// bandSideMid is side over mid RMS from the same L/R/M/S astats; a silent
+// side (mono band) sits at the silence floor, a silent mid has no ratio
+func bandSideMid(chans []map[string]float64) *float64 {
+	if len(chans) < 4 {
+		return nil
+	}
+	mid, ok := chans[2]["rms_level_db"]
+	if !ok {
+		return nil
+	}
+	side, ok := chans[3]["rms_level_db"]
+	if !ok {
+		side = silentFloorDB
+	}
+	r := side - mid
+	return &r
+}
+
+// bandsChain measures every band in one decode: asplit fans the input out,
+// and each branch band-passes, runs astats on all channels (peak/RMS), then
+// optionally ebur128 (true peak) and, for stereo, a second astats on L/R/M/S
+// for the band correlation. Branch order is band order, which is how
+// ffmpegBands matches filter instances back to bands.
+func bandsChain(cfg *Config, bands []Bandspec, channels int) string {
+	var b strings.Builder
+	b.WriteString("[0:a]" + sumFilter(cfg, fmt.Sprintf("asplit=%d", len(bands))))
+	for i := range bands {
+		fmt.Fprintf(&b, "[bd%d]", i)
+	}
+	for i, band := range bands {
+		fmt.Fprintf(&b, ";[bd%d]%s,astats=measure_overall=1:reset=0", i, bandFilter(band))
+		if cfg.UseEBUR128 {
+			b.WriteString(",ebur128=peak=true")
+		}
+		if bandsCorrelate(cfg, channels) {
+			b.WriteString("," + bandCorrPan + ",astats=reset=0")
+		}
+		if i < len(bands)-1 {
+			b.WriteString(",anullsink")
+		}
+	}
+	return b.String()
+}
+
+func bandsCorrelate(cfg *Config, channels int) bool { return !cfg.Mono && channels >= 2 }
+
+// ffmpegBands runs bandsChain and splits its log per filter instance: the
+// i-th level astats (and ebur128) belongs to the i-th band, and with
+// correlation on, astats instances alternate level, L/R/M/S per band.
+// Filtering runs in float so a band whose filtered peak exceeds 0 dBFS is
+// reported as such instead of being clipped by an integer intermediate format.
+func ffmpegBands(cfg *Config, in string, bands []Bandspec, channels int) ([]BandStat, error) {
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-filter_complex", bandsChain(cfg, bands, channels), "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	logs := demuxFilterLog(out)
+	per := 1
+	if bandsCorrelate(cfg, channels) {
+		per = 2
+	}
+	as := logs.instances("astats")
+	if len(as) != per*len(bands) {
+		return nil, fmt.Errorf("bands: want %d astats reports, got %d", per*len(bands), len(as))
+	}
+	eb := logs.instances("ebur128")
+	stats := make([]BandStat, len(bands))
+	for i, b := range bands {
+		overall, _ := parseAstats(as[per*i])
+		// a silent band reads -inf, which JSON can't carry
+		level := func(k string) float64 {
+			if v, ok := overall[k]; ok {
+				return v
+			}
+			return silentFloorDB
+		}
+		bs := BandStat{Band: b, PeakDB: level("peak_level_db"), RMSDB: level("rms_level_db")}
+		if i < len(eb) {
+			if l, err := parseEBUR128(eb[i]); err == nil {
+				bs.TruePeakDBTP = l.TruePeak
+			}
+		}
+		if per == 2 {
+			_, chans := parseAstats(as[per*i+1])
+			bs.Correlation, _ = bandCorrelation(chans)
+			bs.SideMidDB = bandSideMid(chans)
+		}
+		stats[i] = bs
+	}
+	return stats, nil
+}
+
+// pan out the named channels (FL,FR / LFE / ...) into their own layout
+func subsetChain(cfg *Config, names []string) string {
+	layout := fmt.Sprintf("%dc", len(names))
+	switch len(names) {
+	case 1:
+		layout = "mono"
+	case 2:
+		layout = "stereo"
+	}
+	var b strings.Builder
+	b.WriteString("pan=" + layout)
+	for i, n := range names {
+		fmt.Fprintf(&b, "|c%d=%s", i, n)
+	}
+	f := b.String() + ",volumedetect"
+	if cfg.UseEBUR128 {
+		f += "," + ebur128Filter(cfg, len(names))
+	}
+	return f
+}
+
+// level and loudness of a channel subset, in one pass
+func ffmpegChannelSubset(cfg *Config, in string, names []string) (*SubsetStats, error) {
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-af", subsetChain(cfg, names), "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	peak, rms, err := parseVolumedetect(out)
+	if err != nil {
+		return nil, fmt.Errorf("channels %s: %w", strings.Join(names, ","), err)
+	}
+	ss := &SubsetStats{Channels: strings.Join(names, ","), PeakDB: peak, RMSDB: rms}
+	if l, err := parseEBUR128(out); err == nil {
+		ss.Integrated, ss.TruePeakDBTP = &l.Integrated, l.TruePeak
+	}
+	return ss, nil
+}
+
+// the two bands either side of a -tilt-freq crossover
+func tiltBands(freq float64) (lo, hi Bandspec) {
+	return Bandspec{20, freq}, Bandspec{freq, 20000}
+}
+
+// tiltChain is bandsChain over the two tilt bands, without ebur128 (the
+// true peak isn't needed) or correlation
+func tiltChain(cfg *Config, freq float64) (*Config, []Bandspec) {
+	tc := *cfg
+	tc.UseEBUR128 = false
+	lo, hi := tiltBands(freq)
+	return &tc, []Bandspec{lo, hi}
+}
+
+// spectral tilt: high-band RMS minus low-band RMS around freq, both bands
+// measured in one pass
+func ffmpegTilt(cfg *Config, in string, freq float64) (*float64, error) {
+	tc, bands := tiltChain(cfg, freq)
+	bs, err := ffmpegBands(tc, in, bands, 1)
+	if err != nil {
+		return nil, err
+	}
+	t := bs[1].RMSDB - bs[0].RMSDB
+	return &t, nil
+}
+
+// decoded duration from a plain null decode (last progress "time="), the
+// number of decoder error lines seen on the way, and every sample rate the
+// decoder produced. Verbose logging is what surfaces ffmpeg's "frame changed
+// from rate:A ... to rate:B" on midstream format changes.
+func ffmpegDecodedDuration(cfg *Config, in string) (float64, int, []int, error) {
+	args := rangeArgs(cfg, []string{"-hide_banner", "-v", "verbose", "-i", in, "-vn", "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	re := regexp.MustCompile(`time=(\d+):(\d+):([\d\.]+)`)
+	ms := re.FindAllStringSubmatch(out, -1)
+	if len(ms) == 0 {
+		return 0, 0, nil, fmt.Errorf("no decode progress parsed")
+	}
+	m := ms[len(ms)-1]
+	dur := parseFloat(m[1])*3600 + parseFloat(m[2])*60 + parseFloat(m[3])
+	return dur, countDecodeErrors(out), parseRateChanges(out, 0), nil
+}
+
+// countDecodeErrors counts the decoder's error lines in an ffmpeg log; they
+// are logged at error level, so any pass over the input sees them
+func countDecodeErrors(out string) int {
+	errs := 0
+	for _, line := range strings.Split(out, "\n") {
+		l := strings.ToLower(line)
+		if strings.Contains(l, "error while decoding") || strings.Contains(l, "invalid data found") {
+			errs++
+		}
+	}
+	return errs
+}
+
+var (
+	reRateChanged = regexp.MustCompile(`changed from rate:(\d+).*?to rate:(\d+)`)
+	// ffmpeg 7 logs the change at info level when it rebuilds the graph
+	reRateReconfig = regexp.MustCompile(`audio parameters changed to (\d+) Hz`)
+)
+
+// sample rates in order of appearance from "changed from rate:A ... to
+// rate:B" lines, or from graph reconfigurations starting at rate (0 when
+// unknown); nil when the rate never changed
+func parseRateChanges(out string, rate int) []int {
+	var rates []int
+	change := func(from, to int) {
+		if from == to {
+			return
+		}
+		if len(rates) == 0 && from > 0 {
+			rates = append(rates, from)
+		}
+		rates = append(rates, to)
+		rate = to
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if m := reRateChanged.FindStringSubmatch(line); m != nil {
+			change(parseInt(m[1]), parseInt(m[2]))
+		} else if m := reRateReconfig.FindStringSubmatch(line); m != nil {
+			change(rate, parseInt(m[1]))
+		}
+	}
+	return rates
+}
+
+// mid/side split with astats on the original, mid and side
+const stereoChain = "asplit=2[a][b];" +
+	"[a]channelsplit=channel_layout=stereo:channels=FL|FR[aL][aR];" +
+	"[aL][aR]join=inputs=2:channel_layout=stereo,pan=stereo|c0=0.5*FL+0.5*FR|c1=0.5*FL+0.5*FR[mid2];" +
+	"[b]channelsplit=channel_layout=stereo:channels=FL|FR[bL][bR];" +
+	"[bL][bR]join=inputs=2:channel_layout=stereo,pan=stereo|c0=0.5*FL-0.5*FR|c1=0.5*FL-0.5*FR[side2];" +
+	"[0:a]astats=measure_overall=1:reset=0[origstats];" +
+	"[mid2]astats=measure_overall=1:reset=0[midstats];" +
+	"[side2]astats=measure_overall=1:reset=0[sidestats]"
+
+// L+R at unity in float, measured by astats in float, so a sum above full
+// scale shows up as > 0 dBFS
+const monoSumChain = "aformat=sample_fmts=flt,pan=mono|c0=c0+c1,aformat=sample_fmts=flt,astats=measure_perchannel=none"
+
+// peak of the unity-gain mono sum (what a summed phone speaker or bridged
+// club sub receives), which can clip even when both channels are clean
+func ffmpegMonoSumPeak(cfg *Config, in string) (float64, error) {
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-af", monoSumChain, "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	return parseAstatsPeak(out)
 }
 
 // mid/side + correlation (if available)
 func ffmpegStereoStuff(cfg *Config, in string) (StereoStats, error) {
-	filter := "asplit=2[a][b];" +
-		"[a]channelsplit=channel_layout=stereo:channels=FL|FR[aL][aR];" +
-		"[aL][aR]join=inputs=2:channel_layout=stereo,pan=stereo|c0=0.5*FL+0.5*FR|c1=0.5*FL+0.5*FR[mid2];" +
-		"[b]channelsplit=channel_layout=stereo:channels=FL|FR[bL][bR];" +
-		"[bL][bR]join=inputs=2:channel_layout=stereo,pan=stereo|c0=0.5*FL-0.5*FR|c1=0.5*FL-0.5*FR[side2];" +
-		"[0:a]astats=measure_overall=1:reset=0[origstats];" +
-		"[mid2]astats=measure_overall=1:reset=0[midstats];" +
-		"[side2]astats=measure_overall=1:reset=0[sidestats]"
-	args := []string{"-hide_banner", "-nostats", "-vn", "-i", in, "-filter_complex", filter, "-f", "null", "-"}
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-filter_complex", stereoChain, "-f", "null", "-"})
 	out, _ := runCmd(cfg.FFmpegBin, args...)
-	reRMS := regexp.MustCompile(`\[Parsed_astats.*\] Overall RMS level:\s*([-\d\.]+)`)
+	reRMS := regexp.MustCompile(`\[Parsed_astats.*\] Overall RMS level:\s*(-inf|[-\d\.]+)`)
 	var vals []float64
 	sc := bufio.NewScanner(strings.NewReader(out))
 	for sc.Scan() {
 		line := sc.Text()
 		if m := reRMS.FindStringSubmatch(line); len(m) == 2 {
-			vals = append(vals, parseFloat(m[1]))
+			// -inf: a silent side (dual mono) or mid (polarity-flipped copy)
+			vals = append(vals, math.Max(parseDB(m[1]), silentFloorDB))
 		}
 	}
 	var mid, side float64
@@ -170,9 +772,11 @@ func ffmpegStereoStuff(cfg *Config, in string) (StereoStats, error) {
 	}, nil
 }
 
+func spectralChain(cfg *Config) string { return sumFilter(cfg, "astats=measure_overall=1:reset=0") }
+
 // spectral goodies from astats overall
 func ffmpegSpectral(cfg *Config, in string) (SpectralStats, error) {
-	args := []string{"-hide_banner", "-nostats", "-vn", "-i", in, "-af", "astats=measure_overall=1:reset=0", "-f", "null", "-"}
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-af", spectralChain(cfg), "-f", "null", "-"})
 	out, _ := runCmd(cfg.FFmpegBin, args...)
 	get := func(name string) *float64 {
 		re := regexp.MustCompile(fmt.Sprintf(`Overall %s:\s*([-\d\.]+)`, regexp.QuoteMeta(name)))
@@ -192,11 +796,12 @@ func ffmpegSpectral(cfg *Config, in string) (SpectralStats, error) {
 	}, nil
 }
 
-// silence spans
-func detectSilences(cfg *Config, in string) ([]SilenceSpan, error) {
-	filter := fmt.Sprintf("silencedetect=noise=%0.1fdB:d=0.3", cfg.SilThresDB)
-	args := []string{"-hide_banner", "-vn", "-i", in, "-af", filter, "-f", "null", "-"}
-	out, _ := runCmd(cfg.FFmpegBin, args...)
+func silenceChain(cfg *Config) string {
+	return sumFilter(cfg, fmt.Sprintf("silencedetect=noise=%0.1fdB:d=0.3", cfg.SilThresDB))
+}
+
+// silence spans from silencedetect's log
+func parseSilences(out string) []SilenceSpan {
 	var spans []SilenceSpan
 	reS := regexp.MustCompile(`silence_start:\s*([-\d\.]+)`)
 	reE := regexp.MustCompile(`silence_end:\s*([-\d\.]+)`)
@@ -214,5 +819,5 @@ func detectSilences(cfg *Config, in string) ([]SilenceSpan, error) {
 			start = nil
 		}
 	}
-	return spans, nil
+	return spans
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// a float sum over full scale must read above 0 dBFS, and silence as the floor
+func TestParseAstatsPeak(t *testing.T) {
+	over := "[Parsed_astats_2 @ 0x1] Overall\n[Parsed_astats_2 @ 0x1] Peak level dB: 2.498775\n[Parsed_astats_2 @ 0x1] RMS level dB: -9.1\n"
+	if pk, err := parseAstatsPeak(over); err != nil || pk != 2.498775 {
+		t.Errorf("over: peak %v, err %v", pk, err)
+	}
+	silent := "[Parsed_astats_2 @ 0x1] Overall\n[Parsed_astats_2 @ 0x1] Peak level dB: -inf\n[Parsed_astats_2 @ 0x1] Number of samples: 48000\n"
+	if pk, err := parseAstatsPeak(silent); err != nil || pk != silentFloorDB {
+		t.Errorf("silent: peak %v, err %v", pk, err)
+	}
+	if _, err := parseAstatsPeak("Error opening input"); err == nil {
+		t.Error("no astats output: want an error")
+	}
+}
+
+// both log forms of a midstream rate change, the info-level one starting
+// from the probed rate
+func TestParseRateChanges(t *testing.T) {
+	old := "Input stream #0:0 frame changed from rate:44100 fmt:s16 ch:2 chl:stereo to rate:48000 fmt:s16 ch:2 chl:stereo\n"
+	if got := parseRateChanges(old, 0); len(got) != 2 || got[0] != 44100 || got[1] != 48000 {
+		t.Errorf("verbose form: %v", got)
+	}
+	reconf := "[fc#0 @ 0x1] Reconfiguring filter graph because audio parameters changed to 48000 Hz, stereo, s16\n"
+	if got := parseRateChanges(reconf, 44100); len(got) != 2 || got[0] != 44100 || got[1] != 48000 {
+		t.Errorf("reconfigure form: %v", got)
+	}
+	if got := parseRateChanges("[fc#0 @ 0x1] Reconfiguring filter graph because audio parameters changed to 44100 Hz\n", 44100); got != nil {
+		t.Errorf("same rate: %v", got)
+	}
+}
+
+// needFFmpeg skips tests that run the real binary when it isn't installed
+func needFFmpeg(t *testing.T) *Config {
+	t.Helper()
+	cfg := defaultConfig()
+	if _, err := exec.LookPath(cfg.FFmpegBin); err != nil {
+		t.Skip("ffmpeg not found")
+	}
+	return cfg
+}
+
+// writeFloatWAV writes interleaved 32-bit float samples, which may exceed
+// full scale
+func writeFloatWAV(t *testing.T, path string, rate, channels int, x []float32) {
+	t.Helper()
+	var b bytes.Buffer
+	le := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+	data := uint32(4 * len(x))
+	b.WriteString("RIFF")
+	le(36 + data)
+	b.WriteString("WAVEfmt ")
+	le(uint32(16))
+	le(uint16(3)) // IEEE float
+	le(uint16(channels))
+	le(uint32(rate))
+	le(uint32(rate * channels * 4))
+	le(uint16(channels * 4))
+	le(uint16(32))
+	b.WriteString("data")
+	le(data)
+	le(x)
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// a 100 Hz tone at +6 dBFS: its band must read above full scale, not
+// clipped at 0 dBFS by an s16 stage
+func TestBandPeakAboveFullScale(t *testing.T) {
+	cfg := needFFmpeg(t)
+	const rate = 48000
+	x := make([]float32, 2*rate)
+	for i := range x {
+		ts := float64(i) / rate
+		x[i] = float32(2*math.Sin(2*math.Pi*100*ts) + 0.05*math.Sin(2*math.Pi*4000*ts))
+	}
+	in := filepath.Join(t.TempDir(), "boosted.wav")
+	writeFloatWAV(t, in, rate, 1, x)
+	bs, err := ffmpegBands(cfg, in, []Bandspec{{60, 250}, {2000, 8000}}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs[0].PeakDB < 3 {
+		t.Errorf("60-250 Hz peak %.2f dBFS, want about +6", bs[0].PeakDB)
+	}
+	if bs[1].PeakDB > -20 {
+		t.Errorf("2000-8000 Hz peak %.2f dBFS, want about -26", bs[1].PeakDB)
+	}
+}
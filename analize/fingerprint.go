@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fpcalcFingerprint runs chromaprint's fpcalc on in; like aubio it reads
+// the file itself, so ranges go through the cutRange copy
+func fpcalcFingerprint(cfg *Config, in string) (*Fingerprint, error) {
+	if err := mustHave(cfg.FpcalcBin); err != nil {
+		return nil, errors.New("fpcalc not found")
+	}
+	// stdout only: fpcalc warns about the decode on stderr, which would
+	// break the JSON
+	cmd := exec.Command(cfg.FpcalcBin, "-json", in)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	acquireProc()
+	out, err := cmd.Output()
+	releaseProc()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && len(ee.Stderr) > 0 {
+			return nil, fmt.Errorf("fpcalc: %v: %s", err, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, fmt.Errorf("fpcalc: %v", err)
+	}
+	var r struct {
+		Duration    float64 `json:"duration"`
+		Fingerprint string  `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(out, &r); err != nil || r.Fingerprint == "" {
+		return nil, fmt.Errorf("fpcalc: no fingerprint in output")
+	}
+	return &Fingerprint{Chromaprint: r.Fingerprint, Duration: r.Duration}, nil
+}
+
+const acoustIDURL = "https://api.acoustid.org/v2/lookup"
+
+// AcoustID allows 3 requests a second per client; parallel -jobs share this
+var acoustIDTick = time.NewTicker(time.Second / 3)
+
+var acoustIDClient = &http.Client{Timeout: 15 * time.Second}
+
+// acoustIDLookup asks AcoustID which recording fp is. The fingerprint goes
+// in a POST body, it is too long for a query string. nil, nil: no match.
+func acoustIDLookup(cfg *Config, fp *Fingerprint) (*AcoustIDMatch, error) {
+	form := url.Values{
+		"client":      {cfg.AcoustIDKey},
+		"meta":        {"recordings releasegroups"},
+		"duration":    {strconv.Itoa(int(fp.Duration))},
+		"fingerprint": {fp.Chromaprint},
+	}
+	<-acoustIDTick.C
+	resp, err := acoustIDClient.PostForm(acoustIDURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("acoustid: %w", err)
+	}
+	defer resp.Body.Close()
+	var r struct {
+		Status string `json:"status"`
+		Error  struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Results []struct {
+			ID         string  `json:"id"`
+			Score      float64 `json:"score"`
+			Recordings []struct {
+				ID      string `json:"id"`
+				Title   string `json:"title"`
+				Artists []struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"artists"`
+				ReleaseGroups []struct {
+					ID string `json:"id"`
+				} `json:"releasegroups"`
+			} `json:"recordings"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("acoustid: %s: %w", resp.Status, err)
+	}
+	if r.Status != "ok" {
+		return nil, fmt.Errorf("acoustid: %s", r.Error.Message)
+	}
+	// results come best first; take the first that names a recording
+	for _, res := range r.Results {
+		if len(res.Recordings) == 0 {
+			continue
+		}
+		rec := res.Recordings[0]
+		m := &AcoustIDMatch{ID: res.ID, Score: res.Score, RecordingMBID: rec.ID, Title: rec.Title}
+		var names []string
+		for _, a := range rec.Artists {
+			names = append(names, a.Name)
+			m.ArtistMBIDs = append(m.ArtistMBIDs, a.ID)
+		}
+		m.Artist = strings.Join(names, ", ")
+		for _, g := range rec.ReleaseGroups {
+			m.ReleaseGroups = append(m.ReleaseGroups, g.ID)
+		}
+		return m, nil
+	}
+	return nil, nil
+}
+
+// fingerprint is the -fingerprint stage: fpcalc, then the AcoustID lookup
+// when a key is set. A failed lookup keeps the fingerprint.
+func fingerprint(cfg *Config, in string) *Fingerprint {
+	fp, err := fpcalcFingerprint(cfg, in)
+	if err != nil {
+		warnf("%s: %v", in, err)
+		return nil
+	}
+	if cfg.AcoustIDKey == "" {
+		return fp
+	}
+	if fp.AcoustID, err = acoustIDLookup(cfg, fp); err != nil {
+		warnf("%s: %v", in, err)
+	}
+	return fp
+}
+
+// shortPrint abbreviates a fingerprint for text reports
+func shortPrint(fp string) string {
+	if len(fp) <= 24 {
+		return fp
+	}
+	return fp[:24] + "…"
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Precision is the number of decimals rendered per metric category.
+type Precision struct {
+	Level    int // dBFS / dB
+	Loudness int // LUFS / LU
+	Freq     int // spectral Hz
+	Pitch    int // pitch Hz
+	Corr     int // correlation, confidence
+	Time     int // seconds
+	Shape    int // flatness, spread, skewness, kurtosis
+	Tempo    int // BPM, onsets/min
+}
+
+func defaultPrecision() Precision {
+	return Precision{Level: 2, Loudness: 2, Freq: 0, Pitch: 2, Corr: 2, Time: 3, Shape: 3, Tempo: 2}
+}
+
+// parsePrecision applies "loudness=1,freq=0,..." overrides on top of p
+func parsePrecision(p Precision, s string) Precision {
+	fields := map[string]*int{
+		"level": &p.Level, "loudness": &p.Loudness, "freq": &p.Freq, "pitch": &p.Pitch,
+		"corr": &p.Corr, "time": &p.Time, "shape": &p.Shape, "tempo": &p.Tempo,
+	}
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if f, ok := fields[strings.ToLower(strings.TrimSpace(kv[0]))]; ok {
+			if n, err := strconv.Atoi(strings.TrimSpace(kv[1])); err == nil && n >= 0 {
+				*f = n
+			}
+		}
+	}
+	return p
+}
+
+func ff(v float64, n int) string { return strconv.FormatFloat(v, 'f', n, 64) }
+
+func (p Precision) db(v float64) string    { return ff(v, p.Level) }
+func (p Precision) lufs(v float64) string  { return ff(v, p.Loudness) }
+func (p Precision) hz(v float64) string    { return ff(v, p.Freq) }
+func (p Precision) pitch(v float64) string { return ff(v, p.Pitch) }
+func (p Precision) corr(v float64) string  { return ff(v, p.Corr) }
+func (p Precision) sec(v float64) string   { return ff(v, p.Time) }
+func (p Precision) shape(v float64) string { return ff(v, p.Shape) }
+func (p Precision) bpm(v float64) string   { return ff(v, p.Tempo) }
+
+// signed loudness difference, e.g. "+1.20"
+func (p Precision) lu(v float64) string {
+	s := ff(v, p.Loudness)
+	if v >= 0 {
+		s = "+" + s
+	}
+	return s
+}
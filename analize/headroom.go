@@ -0,0 +1,36 @@
+package main
+
+import "fmt"
+
+// true-peak ceiling per delivery target, in dBTP
+//
+//	cd         -0.3  16-bit PCM, no further encoding
+//	streaming  -1.0  survives the platform's lossy transcode
+//	vinyl      -3.0  room for the cutting engineer; hot peaks distort the lathe
+//	broadcast  -1.0  EBU R128 (A/85: -2.0)
+//
+// with no -delivery the generic -1.0 trigger and -1.5 advice apply
+func deliveryCeiling(cfg *Config) (ceiling, advice float64) {
+	switch cfg.Delivery {
+	case "cd":
+		return -0.3, -0.3
+	case "streaming":
+		return -1, -1
+	case "vinyl":
+		return -3, -3
+	case "broadcast":
+		if cfg.LoudnessStd == "atsc" {
+			return -2, -2
+		}
+		return -1, -1
+	}
+	return -1, -1.5
+}
+
+func truePeakAdvice(cfg *Config, tp float64) string {
+	_, advice := deliveryCeiling(cfg)
+	if cfg.Delivery == "" {
+		return fmt.Sprintf("True peak dangerously high (%.2f dBTP). Consider %.1f dBTP ceiling.", tp, advice)
+	}
+	return fmt.Sprintf("True peak %.2f dBTP is over the %.1f dBTP ceiling for %s delivery; lower the limiter ceiling by %.1f dB.", tp, advice, cfg.Delivery, tp-advice)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// hum is measured on a 4 kHz resample with 8192-point frames: ~0.5 Hz bins,
+// enough to tell 50 from 60 Hz and their harmonics from nearby bass notes
+const (
+	humRate      = 4000
+	humFFT       = 8192
+	humHarmonics = 8    // 50..400 Hz, 60..480 Hz
+	humPeakHz    = 0.6  // mains drift; a component is the strongest bin this close
+	humLobeBins  = 2    // Hann main lobe, summed for the level
+	humNearHz    = 2.0  // the surrounding spectrum starts this far away...
+	humFarHz     = 8.0  // ...and ends here
+	humPromDB    = 12.0 // above the surroundings for a harmonic to count
+	humStrongDB  = 20.0 // a fundamental this prominent is hum on its own
+)
+
+// detectHum looks for a comb of narrow lines at 50 or 60 Hz and their
+// harmonics. Each line's prominence is its peak over the median of the
+// spectrum a few Hz either side; hum is reported when two or more lines
+// stand humPromDB out (or the fundamental alone humStrongDB), picking the
+// mains frequency with more prominence. nil: no hum.
+func detectHum(db []float64) *Hum {
+	binHz := float64(humRate) / float64(2*(len(db)-1))
+	var best *Hum
+	bestScore := 0.0
+	for _, f0 := range []float64{50, 60} {
+		h := &Hum{FundamentalHz: f0, LevelDB: silentFloorDB}
+		score := 0.0
+		for n := 1; n <= humHarmonics; n++ {
+			c, ok := humComponent(db, binHz, f0*float64(n))
+			if !ok || c.ProminenceDB < humPromDB {
+				continue
+			}
+			h.Harmonics = append(h.Harmonics, c)
+			h.LevelDB = math.Max(h.LevelDB, c.LevelDB)
+			score += c.ProminenceDB
+		}
+		strong := len(h.Harmonics) == 1 && h.Harmonics[0].Hz == f0 && h.Harmonics[0].ProminenceDB >= humStrongDB
+		if (len(h.Harmonics) >= 2 || strong) && score > bestScore {
+			best, bestScore = h, score
+		}
+	}
+	if best != nil {
+		best.Notch = humNotch(best)
+	}
+	return best
+}
+
+// humComponent measures the line nearest f: the peak bin within humPeakHz,
+// its level as a sine RMS in dBFS from the power of the main lobe, and its
+// prominence over the median of the bins humNearHz..humFarHz away
+func humComponent(db []float64, binHz, f float64) (HumHarmonic, bool) {
+	k0 := int(math.Round(f / binHz))
+	far := int(math.Ceil(humFarHz / binHz))
+	if k0-far < 1 || k0+far >= len(db)-1 {
+		return HumHarmonic{}, false
+	}
+	pk := k0
+	for k := int(math.Floor((f - humPeakHz) / binHz)); k <= int(math.Ceil((f+humPeakHz)/binHz)); k++ {
+		if db[k] > db[pk] {
+			pk = k
+		}
+	}
+	// the skirt of a nearby note rises towards the window edge; a mains
+	// line peaks inside it
+	if db[pk] < db[pk-1] || db[pk] < db[pk+1] {
+		return HumHarmonic{}, false
+	}
+	// Parseval with a Hann window: a sine of amplitude A puts N²A²·3/32
+	// into its lobe (one-sided), and its RMS² is A²/2
+	var lobe float64
+	for k := pk - humLobeBins; k <= pk+humLobeBins; k++ {
+		lobe += math.Pow(10, db[k]/10)
+	}
+	level := 10 * math.Log10(lobe*16/(3*float64(humFFT)*float64(humFFT))+1e-30)
+	near := int(math.Ceil(humNearHz / binHz))
+	var around []float64
+	for d := near; d <= far; d++ {
+		around = append(around, db[k0-d], db[k0+d])
+	}
+	sort.Float64s(around)
+	return HumHarmonic{Hz: f, LevelDB: level, ProminenceDB: db[pk] - around[len(around)/2]}, true
+}
+
+// humNotch is an ffmpeg chain notching out every detected line; Q 30 is a
+// few Hz wide at these frequencies, narrow enough to leave the bass alone
+func humNotch(h *Hum) string {
+	var fs []string
+	for _, c := range h.Harmonics {
+		fs = append(fs, fmt.Sprintf("bandreject=f=%g:width_type=q:w=30", c.Hz))
+	}
+	return strings.Join(fs, ",")
+}
+
+func humNotes(h *Hum) []Note {
+	if h == nil {
+		return nil
+	}
+	return []Note{newNote(SevWarn, "MAINS_HUM", "%g Hz mains hum at %.1f dBFS (%d lines); notch with -af %s", h.FundamentalHz, h.LevelDB, len(h.Harmonics), h.Notch)}
+}
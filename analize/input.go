@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// stdinInput is the <input> that reads audio from standard input
+const stdinInput = "-"
+
+// isURL reports whether in is a remote input (http://, https://, ftp://,
+// ...) for ffmpeg/ffprobe to stream rather than a local path
+func isURL(in string) bool {
+	u, err := url.Parse(in)
+	return err == nil && len(u.Scheme) > 1 && u.Host != ""
+}
+
+// localName turns a URL into a file name for outputs derived from the input
+// (split parts, band previews); paths are returned as-is
+func localName(in string) string {
+	if !isURL(in) {
+		return in
+	}
+	u, _ := url.Parse(in)
+	if b := path.Base(u.Path); b != "/" && b != "." {
+		return b
+	}
+	return u.Host
+}
+
+// bufferInput has ffmpeg read in (a URL, or pipe:0 for stdin) once and
+// remux its audio streams into a temp Matroska file, since a full analysis
+// decodes the input many times and a pipe can only be read once. The
+// streams are copied, not re-encoded, so the measurements match the
+// original. remove deletes the temp file.
+func bufferInput(cfg *Config, in string) (file string, remove func(), err error) {
+	return remuxAudio(cfg, in, "0:a")
+}
+
+// streamInput remuxes audio stream cfg.Stream of in into a temp Matroska
+// file. Left alone, ffmpeg picks the "best" audio stream (most channels) and
+// aubio the first, so a multi-stream file is analyzed from this copy.
+func streamInput(cfg *Config, in string) (file string, remove func(), err error) {
+	return remuxAudio(cfg, in, streamSpec(cfg))
+}
+
+// streamSpec is the -map specifier of the -stream audio stream
+func streamSpec(cfg *Config) string { return fmt.Sprintf("0:a:%d", cfg.Stream) }
+
+func remuxAudio(cfg *Config, in, spec string) (file string, remove func(), err error) {
+	dir, err := os.MkdirTemp("", "analit-input-")
+	if err != nil {
+		return "", nil, err
+	}
+	remove = func() { os.RemoveAll(dir) }
+	file = filepath.Join(dir, "input.mka")
+	src := in
+	if in == stdinInput {
+		src = "pipe:0"
+	}
+	cmd := exec.Command(cfg.FFmpegBin, "-y", "-hide_banner", "-nostats", "-i", src, "-map", spec, "-c", "copy", file)
+	if in == stdinInput {
+		cmd.Stdin = os.Stdin
+	}
+	acquireProc()
+	out, err := cmd.CombinedOutput()
+	releaseProc()
+	if err != nil {
+		remove()
+		return "", nil, fmt.Errorf("buffer %s: %w\n%s", in, err, out)
+	}
+	return file, remove, nil
+}
+
+// analyzeStream analyzes one stream of a multi-stream input from its
+// streamInput copy and reports it under the original name
+func analyzeStream(cfg *Config, in string, probe ProbeInfo) (*Analysis, error) {
+	file, remove, err := streamInput(cfg, in)
+	if err != nil {
+		return nil, err
+	}
+	defer remove()
+	sc := *cfg
+	sc.Stream = 0
+	a, err := analyzeFile(&sc, file)
+	if err != nil {
+		return nil, err
+	}
+	a.File = in
+	// the container-level view comes from the original
+	a.Probe.FormatName, a.Probe.Stream, a.Probe.AudioStreams = probe.FormatName, probe.Stream, probe.AudioStreams
+	a.Probe.Tracks, a.Probe.Video, a.Metadata = probe.Tracks, probe.Video, parseMetadata(probe.Tags)
+	a.Notes = append(a.Notes, filterNotes(videoNotes(probe), cfg.MinSeverity)...)
+	for k, v := range a.Filters {
+		a.Filters[k] = strings.ReplaceAll(v, file, in)
+	}
+	// every pass reads the remuxed stream
+	a.Filters["stream"] = "-i " + in + " -map " + streamSpec(cfg) + " -c copy"
+	return a, nil
+}
+
+// streamName is the file a report row or database entry is keyed by: the
+// path, plus the stream ("song.mkv#a:1") when the file has several
+func streamName(a *Analysis) string {
+	if a.Probe.AudioStreams > 1 {
+		return fmt.Sprintf("%s#a:%d", a.File, a.Probe.Stream)
+	}
+	return a.File
+}
+
+// parseClock reads a position as seconds ("90", "90.5") or [h:]m:ss[.frac]
+// ("1:30", "1:02:03.5")
+func parseClock(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	var t float64
+	for _, part := range strings.Split(s, ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("bad time %q (want seconds or [h:]m:ss)", s)
+		}
+		t = t*60 + v
+	}
+	return t, nil
+}
+
+// parseRange reads "start-end" (either side may be empty: "1:00-", "-2:30")
+func parseRange(s string) (start, end float64, err error) {
+	lo, hi, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("bad range %q (want start-end, e.g. 1:00-2:30)", s)
+	}
+	if strings.TrimSpace(lo) != "" {
+		if start, err = parseClock(lo); err != nil {
+			return 0, 0, err
+		}
+	}
+	if strings.TrimSpace(hi) != "" {
+		if end, err = parseClock(hi); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, end, nil
+}
+
+func hasRange(cfg *Config) bool { return cfg.Start > 0 || cfg.End > 0 }
+
+// rangeArgs puts -ss/-to in front of every -i of an ffmpeg command line so
+// it decodes only the -start/-end section; timestamps then start at 0, so
+// times in the report are relative to the range start
+func rangeArgs(cfg *Config, args []string) []string {
+	if !hasRange(cfg) {
+		return args
+	}
+	var seek []string
+	if cfg.Start > 0 {
+		seek = append(seek, "-ss", fmt.Sprintf("%g", cfg.Start))
+	}
+	if cfg.End > 0 {
+		seek = append(seek, "-to", fmt.Sprintf("%g", cfg.End))
+	}
+	out := make([]string, 0, len(args)+2*len(seek))
+	for _, a := range args {
+		if a == "-i" {
+			out = append(out, seek...)
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// rangeDuration is how much of a file of length d the range covers
+func rangeDuration(cfg *Config, d float64) float64 {
+	end := d
+	if cfg.End > 0 && cfg.End < d {
+		end = cfg.End
+	}
+	return math.Max(0, end-cfg.Start)
+}
+
+// cutRange renders the -start/-end section of in to a temp float WAV for
+// aubio, which can't seek; remove deletes it
+func cutRange(cfg *Config, in string) (file string, remove func(), err error) {
+	dir, err := os.MkdirTemp("", "analit-range-")
+	if err != nil {
+		return "", nil, err
+	}
+	remove = func() { os.RemoveAll(dir) }
+	file = filepath.Join(dir, "range.wav")
+	args := rangeArgs(cfg, []string{"-y", "-hide_banner", "-nostats", "-i", in, "-vn", "-c:a", "pcm_f32le", file})
+	if out, err := runCmd(cfg.FFmpegBin, args...); err != nil {
+		remove()
+		return "", nil, fmt.Errorf("cut range: %w\n%s", err, out)
+	}
+	return file, remove, nil
+}
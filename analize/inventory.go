@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/csv"
+	"strconv"
+	"strings"
+)
+
+var inventoryHeader = []string{"file", "format", "codec", "duration_s", "sample_rate", "channels", "bit_depth", "bitrate", "error"}
+
+// inventory walks dir and runs only ffprobe on each audio file, workers at
+// a time; rows come back in path order, a failed probe fills the error
+// column. On interruption the rows probed so far are returned with the total.
+func inventory(cfg *Config, dir string, workers int) ([][]string, int, error) {
+	files, err := collectAudio(dir, true, "")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows := make([][]string, len(files))
+	done, err := runBatch(len(files), workers, func(i int) error {
+		rows[i] = inventoryRow(cfg, files[i])
+		return nil
+	})
+	var out [][]string
+	for i, r := range rows {
+		if done[i] {
+			out = append(out, r)
+		}
+	}
+	return out, len(files), err
+}
+
+func inventoryRow(cfg *Config, p string) []string {
+	pi, err := ffprobeInfo(cfg, p)
+	if err != nil {
+		return []string{p, "", "", "", "", "", "", "", err.Error()}
+	}
+	depth := ""
+	if pi.BitDepth > 0 {
+		depth = strconv.Itoa(pi.BitDepth)
+	}
+	return []string{
+		p, pi.FormatName, pi.CodecName, strconv.FormatFloat(pi.Duration, 'f', 3, 64),
+		strconv.Itoa(pi.SampleRate), strconv.Itoa(pi.Channels), depth, strconv.FormatInt(pi.BitRate, 10), "",
+	}
+}
+
+func renderInventory(rows [][]string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(inventoryHeader)
+	w.WriteAll(rows)
+	return b.String()
+}
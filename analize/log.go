@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// all user-facing status lines go through here: one lock and one write per
+// line, so output from concurrent workers never interleaves mid-line
+var logMu sync.Mutex
+
+func logTo(w io.Writer, format string, a ...any) {
+	s := fmt.Sprintf(format, a...)
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	logMu.Lock()
+	defer logMu.Unlock()
+	io.WriteString(w, s)
+}
+
+// infof prints a status line to stdout
+func infof(format string, a ...any) { logTo(os.Stdout, format, a...) }
+
+// warnf prints a "[warn]" line to stderr
+func warnf(format string, a ...any) { logTo(os.Stderr, "[warn] "+format, a...) }
+
+// wrote reports a finished output file
+func wrote(path string) { infof("[+] wrote %s", path) }
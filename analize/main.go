@@ -4,27 +4,93 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 )
 
 func main() {
 	cfg := defaultConfig()
 	outPath := flag.String("o", cfg.OutPath, "output path")
-	report := flag.String("report", cfg.Report, "report: txt|json|md")
+	report := flag.String("report", cfg.Report, "report: txt|json|yaml|md|csv|ndjson (default: from -o extension)")
 	ffmpeg := flag.String("ffmpeg", cfg.FFmpegBin, "path to ffmpeg")
 	ffprobe := flag.String("ffprobe", cfg.FFprobeBin, "path to ffprobe")
+	dbPath := flag.String("db", "", "also store each analysis in this SQLite database (files/runs/metrics tables)")
+	sqlite := flag.String("sqlite3", cfg.SQLiteBin, "path to sqlite3 (for -db and query)")
 	aubio := flag.String("aubio", cfg.AubioBin, "path to aubio (tempo/key/pitch/onset)")
+	aubioBuf := flag.Int("aubio-bufsize", 0, "aubio buffer size -B (0=default; larger helps low pitch)")
+	aubioHop := flag.Int("aubio-hopsize", 0, "aubio hop size -H (0=default; smaller helps onsets)")
+	fpcalc := flag.String("fpcalc", cfg.FpcalcBin, "path to chromaprint fpcalc (-fingerprint)")
+	fprint := flag.Bool("fingerprint", false, "add a Chromaprint acoustic fingerprint (needs fpcalc)")
+	acoustKey := flag.String("acoustid-key", os.Getenv("ACOUSTID_KEY"), "AcoustID client key: look fingerprints up for title/artist/MBIDs (implies -fingerprint; default $ACOUSTID_KEY)")
 	bpmEng := flag.String("bpm-engine", cfg.BPMEngine, "bpm engine: aubio|none")
 	bandsStr := flag.String("bands", "20-60,60-120,120-250,250-500,500-2000,2000-5000,5000-10000,10000-20000", "bands Hz: \"20-60,60-120,...\"")
 	noBands := flag.Bool("no-bands", false, "disable band loudness")
 	noEbu := flag.Bool("no-ebur128", false, "disable LUFS ebur128/true peak")
-	astWin := flag.Float64("astats-window", 0.0, "astats window sec (0=overall)")
+	ebuPeak := flag.String("ebur128-peak", cfg.EBUPeak, "ebur128 peak mode: true|sample|sample+true|none")
+	dualMono := flag.String("dualmono", cfg.EBUDualMono, "ebur128 dualmono for mono inputs: auto|on|off")
+	loudStd := flag.String("loudness-standard", cfg.LoudnessStd, "loudness standard: ebu (R128) | atsc (A/85)")
+	delivery := flag.String("delivery", "", "delivery target for true-peak advice: cd|streaming|vinyl|broadcast")
+	target := flag.String("target", "", "check loudness against a platform: spotify|youtube|apple|tidal|broadcast")
+	mono := flag.Bool("mono", false, "measure the mono sum (0.5*L+0.5*R) and skip the stereo section")
+	chanSel := flag.String("channels", "", "also measure only these channels, e.g. FL,FR or LFE (ffmpeg channel names)")
+	phase := flag.Bool("phase-scope", false, "add an L/R phase-scope histogram and stereo width % (JSON carries the grid)")
+	dialog := flag.Bool("dialog-gate", false, "also measure integrated loudness of the 300-3400 Hz speech band (approximate dialog level)")
+	clicks := flag.Bool("clicks", false, "count click events (raw sample pass, clicks on all channels within 5 ms are one) and grade clicks/min")
+	defects := flag.Bool("defects", false, "list clicks, digital dropouts and buffer glitches with timestamps (raw sample pass)")
+	astWin := flag.Float64("astats-window", 0.0, "also record a peak/RMS envelope in windows of this many seconds (0=off)")
+	tilt := flag.Float64("tilt-freq", 0, "report spectral tilt: RMS above minus below this crossover Hz, e.g. 1000 (0=off)")
+	tpOver := flag.Int("tp-oversample", cfg.Oversample, "oversampling of the per-channel true-peak pass: 4|8 (also the true peak with -no-ebur128)")
 	silTh := flag.Float64("silence-threshold", cfg.SilThresDB, "silence threshold dBFS")
+	sections := flag.String("sections", "0.1,0.1", "head,tail fractions for intro/body/outro loudness (0=off)")
+	structLU := flag.Float64("structure-threshold", cfg.StructLU, "sustained level change in LU that starts a new structure section (0=off)")
+	lufsRel := flag.Float64("lufs-relative", 0.0, "also report loudness in LU relative to this target LUFS (0=off)")
+	minSev := flag.String("min-severity", string(cfg.MinSeverity), "only report notes at or above: info|warn|error")
+	sortBy := flag.String("sort", "", "order summary rows by: lufs|peak|bpm|name|duration")
+	sortRev := flag.Bool("reverse", false, "reverse -sort order")
+	jobs := flag.Int("jobs", 1, "analyze this many files at once (batch, stability)")
+	maxProcs := flag.Int("max-procs", runtime.NumCPU(), "cap on concurrent ffmpeg/ffprobe/aubio processes across all files and passes")
+	explain := flag.Bool("explain", false, "annotate txt report metrics with a short interpretation")
+	jsonCompact := flag.Bool("json-compact", false, "write JSON without indentation")
+	precStr := flag.String("precision", "", "decimals per category, e.g. \"loudness=1,freq=0,corr=2\" (level|loudness|freq|pitch|corr|time|shape|tempo)")
 	splitSec := flag.Float64("split-on-silence", 0.0, "split input on silence >= seconds (0=off)")
+	segOut := flag.String("segments-out", "", "write silence-split segment map as JSON to this path")
+	previewBand := flag.String("preview-band", "", "write a WAV of one band (lo-hi Hz, e.g. 20-60) to audition it")
+	previewOut := flag.String("preview-out", "", "path for -preview-band (default <input>-band-<lo>-<hi>.wav)")
+	previewSec := flag.Float64("preview-seconds", 30, "length of -preview-band output in seconds (0=full)")
+	wavePNG := flag.String("waveform", "", "full: write a waveform overview PNG to this path")
+	peaksOut := flag.String("peaks", "", "full: write min/max peaks JSON (audiowaveform format) to this path")
+	waveW := flag.Int("waveform-width", 1800, "pixel columns of -waveform/-peaks")
+	waveH := flag.Int("waveform-height", 280, "height of the -waveform PNG")
 	trimSec := flag.Float64("trim-ends", 0.0, "trim this many seconds from start/end of segments")
+	recursive := flag.Bool("recursive", false, "batch: descend into subdirectories")
+	glob := flag.String("glob", "", "batch: only files whose name matches this pattern, e.g. \"*.flac\"")
+	reportDir := flag.String("report-dir", "reports", "batch: directory for per-file reports (mirrors the input tree)")
+	startStr := flag.String("start", "", "analyze from this position: seconds or [h:]m:ss")
+	endStr := flag.String("end", "", "analyze up to this position: seconds or [h:]m:ss")
+	rangeStr := flag.String("range", "", "analyze only this section, start-end, e.g. 1:00-2:30 (instead of -start/-end)")
+	stream := flag.Int("stream", 0, "analyze this audio stream of a multi-stream file (0-based, as ffmpeg 0:a:N)")
+	allStreams := flag.Bool("all-streams", false, "full: analyze every audio stream, one report section per stream")
+	downloadFirst := flag.Bool("download-first", false, "full: fetch a URL input to a temp file once instead of streaming it to every pass")
+	writeTags := flag.Bool("write-tags", false, "replaygain: write the track tags into the files (mp3/flac/opus/vorbis)")
+	declipHeadroom := flag.Float64("declip-headroom", 3, "declip: lower the output by this many dB so reconstructed peaks do not clip again")
+	corrWin := flag.Float64("corr-window", cfg.CorrWin, "stereo: L/R correlation timeline in windows of this many seconds (0=off)")
+	corrThresh := flag.Float64("corr-threshold", cfg.CorrThresh, "stereo: report the sections whose windowed correlation is below this")
+	balance := flag.Float64("balance-threshold", cfg.BalanceDB, "stereo: flag an L/R RMS difference above this many dB (0=off)")
+	dcThreshold := flag.Float64("dc-threshold", cfg.DCThreshold, "flag (and fix-dc: correct) channels whose |DC offset| is above this (0=off)")
+	dcMethod := flag.String("dc-method", "shift", "fix-dc: shift (subtract the measured offset) | highpass (5 Hz, follows drift)")
+	trimPad := flag.Float64("trim-pad", 0.2, "trim: keep this many seconds of the silence before and after the audio")
+	trimFade := flag.Float64("trim-fade", 0, "trim: fade this many seconds in and out at the cut edges (0=hard cut)")
+	chapFormat := flag.String("chapter-format", "cue", "chapters: cue | ffmetadata")
+	chapGap := flag.Float64("chapter-gap", 2, "chapters: a silence at least this many seconds long starts a new track")
+	chapMarkers := flag.String("markers", "", "chapters: track starts as comma-separated seconds or [h:]m:ss, instead of silence")
+	chapName := flag.String("chapter-name", "Track {n}", "chapters: title template; {n} is the track number, {start} its position")
+	chapFirst := flag.Int("chapter-first", 1, "chapters: number of the first track")
+	declick := flag.Bool("declick", false, "declip: also run adeclick on the clipped sections")
+	noAlign := flag.Bool("no-align", false, "nulltest: subtract as-is instead of cross-correlating the first 10s to line B up with A")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "analit — overkill audio analysis\n\n")
-		fmt.Fprintf(os.Stderr, "Usage:\n  analit full <input> [flags]\n  analit compare <inputA> <inputB> [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n  analit full <input|-|url> [flags]\n  analit compare <inputA> <inputB> [flags]\n  analit nulltest <inputA> <inputB> [flags]\n  analit stems <dir> [mix] [flags]\n  analit album <file|dir>... [flags]\n  analit replaygain <file|dir>... [-write-tags] [flags]\n  analit declip <input> <output> [flags]\n  analit fix-dc <input> <output> [flags]\n  analit trim <input> <output> [flags]\n  analit chapters <input> [-markers t1,t2,...] [flags]\n  analit inventory <dir> -o catalog.csv [flags]\n  analit batch <dir> [-recursive] [-glob pattern] [flags]\n  analit stability <capture1> <capture2> [capture...] [flags]\n  analit tui <input> [flags]\n  analit selftest [flags]\n  analit metrics\n  analit query -db <file.sqlite> [metric<op>value,...]\n\n")
 		flag.PrintDefaults()
 	}
 	flag.Parse()
@@ -34,18 +100,142 @@ func main() {
 		os.Exit(2)
 	}
 
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
 	cfg.OutPath = *outPath
 	cfg.Report = strings.ToLower(*report)
+	if inferred := reportFromExt(cfg.OutPath); !explicit["report"] {
+		cfg.Report = inferred
+	} else if explicit["o"] && inferred != cfg.Report {
+		warnf("-o %s looks like %s but -report is %s", cfg.OutPath, inferred, cfg.Report)
+	}
 	cfg.FFmpegBin = *ffmpeg
 	cfg.FFprobeBin = *ffprobe
 	cfg.AubioBin = *aubio
+	cfg.FpcalcBin = *fpcalc
+	cfg.AcoustIDKey = *acoustKey
+	cfg.Fingerprint = *fprint || cfg.AcoustIDKey != ""
+	cfg.DBPath = *dbPath
+	cfg.SQLiteBin = *sqlite
 	cfg.BPMEngine = strings.ToLower(*bpmEng)
+	cfg.AubioBufSize = *aubioBuf
+	cfg.AubioHopSize = *aubioHop
 	cfg.Bands = parseBands(*bandsStr)
 	cfg.UseBands = !(*noBands)
 	cfg.UseEBUR128 = !(*noEbu)
+	cfg.EBUPeak = strings.ToLower(*ebuPeak)
+	cfg.EBUDualMono = strings.ToLower(*dualMono)
+	cfg.LoudnessStd = strings.ToLower(*loudStd)
+	if cfg.LoudnessStd != "atsc" {
+		cfg.LoudnessStd = "ebu"
+	}
+	cfg.Delivery = strings.ToLower(*delivery)
+	switch cfg.Delivery {
+	case "", "cd", "streaming", "vinyl", "broadcast":
+	default:
+		fail("delivery: unknown target %q (cd|streaming|vinyl|broadcast)", *delivery)
+	}
+	cfg.Platform = strings.ToLower(*target)
+	switch cfg.Platform {
+	case "", "spotify", "youtube", "apple", "tidal", "broadcast":
+	default:
+		fail("target: unknown platform %q (spotify|youtube|apple|tidal|broadcast)", *target)
+	}
+	cfg.UseClicks = *clicks
+	cfg.Defects = *defects
+	cfg.DialogGate = *dialog
+	cfg.Mono = *mono
+	cfg.PhaseScope = *phase
+	for _, c := range strings.Split(*chanSel, ",") {
+		if c = strings.ToUpper(strings.TrimSpace(c)); c != "" {
+			cfg.Channels = append(cfg.Channels, c)
+		}
+	}
 	cfg.AstatsWin = *astWin
+	if *tpOver != 4 && *tpOver != 8 {
+		fail("tp-oversample: want 4 or 8, got %d", *tpOver)
+	}
+	cfg.Oversample = *tpOver
+	cfg.TiltFreq = *tilt
 	cfg.SilThresDB = *silTh
+	cfg.LUFSTarget = *lufsRel
+	head, tail, err := parseSections(*sections)
+	if err != nil {
+		fail("sections: %v", err)
+	}
+	cfg.HeadFrac, cfg.TailFrac = head, tail
+	if *rangeStr != "" {
+		if *startStr != "" || *endStr != "" {
+			fail("range: use either -range or -start/-end")
+		}
+		if cfg.Start, cfg.End, err = parseRange(*rangeStr); err != nil {
+			fail("range: %v", err)
+		}
+	}
+	if *startStr != "" {
+		if cfg.Start, err = parseClock(*startStr); err != nil {
+			fail("start: %v", err)
+		}
+	}
+	if *endStr != "" {
+		if cfg.End, err = parseClock(*endStr); err != nil {
+			fail("end: %v", err)
+		}
+	}
+	if cfg.End > 0 && cfg.End <= cfg.Start {
+		fail("range: end %gs is not after start %gs", cfg.End, cfg.Start)
+	}
+	if *stream < 0 {
+		fail("stream: want a stream index >= 0, got %d", *stream)
+	}
+	if *allStreams && (explicit["stream"] || *splitSec > 0 || *previewBand != "" || *wavePNG != "" || *peaksOut != "") {
+		fail("all-streams: -stream, -split-on-silence, -preview-band and -waveform/-peaks work on a single stream")
+	}
+	cfg.Stream = *stream
+	cfg.StructLU = *structLU
+	cfg.DCThreshold = *dcThreshold
+	cfg.CorrWin, cfg.CorrThresh = *corrWin, *corrThresh
+	cfg.BalanceDB = *balance
+	switch cfg.MinSeverity = Severity(strings.ToLower(*minSev)); cfg.MinSeverity {
+	case SevInfo, SevWarn, SevError:
+	default:
+		fail("min-severity: want info|warn|error, got %q", *minSev)
+	}
+	cfg.JSONCompact = *jsonCompact
+	setMaxProcs(*maxProcs)
+	cfg.Explain = *explain
+	cfg.SortBy = strings.ToLower(*sortBy)
+	cfg.SortReverse = *sortRev
+	cfg.Precision = parsePrecision(cfg.Precision, *precStr)
 
+	if strings.ToLower(args[0]) == "metrics" {
+		fmt.Print(renderMetricRegistry())
+		return
+	}
+	if cfg.DBPath != "" {
+		if err := mustHave(cfg.SQLiteBin); err != nil {
+			fail("sqlite3 not found (needed for -db): %v", err)
+		}
+	}
+	if strings.ToLower(args[0]) == "query" {
+		if cfg.DBPath == "" {
+			fail("query: need -db <file.sqlite>")
+		}
+		out, err := queryDB(cfg, strings.Join(args[1:], ","))
+		if err != nil {
+			fail("query: %v", err)
+		}
+		if !explicit["o"] {
+			fmt.Print(out)
+			return
+		}
+		if err := writeFile(cfg.OutPath, []byte(out)); err != nil {
+			fail("write query: %v", err)
+		}
+		wrote(cfg.OutPath)
+		return
+	}
 	if err := mustHave(cfg.FFmpegBin); err != nil {
 		fail("ffmpeg not found: %v", err)
 	}
@@ -54,29 +244,114 @@ func main() {
 	}
 	if cfg.BPMEngine == "aubio" {
 		if err := mustHave(cfg.AubioBin); err != nil {
-			fmt.Fprintf(os.Stderr, "[warn] aubio not found; disabling aubio features\n")
+			warnf("aubio not found; disabling aubio features")
 			cfg.BPMEngine = "none"
 		}
 	}
+	if cfg.Fingerprint {
+		if err := mustHave(cfg.FpcalcBin); err != nil {
+			warnf("fpcalc not found; disabling fingerprinting")
+			cfg.Fingerprint = false
+		}
+	}
 
 	switch strings.ToLower(args[0]) {
 	case "full":
 		if len(args) < 2 {
 			fail("full: missing <input>")
 		}
-		in := args[1]
+		in, name := args[1], args[1]
+		if in == stdinInput || (isURL(in) && *downloadFirst) {
+			file, remove, err := bufferInput(cfg, in)
+			if err != nil {
+				fail("%v", err)
+			}
+			// fail exits without running defers; it calls remove itself
+			atExit = append(atExit, remove)
+			defer remove()
+			in = file
+			if name == stdinInput {
+				name = "stdin.mka"
+			}
+		}
+		if *allStreams {
+			as, err := analyzeStreams(cfg, in, *jobs)
+			if err != nil {
+				fail("analysis failed: %v", err)
+			}
+			for _, a := range as {
+				a.File = name
+				recordAnalysis(cfg, a)
+			}
+			if err := writeReports(cfg, as, cfg.OutPath); err != nil {
+				fail("write: %v", err)
+			}
+			wrote(cfg.OutPath)
+			return
+		}
 		a, err := analyzeFile(cfg, in)
 		if err != nil {
 			fail("analysis failed: %v", err)
 		}
+		a.File = name
+		if *wavePNG != "" || *peaksOut != "" {
+			if *waveW < 1 || *waveH < 2 {
+				fail("waveform: bad size %dx%d", *waveW, *waveH)
+			}
+			// columns span what was analyzed: the -start/-end range, as decoded
+			dur := a.Probe.Duration
+			if a.Decoded != nil {
+				dur = *a.Decoded
+			}
+			pk, err := wavePeaks(cfg, in, a.Probe.SampleRate, dur, *waveW)
+			if err != nil {
+				fail("waveform: %v", err)
+			}
+			a.Waveform = &Waveform{PNG: *wavePNG, Peaks: *peaksOut, Width: pk.Length}
+			if *peaksOut != "" {
+				if err := writePeaksJSON(*peaksOut, pk); err != nil {
+					fail("write peaks: %v", err)
+				}
+				wrote(*peaksOut)
+			}
+			if *wavePNG != "" {
+				if err := writeWaveformPNG(*wavePNG, pk, *waveH); err != nil {
+					fail("write waveform: %v", err)
+				}
+				wrote(*wavePNG)
+			}
+		}
 		if err := writeReport(cfg, a, cfg.OutPath); err != nil {
 			fail("write: %v", err)
 		}
-		fmt.Printf("[+] wrote %s\n", cfg.OutPath)
+		wrote(cfg.OutPath)
+		recordAnalysis(cfg, a)
+		if *previewBand != "" {
+			bs := parseBands(*previewBand)
+			if len(bs) != 1 {
+				fail("preview-band: want a single lo-hi range, got %q", *previewBand)
+			}
+			out := *previewOut
+			if out == "" {
+				ln := localName(name)
+				out = fmt.Sprintf("%s-band-%g-%g.wav", strings.TrimSuffix(ln, filepath.Ext(ln)), bs[0].Lo, bs[0].Hi)
+			}
+			if err := ffmpegBandPreview(cfg, in, bs[0], out, *previewSec); err != nil {
+				fail("%v", err)
+			}
+			wrote(out)
+		}
 		if *splitSec > 0 {
-			if _, err := splitBySilence(cfg, in, a, *splitSec, *trimSec); err != nil {
+			segs, err := splitBySilence(cfg, in, localName(name), a, *splitSec, *trimSec)
+			if err != nil {
 				fail("split: %v", err)
 			}
+			if *segOut != "" {
+				if err := writeSegmentsJSON(*segOut, segs); err != nil {
+					fail("write segments: %v", err)
+				}
+				wrote(*segOut)
+			}
 		}
 
 	case "compare":
@@ -92,11 +367,292 @@ func main() {
 			fail("B: %v", err)
 		}
 		diff := compare(a1, a2)
-		out := renderDiff(cfg, diff)
-		if err := os.WriteFile(cfg.OutPath, []byte(out), 0644); err != nil {
+		out, err := renderDiff(cfg, diff)
+		if err != nil {
+			fail("render diff: %v", err)
+		}
+		if err := writeFile(cfg.OutPath, []byte(out)); err != nil {
 			fail("write diff: %v", err)
 		}
-		fmt.Printf("[+] wrote %s\n", cfg.OutPath)
+		wrote(cfg.OutPath)
+
+	case "nulltest":
+		if len(args) < 3 {
+			fail("nulltest: need <inputA> <inputB>")
+		}
+		n, err := nullTest(cfg, args[1], args[2], !*noAlign)
+		if err != nil {
+			fail("nulltest: %v", err)
+		}
+		out, err := renderNullTest(cfg, n)
+		if err != nil {
+			fail("render nulltest: %v", err)
+		}
+		if err := writeFile(cfg.OutPath, []byte(out)); err != nil {
+			fail("write nulltest: %v", err)
+		}
+		wrote(cfg.OutPath)
+
+	case "batch":
+		if len(args) < 2 {
+			fail("batch: missing <dir>")
+		}
+		files, err := collectAudio(args[1], *recursive, *glob)
+		if err != nil {
+			fail("batch: %v", err)
+		}
+		if len(files) == 0 {
+			fail("batch: no audio files in %s", args[1])
+		}
+		// ndjson streams each file's analysis to -o from the workers, so
+		// those lines are the summary
+		stream := strings.ToLower(cfg.Report) == "ndjson"
+		as, failed, err := analyzeBatch(cfg, args[1], files, *reportDir, *jobs)
+		if err != nil && len(as)+len(failed) == 0 {
+			fail("%v", err)
+		}
+		if !stream {
+			st := stability(as)
+			st.Failed = failed
+			sortRows(st.Rows, cfg.SortBy, cfg.SortReverse)
+			out, rerr := renderSpread(cfg, st, "Batch", "files")
+			if rerr != nil {
+				fail("render batch: %v", rerr)
+			}
+			if werr := writeFile(cfg.OutPath, []byte(out)); werr != nil {
+				fail("write summary: %v", werr)
+			}
+		}
+		wrote(cfg.OutPath)
+		if err != nil {
+			fail("%v; partial summary of %d/%d files written", err, len(as)+len(failed), len(files))
+		}
+		if len(failed) > 0 {
+			fail("batch: %d of %d files failed", len(failed), len(files))
+		}
+
+	case "stability":
+		if len(args) < 3 {
+			fail("stability: need at least two captures")
+		}
+		as, err := analyzeFiles(cfg, args[1:], *jobs)
+		if err != nil && len(as) == 0 {
+			fail("%v", err)
+		}
+		st := stability(as)
+		sortRows(st.Rows, cfg.SortBy, cfg.SortReverse)
+		out, rerr := renderStability(cfg, st)
+		if rerr != nil {
+			fail("render stability: %v", rerr)
+		}
+		if werr := writeFile(cfg.OutPath, []byte(out)); werr != nil {
+			fail("write stability: %v", werr)
+		}
+		wrote(cfg.OutPath)
+		if err != nil {
+			fail("%v; partial summary of %d/%d captures written", err, len(as), len(args)-1)
+		}
+
+	case "inventory":
+		if len(args) < 2 {
+			fail("inventory: missing <dir>")
+		}
+		rows, total, err := inventory(cfg, args[1], *maxProcs)
+		if err != nil && len(rows) == 0 {
+			fail("inventory: %v", err)
+		}
+		if werr := writeFile(cfg.OutPath, []byte(renderInventory(rows))); werr != nil {
+			fail("write inventory: %v", werr)
+		}
+		infof("[+] probed %d/%d files", len(rows), total)
+		wrote(cfg.OutPath)
+		if err != nil {
+			fail("inventory: %v; partial catalog written", err)
+		}
+
+	case "stems":
+		if len(args) < 2 {
+			fail("stems: missing <dir>")
+		}
+		mix := ""
+		if len(args) >= 3 {
+			mix = args[2]
+		}
+		r, err := analyzeStems(cfg, args[1], mix)
+		if err != nil {
+			fail("stems: %v", err)
+		}
+		out, err := renderStems(cfg, r)
+		if err != nil {
+			fail("render stems: %v", err)
+		}
+		if err := writeFile(cfg.OutPath, []byte(out)); err != nil {
+			fail("write stems: %v", err)
+		}
+		wrote(cfg.OutPath)
+
+	case "album":
+		if len(args) < 2 {
+			fail("album: missing <files|dir>")
+		}
+		files, err := expandInputs(args[1:], *recursive, *glob)
+		if err != nil {
+			fail("album: %v", err)
+		}
+		if len(files) == 0 {
+			fail("album: no audio files")
+		}
+		r, err := analyzeAlbum(cfg, files, *jobs)
+		if err != nil {
+			fail("album: %v", err)
+		}
+		out, err := renderAlbum(cfg, r)
+		if err != nil {
+			fail("render album: %v", err)
+		}
+		if err := writeFile(cfg.OutPath, []byte(out)); err != nil {
+			fail("write album: %v", err)
+		}
+		wrote(cfg.OutPath)
+
+	case "replaygain":
+		if len(args) < 2 {
+			fail("replaygain: missing <file|dir>")
+		}
+		if !cfg.UseEBUR128 {
+			fail("replaygain: needs ebur128 (drop -no-ebur128)")
+		}
+		files, err := expandInputs(args[1:], *recursive, *glob)
+		if err != nil {
+			fail("replaygain: %v", err)
+		}
+		rows, err := replayGainFiles(cfg, files, *jobs, *writeTags)
+		if err != nil && len(rows) == 0 {
+			fail("replaygain: %v", err)
+		}
+		out, rerr := renderReplayGain(cfg, rows)
+		if rerr != nil {
+			fail("render replaygain: %v", rerr)
+		}
+		if !explicit["o"] {
+			fmt.Print(out)
+		} else if werr := writeFile(cfg.OutPath, []byte(out)); werr != nil {
+			fail("write replaygain: %v", werr)
+		} else {
+			wrote(cfg.OutPath)
+		}
+		if err != nil {
+			fail("replaygain: %v; %d/%d files done", err, len(rows), len(files))
+		}
+
+	case "declip":
+		if len(args) < 3 {
+			fail("declip: need <input> <output>")
+		}
+		if *declipHeadroom < 0 {
+			fail("declip-headroom: want dB >= 0, got %g", *declipHeadroom)
+		}
+		r, err := declip(cfg, args[1], args[2], *declipHeadroom, *declick)
+		if err != nil {
+			fail("declip: %v", err)
+		}
+		out, err := renderDeclip(cfg, r)
+		if err != nil {
+			fail("render declip: %v", err)
+		}
+		if !explicit["o"] {
+			fmt.Print(out)
+		} else if err := writeFile(cfg.OutPath, []byte(out)); err != nil {
+			fail("write declip: %v", err)
+		} else {
+			wrote(cfg.OutPath)
+		}
+
+	case "fix-dc":
+		if len(args) < 3 {
+			fail("fix-dc: need <input> <output>")
+		}
+		if *dcMethod != "shift" && *dcMethod != "highpass" {
+			fail("dc-method: unknown %q (shift|highpass)", *dcMethod)
+		}
+		if cfg.DCThreshold <= 0 {
+			fail("fix-dc: needs -dc-threshold > 0")
+		}
+		r, err := fixDC(cfg, args[1], args[2], *dcMethod)
+		if err != nil {
+			fail("fix-dc: %v", err)
+		}
+		out, err := renderDCFix(cfg, r)
+		if err != nil {
+			fail("render fix-dc: %v", err)
+		}
+		if !explicit["o"] {
+			fmt.Print(out)
+		} else if err := writeFile(cfg.OutPath, []byte(out)); err != nil {
+			fail("write fix-dc: %v", err)
+		} else {
+			wrote(cfg.OutPath)
+		}
+
+	case "trim":
+		if len(args) < 3 {
+			fail("trim: need <input> <output>")
+		}
+		if *trimPad < 0 || *trimFade < 0 {
+			fail("trim: -trim-pad and -trim-fade want seconds >= 0")
+		}
+		r, err := trimSilence(cfg, args[1], args[2], *trimPad, *trimFade)
+		if err != nil {
+			fail("trim: %v", err)
+		}
+		out, err := renderTrim(cfg, r)
+		if err != nil {
+			fail("render trim: %v", err)
+		}
+		if !explicit["o"] {
+			fmt.Print(out)
+		} else if err := writeFile(cfg.OutPath, []byte(out)); err != nil {
+			fail("write trim: %v", err)
+		} else {
+			wrote(cfg.OutPath)
+		}
+
+	case "chapters":
+		if len(args) < 2 {
+			fail("chapters: need <input>")
+		}
+		if *chapFormat != "cue" && *chapFormat != "ffmetadata" {
+			fail("chapter-format: unknown %q (cue|ffmetadata)", *chapFormat)
+		}
+		if *chapGap <= 0 {
+			fail("chapter-gap: want seconds > 0, got %g", *chapGap)
+		}
+		out, err := buildChapters(cfg, args[1], *chapFormat, *chapMarkers, *chapGap, *chapFirst, *chapName)
+		if err != nil {
+			fail("chapters: %v", err)
+		}
+		if !explicit["o"] {
+			fmt.Print(out)
+		} else if err := writeFile(cfg.OutPath, []byte(out)); err != nil {
+			fail("write chapters: %v", err)
+		} else {
+			wrote(cfg.OutPath)
+		}
+
+	case "selftest":
+		if runSelftest(cfg) > 0 {
+			os.Exit(1)
+		}
+
+	case "tui":
+		if len(args) < 2 {
+			fail("tui: missing <input>")
+		}
+		a, err := analyzeFile(cfg, args[1])
+		if err != nil {
+			fail("analysis failed: %v", err)
+		}
+		showTUI(cfg, a)
 
 	default:
 		flag.Usage()
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+)
+
+// parseMetadata picks the identifying fields out of the merged ffprobe tags
+// (keys lowercased). The same field goes by different names across ID3,
+// Vorbis comments and MP4 atoms; the first name present wins.
+func parseMetadata(tags map[string]string) *Metadata {
+	if len(tags) == 0 {
+		return nil
+	}
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := strings.TrimSpace(tags[k]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	m := &Metadata{
+		Title:   first("title"),
+		Artist:  first("artist", "album_artist"),
+		Album:   first("album"),
+		ISRC:    first("isrc", "tsrc"),
+		Encoder: first("encoder", "encoded_by"),
+		Tags:    tags,
+	}
+	// "-6.54 dB" gains, linear peaks
+	num := func(key string) *float64 {
+		s := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(tags[key]), "dB"))
+		if s == "" {
+			return nil
+		}
+		v := parseFloat(s)
+		return &v
+	}
+	rg := EmbeddedReplayGain{
+		TrackGainDB: num("replaygain_track_gain"),
+		TrackPeak:   num("replaygain_track_peak"),
+		AlbumGainDB: num("replaygain_album_gain"),
+		AlbumPeak:   num("replaygain_album_peak"),
+	}
+	if rg.TrackGainDB != nil || rg.AlbumGainDB != nil {
+		m.ReplayGain = &rg
+	}
+	return m
+}
+
+// metadataLine is the one-line summary of the identifying tags
+func metadataLine(m *Metadata) string {
+	var parts []string
+	add := func(label, v string) {
+		if v != "" {
+			parts = append(parts, label+" "+v)
+		}
+	}
+	add("Title", m.Title)
+	add("Artist", m.Artist)
+	add("Album", m.Album)
+	add("ISRC", m.ISRC)
+	add("Encoder", m.Encoder)
+	return strings.Join(parts, " | ")
+}
+
+// taggedReplayGain summarizes the embedded gains, e.g. "track -6.54 dB (peak
+// 0.988) | album -7.10 dB"
+func taggedReplayGain(p Precision, rg *EmbeddedReplayGain) string {
+	var parts []string
+	add := func(label string, gain, peak *float64) {
+		if gain == nil {
+			return
+		}
+		s := label + " " + p.lu(*gain) + " dB"
+		if peak != nil {
+			s += " (peak " + p.shape(*peak) + ")"
+		}
+		parts = append(parts, s)
+	}
+	add("track", rg.TrackGainDB, rg.TrackPeak)
+	add("album", rg.AlbumGainDB, rg.AlbumPeak)
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// metric identifiers: the one vocabulary for compare deltas, stability
+// spreads and CSV columns. These are part of the output format; add new
+// names freely but never rename or reuse one.
+const (
+	MetricDuration    = "duration_s"
+	MetricSampleRate  = "sample_rate"
+	MetricChannels    = "channels"
+	MetricPeak        = "peak_db"
+	MetricRMS         = "rms_db"
+	MetricCrest       = "crest_db"
+	MetricTruePeak    = "true_peak_dbtp"
+	MetricLUFS        = "lufs_integrated"
+	MetricLRA         = "lufs_range"
+	MetricSideMid     = "stereo_side_mid_db"
+	MetricCorrelation = "correlation"
+	MetricBPM         = "bpm_median"
+	MetricKey         = "key"
+	MetricBalance     = "lr_balance_db"
+	MetricContent     = "content"
+	MetricSpeechPct   = "speech_pct"
+	MetricPLR         = "plr_db"
+	MetricPSR         = "psr_db"
+	MetricDR          = "dr"
+)
+
+type metricDef struct {
+	Name    string
+	Unit    string
+	Doc     string
+	Compare bool // numeric and meaningful as a B-A delta / capture spread
+	// nil for non-numeric metrics (key); ok=false when not measured
+	Value func(a *Analysis) (float64, bool)
+}
+
+func always(f func(a *Analysis) float64) func(*Analysis) (float64, bool) {
+	return func(a *Analysis) (float64, bool) { return f(a), true }
+}
+
+func optional(f func(a *Analysis) *float64) func(*Analysis) (float64, bool) {
+	return func(a *Analysis) (float64, bool) {
+		if p := f(a); p != nil {
+			return *p, true
+		}
+		return 0, false
+	}
+}
+
+// metricRegistry in report order
+var metricRegistry = []metricDef{
+	{MetricDuration, "s", "declared duration", true, always(func(a *Analysis) float64 { return a.Probe.Duration })},
+	{MetricSampleRate, "Hz", "stream sample rate", false, always(func(a *Analysis) float64 { return float64(a.Probe.SampleRate) })},
+	{MetricChannels, "", "channel count", false, always(func(a *Analysis) float64 { return float64(a.Probe.Channels) })},
+	{MetricPeak, "dBFS", "sample peak", true, always(func(a *Analysis) float64 { return a.Level.PeakDB })},
+	{MetricRMS, "dBFS", "RMS level", true, always(func(a *Analysis) float64 { return a.Level.RMSDB })},
+	{MetricCrest, "dB", "peak minus RMS", true, always(func(a *Analysis) float64 { return a.Level.CrestDB })},
+	{MetricTruePeak, "dBTP", "oversampled true peak (ebur128, or the -tp-oversample pass)", false, optional(func(a *Analysis) *float64 { return a.Level.TruePeakDBTP })},
+	{MetricLUFS, "LUFS", "BS.1770 gated integrated loudness", true, optional(func(a *Analysis) *float64 {
+		if a.Loudness == nil {
+			return nil
+		}
+		return &a.Loudness.Integrated
+	})},
+	{MetricLRA, "LU", "loudness range", true, optional(func(a *Analysis) *float64 {
+		if a.Loudness == nil {
+			return nil
+		}
+		return &a.Loudness.Range
+	})},
+	{MetricSideMid, "dB", "side energy relative to mid", true, always(func(a *Analysis) float64 { return a.Stereo.SideMidRatioDB })},
+	{MetricCorrelation, "", "L/R correlation, -1..+1", false, optional(func(a *Analysis) *float64 { return a.Stereo.Correlation })},
+	{MetricBPM, "BPM", "median tempo (aubio)", true, optional(func(a *Analysis) *float64 {
+		if a.Tempo == nil {
+			return nil
+		}
+		return a.Tempo.BPMMedian
+	})},
+	{MetricKey, "", "estimated key and scale (aubio)", false, nil},
+	{MetricBalance, "dB", "left minus right RMS; + leans left", true, optional(func(a *Analysis) *float64 { return a.Stereo.BalanceDB })},
+	{MetricContent, "", "speech, music or mixed", false, nil},
+	{MetricSpeechPct, "%", "share of non-silent seconds classified as speech", true, optional(func(a *Analysis) *float64 {
+		if a.Content == nil {
+			return nil
+		}
+		return &a.Content.SpeechPct
+	})},
+	{MetricPLR, "dB", "peak-to-loudness ratio: true peak minus integrated", true, optional(func(a *Analysis) *float64 { return a.Level.PLR })},
+	{MetricPSR, "dB", "peak-to-short-term ratio: true peak minus max short-term", true, optional(func(a *Analysis) *float64 { return a.Level.PSR })},
+	{MetricDR, "DR", "TT DR meter style dynamic range, 3 s blocks", true, func(a *Analysis) (float64, bool) {
+		if a.Level.DR == nil {
+			return 0, false
+		}
+		return float64(*a.Level.DR), true
+	}},
+}
+
+// metricText gives each non-numeric metric (nil Value) its text; "" when
+// not measured
+var metricText = map[string]func(a *Analysis) string{
+	MetricKey: func(a *Analysis) string {
+		if a.Key == nil || a.Key.Key == nil {
+			return ""
+		}
+		if a.Key.Scale != nil {
+			return *a.Key.Key + " " + *a.Key.Scale
+		}
+		return *a.Key.Key
+	},
+	MetricContent: func(a *Analysis) string {
+		if a.Content == nil {
+			return ""
+		}
+		return a.Content.Label
+	},
+}
+
+func metricNames() []string {
+	names := make([]string, len(metricRegistry))
+	for i, m := range metricRegistry {
+		names[i] = m.Name
+	}
+	return names
+}
+
+func renderMetricRegistry() string {
+	var b strings.Builder
+	for _, m := range metricRegistry {
+		unit := m.Unit
+		if unit == "" {
+			unit = "-"
+		}
+		fmt.Fprintf(&b, "%-20s %-5s %s\n", m.Name, unit, m.Doc)
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package main
+
+import "fmt"
+
+type Severity string
+
+const (
+	SevInfo  Severity = "info"
+	SevWarn  Severity = "warn"
+	SevError Severity = "error"
+)
+
+// Note is a report finding; Code is stable and meant for machine consumers,
+// Message is for humans and may change.
+type Note struct {
+	Severity Severity
+	Code     string
+	Message  string
+}
+
+func newNote(sev Severity, code, format string, a ...any) Note {
+	return Note{Severity: sev, Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+func (s Severity) rank() int {
+	switch s {
+	case SevWarn:
+		return 1
+	case SevError:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// filterNotes drops notes below min severity
+func filterNotes(notes []Note, min Severity) []Note {
+	var out []Note
+	for _, n := range notes {
+		if n.Severity.rank() >= min.rank() {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	alignCoarseRate = 4000 // Hz, for the wide lag search
+	alignSeconds    = 10   // how much of each file the alignment looks at
+	alignMaxLagSec  = 1.0
+
+	// residual level reported for an exact digital null; astats measures
+	// in float, so any difference that is left reads above this
+	nullFloorDB = -200.0
+)
+
+// nullLevelChain measures overall peak and RMS in float: volumedetect
+// works on s16 and would floor the residual near -91 dBFS
+const nullLevelChain = "aformat=sample_fmts=flt,astats=measure_perchannel=none"
+
+// decodeMonoFloats decodes the first secs seconds of in as mono float32 at rate
+func decodeMonoFloats(cfg *Config, in string, rate int, secs float64) ([]float32, error) {
+	cmd := exec.Command(cfg.FFmpegBin, rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-loglevel", "error",
+		"-i", in, "-vn", "-t", fmt.Sprintf("%g", secs), "-ac", "1", "-ar", fmt.Sprint(rate),
+		"-f", "f32le", "-acodec", "pcm_f32le", "-"})...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	acquireProc()
+	defer releaseProc()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	var xs []float32
+	r := bufio.NewReaderSize(stdout, 1<<16)
+	var buf [4]byte
+	for {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			break
+		}
+		xs = append(xs, math.Float32frombits(binary.LittleEndian.Uint32(buf[:])))
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg decode: %w", err)
+	}
+	return xs, nil
+}
+
+// bestLag returns the lag in [lo,hi] maximizing sum a[i]*b[i+lag]
+func bestLag(a, b []float32, lo, hi int) int {
+	best, bestV := 0, math.Inf(-1)
+	for lag := lo; lag <= hi; lag++ {
+		v := 0.0
+		for i := range a {
+			j := i + lag
+			if j < 0 || j >= len(b) {
+				continue
+			}
+			v += float64(a[i]) * float64(b[j])
+		}
+		if v > bestV {
+			best, bestV = lag, v
+		}
+	}
+	return best
+}
+
+// alignOffset finds how many samples (at rate) b runs late relative to a:
+// a wide search at 4 kHz, then a ±2 coarse-step refinement at full rate
+func alignOffset(cfg *Config, a, b string, rate int) (int, error) {
+	ca, err := decodeMonoFloats(cfg, a, alignCoarseRate, alignSeconds)
+	if err != nil {
+		return 0, err
+	}
+	cb, err := decodeMonoFloats(cfg, b, alignCoarseRate, alignSeconds)
+	if err != nil {
+		return 0, err
+	}
+	maxLag := int(alignMaxLagSec * alignCoarseRate)
+	coarse := bestLag(ca, cb, -maxLag, maxLag)
+
+	fa, err := decodeMonoFloats(cfg, a, rate, alignSeconds)
+	if err != nil {
+		return 0, err
+	}
+	fb, err := decodeMonoFloats(cfg, b, rate, alignSeconds)
+	if err != nil {
+		return 0, err
+	}
+	step := rate / alignCoarseRate
+	center := coarse * rate / alignCoarseRate
+	return bestLag(fa, fb, center-2*step, center+2*step), nil
+}
+
+// trims whichever input starts late so both line up at sample 0
+func alignChains(offset int) (string, string) {
+	a, b := "[0:a]aformat=sample_fmts=flt", "[1:a]aformat=sample_fmts=flt"
+	switch {
+	case offset > 0:
+		b += fmt.Sprintf(",atrim=start_sample=%d,asetpts=PTS-STARTPTS", offset)
+	case offset < 0:
+		a += fmt.Sprintf(",atrim=start_sample=%d,asetpts=PTS-STARTPTS", -offset)
+	}
+	return a + "[a]", b + "[b]"
+}
+
+// nullTest subtracts b from a (after optional alignment) and measures the
+// residual; the deeper the null relative to a, the more identical they are
+func nullTest(cfg *Config, a, b string, align bool) (*NullTest, error) {
+	pa, err := ffprobeInfo(cfg, a)
+	if err != nil {
+		return nil, fmt.Errorf("A: %w", err)
+	}
+	pb, err := ffprobeInfo(cfg, b)
+	if err != nil {
+		return nil, fmt.Errorf("B: %w", err)
+	}
+	n := &NullTest{A: a, B: b}
+	if pa.SampleRate != pb.SampleRate {
+		n.Notes = append(n.Notes, newNote(SevWarn, "RATE_MISMATCH", "Sample rates differ (%d vs %d Hz); B is resampled, which alone prevents a deep null.", pa.SampleRate, pb.SampleRate))
+	}
+	if align && pa.SampleRate > 0 {
+		off, err := alignOffset(cfg, a, b, pa.SampleRate)
+		if err != nil {
+			return nil, fmt.Errorf("align: %w", err)
+		}
+		n.Aligned, n.OffsetSamples = true, off
+		n.OffsetSec = float64(off) / float64(pa.SampleRate)
+	}
+	ca, cb := alignChains(n.OffsetSamples)
+	filter := ca + ";" + cb + ";" +
+		fmt.Sprintf("[b]aresample=%d[br];", pa.SampleRate) +
+		"[a][br]amix=inputs=2:weights='1 -1':normalize=0:duration=shortest," + nullLevelChain
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", a, "-i", b, "-vn", "-filter_complex", filter, "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	peak, rms, err := parseNullLevels(out)
+	if err != nil {
+		return nil, fmt.Errorf("residual: %w", err)
+	}
+	args = rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", a, "-vn", "-af", nullLevelChain, "-f", "null", "-"})
+	out, _ = runCmd(cfg.FFmpegBin, args...)
+	_, refRMS, err := parseNullLevels(out)
+	if err != nil {
+		return nil, fmt.Errorf("A level: %w", err)
+	}
+	n.ResidualPeakDB, n.ResidualRMSDB = peak, rms
+	n.ReferenceRMSDB = refRMS
+	n.DepthDB = refRMS - n.ResidualRMSDB
+	n.Verdict = nullVerdict(n)
+	n.Notes = filterNotes(n.Notes, cfg.MinSeverity)
+	return n, nil
+}
+
+// parseNullLevels reads the overall peak and RMS of nullLevelChain; astats
+// prints -inf for an all-zero signal, which becomes nullFloorDB
+func parseNullLevels(out string) (peakDB, rmsDB float64, err error) {
+	ov, _ := parseAstats(out)
+	if len(ov) == 0 {
+		return 0, 0, fmt.Errorf("astats parse failed")
+	}
+	level := func(k string) float64 {
+		if v, ok := ov[k]; ok {
+			return math.Max(v, nullFloorDB)
+		}
+		return nullFloorDB
+	}
+	return level("peak_level_db"), level("rms_level_db"), nil
+}
+
+func nullVerdict(n *NullTest) string {
+	switch {
+	case n.ResidualPeakDB <= nullFloorDB:
+		return "identical"
+	case n.DepthDB >= 90:
+		return "identical within dither"
+	case n.DepthDB >= 60:
+		return "transparent"
+	case n.DepthDB >= 30:
+		return "minor change"
+	}
+	return "substantial change"
+}
+
+func renderNullTest(cfg *Config, n *NullTest) (string, error) {
+	p := cfg.Precision
+	var b strings.Builder
+	switch strings.ToLower(cfg.Report) {
+	case "json", "yaml":
+		return renderJSON(cfg, n)
+	case "md":
+		fmt.Fprintf(&b, "# Null test: %s − %s\n\n", filepath.Base(n.A), filepath.Base(n.B))
+		if n.Aligned {
+			fmt.Fprintf(&b, "- Alignment: B offset `%d samples` (`%ss`)\n", n.OffsetSamples, p.sec(n.OffsetSec))
+		}
+		fmt.Fprintf(&b, "- Residual peak: `%s dBFS`\n- Residual RMS: `%s dBFS`\n- Null depth: `%s dB` below A\n- Verdict: **%s**\n",
+			p.db(n.ResidualPeakDB), p.db(n.ResidualRMSDB), p.db(n.DepthDB), n.Verdict)
+		writeNotesMD(&b, n.Notes)
+	default:
+		fmt.Fprintf(&b, "NULL TEST: %s - %s\n\n", n.A, n.B)
+		if n.Aligned {
+			fmt.Fprintf(&b, "Alignment: B offset %d samples (%ss)\n", n.OffsetSamples, p.sec(n.OffsetSec))
+		}
+		fmt.Fprintf(&b, "Residual: Peak %s dBFS | RMS %s dBFS | Depth %s dB below A\nVerdict: %s\n",
+			p.db(n.ResidualPeakDB), p.db(n.ResidualRMSDB), p.db(n.DepthDB), n.Verdict)
+		writeNotesTXT(&b, n.Notes)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+// a residual below 16-bit resolution must keep its level, and an exact
+// null must read as the floor rather than be dropped
+func TestParseNullLevels(t *testing.T) {
+	deep := "[Parsed_astats_4 @ 0x1] Overall\n[Parsed_astats_4 @ 0x1] Peak level dB: -118.350000\n[Parsed_astats_4 @ 0x1] RMS level dB: -131.020000\n"
+	pk, rms, err := parseNullLevels(deep)
+	if err != nil || pk != -118.35 || rms != -131.02 {
+		t.Errorf("deep: peak %v, rms %v, err %v", pk, rms, err)
+	}
+	exact := "[Parsed_astats_4 @ 0x1] Overall\n[Parsed_astats_4 @ 0x1] Peak level dB: -inf\n[Parsed_astats_4 @ 0x1] RMS level dB: -inf\n[Parsed_astats_4 @ 0x1] Number of samples: 48000\n"
+	pk, rms, err = parseNullLevels(exact)
+	if err != nil || pk != nullFloorDB || rms != nullFloorDB {
+		t.Errorf("exact: peak %v, rms %v, err %v", pk, rms, err)
+	}
+	if v := nullVerdict(&NullTest{ResidualPeakDB: pk, ResidualRMSDB: rms, DepthDB: -20 - rms}); v != "identical" {
+		t.Errorf("exact null verdict %q", v)
+	}
+	if _, _, err := parseNullLevels("Error opening input"); err == nil {
+		t.Error("no astats output: want an error")
+	}
+}
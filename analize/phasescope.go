@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+)
+
+const (
+	phaseScopeBins  = 64
+	phaseScopeChain = "pan=stereo|c0=c0|c1=c1"
+)
+
+// phaseScope decodes the first two channels to float and bins every L/R
+// sample pair into a bins×bins grid over [-1,1]² (Hist[r][l], R row, L
+// column), which is what a goniometer plots. Width is side energy over
+// total mid+side energy: 0% mono, 50% uncorrelated, 100% fully out of phase.
+func phaseScope(cfg *Config, in string, bins int) (*PhaseScope, error) {
+	cmd := exec.Command(cfg.FFmpegBin, rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-loglevel", "error",
+		"-i", in, "-vn", "-af", phaseScopeChain, "-f", "f32le", "-acodec", "pcm_f32le", "-"})...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	acquireProc()
+	defer releaseProc()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	ps := &PhaseScope{Bins: bins, Hist: make([][]int64, bins)}
+	for i := range ps.Hist {
+		ps.Hist[i] = make([]int64, bins)
+	}
+	bin := func(v float32) int {
+		i := int((float64(v) + 1) / 2 * float64(bins))
+		return max(0, min(bins-1, i))
+	}
+	var midE, sideE float64
+	r := bufio.NewReaderSize(stdout, 1<<16)
+	var frame [8]byte
+	for {
+		if _, err := io.ReadFull(r, frame[:]); err != nil {
+			break
+		}
+		l := math.Float32frombits(binary.LittleEndian.Uint32(frame[0:4]))
+		rr := math.Float32frombits(binary.LittleEndian.Uint32(frame[4:8]))
+		ps.Hist[bin(rr)][bin(l)]++
+		m, s := float64(l+rr)/2, float64(l-rr)/2
+		midE += m * m
+		sideE += s * s
+		ps.Samples++
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg decode: %w", err)
+	}
+	if ps.Samples == 0 {
+		return nil, fmt.Errorf("no samples decoded")
+	}
+	if midE+sideE > 0 {
+		w := 100 * sideE / (midE + sideE)
+		ps.WidthPct = &w
+	}
+	return ps, nil
+}
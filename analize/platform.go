@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// platformSpec is a playback normalization target. raises: the platform also
+// turns quiet tracks up, but only as far as the true-peak ceiling allows.
+type platformSpec struct {
+	name        string
+	targetLUFS  float64
+	ceilingDBTP float64
+	raises      bool
+}
+
+// published reference levels; broadcast is EBU R128 (A/85 with
+// -loudness-standard atsc) and is a compliance check, not a normalization
+var platformSpecs = map[string]platformSpec{
+	"spotify": {"Spotify", -14, -1, true},
+	"youtube": {"YouTube", -14, -1, false},
+	"apple":   {"Apple Music", -16, -1, true},
+	"tidal":   {"Tidal", -14, -1, false},
+}
+
+// broadcast tolerance around the reference, LU
+const (
+	ebuToleranceLU  = 0.5
+	atscToleranceLU = 2.0
+)
+
+func broadcastSpec(cfg *Config) (platformSpec, float64) {
+	if cfg.LoudnessStd == "atsc" {
+		return platformSpec{"ATSC A/85", -24, -2, false}, atscToleranceLU
+	}
+	return platformSpec{"EBU R128", -23, -1, false}, ebuToleranceLU
+}
+
+// platformTarget compares integrated loudness and true peak with the -target
+// spec and works out the gain the platform applies on playback
+func platformTarget(cfg *Config, lufs *LUFS, lv LevelStats) *PlatformTarget {
+	if cfg.Platform == "" || lufs == nil {
+		return nil
+	}
+	spec, tol := platformSpecs[cfg.Platform], 0.0
+	if cfg.Platform == "broadcast" {
+		spec, tol = broadcastSpec(cfg)
+	}
+	peak := lv.PeakDB // sample peak when true peak is off
+	if lv.TruePeakDBTP != nil {
+		peak = *lv.TruePeakDBTP
+	}
+	pt := &PlatformTarget{
+		Platform:     cfg.Platform,
+		Name:         spec.name,
+		TargetLUFS:   spec.targetLUFS,
+		CeilingDBTP:  spec.ceilingDBTP,
+		TruePeakDBTP: peak,
+		NeededDB:     spec.targetLUFS - lufs.Integrated,
+	}
+	switch {
+	case cfg.Platform == "broadcast":
+		pt.ToleranceLU = &tol
+	case pt.NeededDB < 0:
+		pt.GainDB = pt.NeededDB
+	case spec.raises:
+		pt.GainDB = math.Max(0, math.Min(pt.NeededDB, spec.ceilingDBTP-peak))
+	}
+	pt.ResultLUFS = lufs.Integrated + pt.GainDB
+	pt.ResultTruePeakDBTP = peak + pt.GainDB
+	return pt
+}
+
+// platformSummary is the one-line "what happens on playback" verdict
+func platformSummary(pt *PlatformTarget) string {
+	if pt.ToleranceLU != nil {
+		if math.Abs(pt.NeededDB) <= *pt.ToleranceLU {
+			return fmt.Sprintf("within %s (%.0f ±%.1f LU)", pt.Name, pt.TargetLUFS, *pt.ToleranceLU)
+		}
+		return fmt.Sprintf("out of %s spec: needs %+.1f dB to reach %.0f LUFS", pt.Name, pt.NeededDB, pt.TargetLUFS)
+	}
+	switch {
+	case pt.GainDB < -0.05:
+		return fmt.Sprintf("%s will turn this down %.1f dB", pt.Name, -pt.GainDB)
+	case pt.NeededDB > 0.05 && pt.GainDB >= pt.NeededDB-0.05:
+		return fmt.Sprintf("%s will turn this up %.1f dB", pt.Name, pt.GainDB)
+	case pt.NeededDB > 0.05:
+		return fmt.Sprintf("%s will turn this up only %.1f dB and it plays %.1f dB quiet", pt.Name, pt.GainDB, pt.NeededDB-pt.GainDB)
+	}
+	return fmt.Sprintf("%s plays this as is", pt.Name)
+}
+
+func platformNotes(pt *PlatformTarget) []Note {
+	if pt == nil {
+		return nil
+	}
+	var notes []Note
+	if pt.ToleranceLU != nil {
+		if math.Abs(pt.NeededDB) > *pt.ToleranceLU {
+			notes = append(notes, newNote(SevWarn, "TARGET_LOUDNESS", "Integrated %.1f LUFS is out of %s spec (%.0f ±%.1f LU); apply %+.1f dB.",
+				pt.TargetLUFS-pt.NeededDB, pt.Name, pt.TargetLUFS, *pt.ToleranceLU, pt.NeededDB))
+		}
+	} else {
+		switch {
+		case pt.GainDB < -1:
+			notes = append(notes, newNote(SevInfo, "TARGET_TURNED_DOWN", "%s will turn this down %.1f dB to %.0f LUFS; the extra loudness buys nothing there and costs dynamics.",
+				pt.Name, -pt.GainDB, pt.TargetLUFS))
+		case pt.NeededDB > 1 && pt.GainDB < pt.NeededDB-0.05:
+			why := "it never turns tracks up"
+			if pt.GainDB > 0 {
+				why = fmt.Sprintf("only %.1f dB before true peak hits %.0f dBTP", pt.GainDB, pt.CeilingDBTP)
+			}
+			notes = append(notes, newNote(SevWarn, "TARGET_QUIET", "%s plays this %.1f dB quieter than tracks at %.0f LUFS (%s); raise the master or limit peaks.",
+				pt.Name, pt.NeededDB-pt.GainDB, pt.TargetLUFS, why))
+		}
+	}
+	if pt.TruePeakDBTP > pt.CeilingDBTP {
+		what := "the lossy transcode may clip"
+		if pt.ToleranceLU != nil {
+			what = "the delivery will be rejected"
+		}
+		notes = append(notes, newNote(SevWarn, "TARGET_TRUE_PEAK", "True peak %.2f dBTP is over %s's %.0f dBTP limit; %s.",
+			pt.TruePeakDBTP, pt.Name, pt.CeilingDBTP, what))
+	}
+	return notes
+}
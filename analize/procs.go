@@ -0,0 +1,20 @@
+package main
+
+import (
+	"runtime"
+)
+
+// procSem bounds how many child processes (ffmpeg, ffprobe, aubio) run at
+// once across everything: the concurrent passes inside one file and the
+// files of a batch share it, so -jobs never multiplies into a fork bomb
+var procSem = make(chan struct{}, runtime.NumCPU())
+
+func setMaxProcs(n int) {
+	if n < 1 {
+		n = 1
+	}
+	procSem = make(chan struct{}, n)
+}
+
+func acquireProc() { procSem <- struct{}{} }
+func releaseProc() { <-procSem }
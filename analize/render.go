@@ -1,105 +1,431 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 func writeReport(cfg *Config, a *Analysis, path string) error {
 	var s string
 	switch strings.ToLower(cfg.Report) {
-	case "json":
-		buf, _ := json.MarshalIndent(a, "", "  ")
-		s = string(buf) + "\n"
+	case "json", "yaml":
+		var err error
+		if s, err = renderJSON(cfg, a); err != nil {
+			return err
+		}
+	case "ndjson":
+		return appendNDJSON(path, a)
 	case "md":
-		s = renderMD(a)
+		s = renderMD(cfg, a)
+	case "csv":
+		s = renderCSV(a)
 	default:
-		s = renderTXT(a)
+		s = renderTXT(cfg, a) + renderFooter(cfg, a, path)
 	}
-	return os.WriteFile(path, []byte(s), 0644)
+	return writeFile(path, []byte(s))
 }
 
-func renderTXT(a *Analysis) string {
+// writeReports writes the -all-streams analyses of one file as one report:
+// a section per stream in txt/md, a JSON/YAML array, a CSV row per stream
+func writeReports(cfg *Config, as []*Analysis, path string) error {
+	if len(as) == 1 {
+		return writeReport(cfg, as[0], path)
+	}
 	var b strings.Builder
-	fmt.Fprintf(&b, "File: %s\nWhen: %s\n\n", a.File, a.When)
-	fmt.Fprintf(&b, "Format: %s | Duration: %.3fs | SR: %d Hz | Ch: %d | Bitrate: %d bps | BitDepth: %d\n",
-		a.Probe.FormatName, a.Probe.Duration, a.Probe.SampleRate, a.Probe.Channels, a.Probe.BitRate, a.Probe.BitDepth)
-	fmt.Fprintf(&b, "Levels: Peak %.2f dBFS | RMS %.2f dBFS | Crest %.2f dB | Headroom %.2f dB",
-		a.Level.PeakDB, a.Level.RMSDB, a.Level.CrestDB, a.Level.HeadroomDB)
+	switch strings.ToLower(cfg.Report) {
+	case "json", "yaml":
+		s, err := renderJSON(cfg, as)
+		if err != nil {
+			return err
+		}
+		b.WriteString(s)
+	case "ndjson":
+		for _, a := range as {
+			if err := appendNDJSON(path, a); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "md":
+		for i, a := range as {
+			if i > 0 {
+				b.WriteString("\n---\n\n")
+			}
+			b.WriteString(renderMD(cfg, a))
+		}
+	case "csv":
+		b.WriteString(renderCSV(as...))
+	default:
+		for i, a := range as {
+			if i > 0 {
+				b.WriteString("\n" + strings.Repeat("=", 60) + "\n\n")
+			}
+			b.WriteString(renderTXT(cfg, a) + renderFooter(cfg, a, path))
+		}
+	}
+	return writeFile(path, []byte(b.String()))
+}
+
+// renderJSON is pretty by default, compact with -json-compact; with
+// -report yaml the same document is emitted as YAML
+func renderJSON(cfg *Config, v any) (string, error) {
+	if strings.ToLower(cfg.Report) == "yaml" {
+		return toYAML(v)
+	}
+	var buf []byte
+	var err error
+	if cfg.JSONCompact {
+		buf, err = json.Marshal(v)
+	} else {
+		buf, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(buf) + "\n", nil
+}
+
+// ndjsonStarted holds the files appendNDJSON has written this run: the
+// first line truncates whatever an earlier run left there, later ones append
+var ndjsonStarted = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: map[string]bool{}}
+
+// appendNDJSON appends one compact JSON line for v, so several analyses
+// (e.g. a batch) stream into the same file as they complete. Each line goes
+// out in a single O_APPEND write, so readers see whole lines only; the
+// rename trick writeFile uses would drop earlier lines.
+func appendNDJSON(path string, v any) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := ensureParent(path); err != nil {
+		return err
+	}
+	ndjsonStarted.Lock()
+	defer ndjsonStarted.Unlock()
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if key := filepath.Clean(path); !ndjsonStarted.paths[key] {
+		flag |= os.O_TRUNC
+		ndjsonStarted.paths[key] = true
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(buf, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func renderTXT(cfg *Config, a *Analysis) string {
+	p := cfg.Precision
+	var b strings.Builder
+	fmt.Fprintf(&b, "File: %s\nWhen: %s\n", a.File, a.When)
+	if a.Probe.AudioStreams > 1 {
+		fmt.Fprintf(&b, "Stream: a:%d (of %d audio streams)\n", a.Probe.Stream, a.Probe.AudioStreams)
+	}
+	if r := a.Range; r != nil {
+		fmt.Fprintf(&b, "Range: %ss-%ss (times below are relative to its start)\n", p.sec(r.Start), p.sec(r.End))
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "Format: %s | Codec: %s | Duration: %ss | SR: %d Hz | Ch: %d | Bitrate: %d bps | BitDepth: %d\n",
+		a.Probe.FormatName, a.Probe.CodecName, p.sec(a.Probe.Duration), a.Probe.SampleRate, a.Probe.Channels, a.Probe.BitRate, a.Probe.BitDepth)
+	if v := a.Probe.Video; v != nil {
+		fmt.Fprintf(&b, "Video: %s %dx%d | Duration: %s\n", v.Codec, v.Width, v.Height, fmtOpt(v.Duration, secs(p)))
+	}
+	if len(a.Probe.Tracks) > 0 {
+		fmt.Fprintf(&b, "Audio tracks:\n")
+		for _, t := range a.Probe.Tracks {
+			mark := " "
+			if t.Stream == a.Probe.Stream {
+				mark = "*"
+			}
+			fmt.Fprintf(&b, "  %s a:%d %s | %s | %d ch %s | %s\n", mark, t.Stream, t.Codec, orNA(t.Language), t.Channels, orNA(t.Layout), fmtOpt(t.Duration, secs(p)))
+		}
+	}
+	if a.Decoded != nil {
+		fmt.Fprintf(&b, "Decoded: %ss", p.sec(*a.Decoded))
+		if a.DecodeErrors > 0 {
+			fmt.Fprintf(&b, " | decode errors %d", a.DecodeErrors)
+		}
+		if len(a.SampleRates) > 1 {
+			fmt.Fprintf(&b, " | sample rate changes %s Hz", joinInts(a.SampleRates, " → "))
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+	if a.Probe.EncoderDelay != nil || a.Probe.EncoderPadding != nil {
+		fmt.Fprintf(&b, "Gapless:")
+		if a.Probe.EncoderDelay != nil {
+			fmt.Fprintf(&b, " delay %d samples", *a.Probe.EncoderDelay)
+		}
+		if a.Probe.EncoderPadding != nil {
+			fmt.Fprintf(&b, " | padding %d samples", *a.Probe.EncoderPadding)
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+	if a.Probe.OpusOutputGainDB != nil {
+		fmt.Fprintf(&b, "Opus: output gain %s dB | mapping family %d\n", p.lu(*a.Probe.OpusOutputGainDB), *a.Probe.OpusMappingFamily)
+	}
+	if m := a.Metadata; m != nil {
+		if line := metadataLine(m); line != "" {
+			fmt.Fprintf(&b, "Tags: %s\n", line)
+		}
+		if rg := m.ReplayGain; rg != nil {
+			fmt.Fprintf(&b, "Tagged ReplayGain: %s\n", taggedReplayGain(p, rg))
+		}
+	}
+	if a.Silent {
+		fmt.Fprintf(&b, "Silent: peak <= %s dBFS, no further measurements\n", p.db(a.Level.PeakDB))
+		writeNotesTXT(&b, a.Notes)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Levels: Peak %s dBFS | RMS %s dBFS | Crest %s dB | Headroom %s dB",
+		p.db(a.Level.PeakDB), p.db(a.Level.RMSDB), p.db(a.Level.CrestDB), p.db(a.Level.HeadroomDB))
 	if a.Level.TruePeakDBTP != nil {
-		fmt.Fprintf(&b, " | TruePeak %.2f dBTP", *a.Level.TruePeakDBTP)
+		fmt.Fprintf(&b, " | TruePeak %s dBTP", p.db(*a.Level.TruePeakDBTP))
+		if e := a.Level.TruePeakEngine; e != "" && e != "ebur128" {
+			fmt.Fprintf(&b, " (%s)", e)
+		}
+	}
+	if a.Level.TrueCrestDB != nil {
+		fmt.Fprintf(&b, " | TrueCrest %s dB", p.db(*a.Level.TrueCrestDB))
+	}
+	if a.Level.PLR != nil {
+		fmt.Fprintf(&b, " | PLR %s dB", p.db(*a.Level.PLR))
+	}
+	if a.Level.PSR != nil {
+		fmt.Fprintf(&b, " | PSR %s dB", p.db(*a.Level.PSR))
+	}
+	if a.Level.DR != nil {
+		fmt.Fprintf(&b, " | DR%d", *a.Level.DR)
 	}
 	if a.Level.ClipSamples != nil && a.Level.ClipPercent != nil {
 		fmt.Fprintf(&b, " | Clips %d (%.3f%%)", *a.Level.ClipSamples, *a.Level.ClipPercent)
 	}
-	fmt.Fprintf(&b, " | DC %.4f | ZeroX %.2f | NoiseFloor %.2f dBFS\n",
-		a.Level.DCOffset, a.Level.ZeroXRate, a.Level.NoiseFloor)
+	if a.Level.SustainedPeakRatio != nil {
+		fmt.Fprintf(&b, " | Sustained %.1f%%", *a.Level.SustainedPeakRatio*100)
+	}
+	if a.Level.PinnedRatio != nil {
+		fmt.Fprintf(&b, " | Pinned %.3f%%", *a.Level.PinnedRatio*100)
+		if a.Level.Brickwalled {
+			fmt.Fprintf(&b, " (brickwalled)")
+		}
+	}
+	if a.Level.Clicks != nil {
+		fmt.Fprintf(&b, " | Clicks %d", *a.Level.Clicks)
+		if a.Level.ClicksPerMin != nil && a.Level.ClickGrade != nil {
+			fmt.Fprintf(&b, " (%.1f/min, %s)", *a.Level.ClicksPerMin, *a.Level.ClickGrade)
+		}
+	}
+	if a.Level.EffectiveBits != nil {
+		fmt.Fprintf(&b, " | EffBits %.0f", *a.Level.EffectiveBits)
+	}
+	fmt.Fprintf(&b, " | DC %.4f | ZeroX %.2f | NoiseFloor %s dBFS\n",
+		a.Level.DCOffset, a.Level.ZeroXRate, p.db(a.Level.NoiseFloor))
+	if len(a.Level.PerChannel) >= 2 {
+		fmt.Fprintf(&b, "Channels (peak/RMS dBFS, true peak dBTP, DC):")
+		for _, ch := range a.Level.PerChannel {
+			name := ch.Name
+			if name == "" {
+				name = strconv.Itoa(ch.Channel)
+			}
+			fmt.Fprintf(&b, " %s %s/%s/%s/%.4f", name, p.db(ch.PeakDB), p.db(ch.RMSDB), fmtOpt(ch.TruePeakDBTP, p.db), ch.DCOffset)
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+	if cfg.Explain {
+		writeExplainTXT(&b, p, a, "levels")
+	}
 	if a.Loudness != nil {
-		fmt.Fprintf(&b, "LUFS: Integrated %.2f LUFS | Range %.2f LU", a.Loudness.Integrated, a.Loudness.Range)
+		fmt.Fprintf(&b, "%s: Integrated %s %s | Range %s LU", loudnessUnit(a.Loudness), p.lufs(a.Loudness.Integrated), loudnessUnit(a.Loudness), p.lufs(a.Loudness.Range))
+		if a.Loudness.Relative != nil && a.Loudness.Target != nil {
+			fmt.Fprintf(&b, " | Rel %s LU (ref %.1f LUFS)", p.lu(*a.Loudness.Relative), *a.Loudness.Target)
+		}
+		if a.Loudness.Dialog != nil {
+			fmt.Fprintf(&b, " | Dialog %s %s", p.lufs(*a.Loudness.Dialog), loudnessUnit(a.Loudness))
+		}
 		if a.Loudness.TruePeak != nil {
-			fmt.Fprintf(&b, " | TruePeak %.2f dBTP", *a.Loudness.TruePeak)
+			fmt.Fprintf(&b, " | TruePeak %s dBTP", p.db(*a.Loudness.TruePeak))
+		}
+		if a.Loudness.SamplePeak != nil {
+			fmt.Fprintf(&b, " | SamplePeak %s dBFS", p.db(*a.Loudness.SamplePeak))
 		}
 		fmt.Fprintf(&b, "\n")
+		if a.Loudness.MaxMomentary != nil || a.Loudness.MaxShortTerm != nil {
+			fmt.Fprintf(&b, "Max Momentary %s | Max Short-term %s %s\n", fmtOpt(a.Loudness.MaxMomentary, p.lufs), fmtOpt(a.Loudness.MaxShortTerm, p.lufs), loudnessUnit(a.Loudness))
+		}
+		if rg := a.ReplayGain; rg != nil {
+			fmt.Fprintf(&b, "ReplayGain: Track %s dB (ref %.0f LUFS) → %s LUFS | TruePeak %s dBTP", p.lu(rg.TrackGainDB), rg.ReferenceLUFS, p.lufs(rg.ResultLUFS), p.db(rg.ResultTruePeakDBTP))
+			if rg.Clips {
+				fmt.Fprintf(&b, " [CLIPS]")
+			}
+			fmt.Fprintf(&b, "\n")
+		}
+		if pt := a.Target; pt != nil {
+			fmt.Fprintf(&b, "Target %s (%.0f LUFS, %.0f dBTP): %s → %s LUFS | TruePeak %s dBTP\n", pt.Name, pt.TargetLUFS, pt.CeilingDBTP, platformSummary(pt), p.lufs(pt.ResultLUFS), p.db(pt.ResultTruePeakDBTP))
+		}
+		if len(a.Sections) > 0 {
+			fmt.Fprintf(&b, "Sections:")
+			for i, sec := range a.Sections {
+				if i > 0 {
+					fmt.Fprintf(&b, " |")
+				}
+				fmt.Fprintf(&b, " %s %s-%ss %s", sec.Name, p.sec(sec.Start), p.sec(sec.End), fmtOpt(sec.Integrated, p.lufs))
+			}
+			fmt.Fprintf(&b, " %s\n", loudnessUnit(a.Loudness))
+		}
+		if len(a.Structure) > 0 {
+			fmt.Fprintf(&b, "Structure: %d sections\n", len(a.Structure))
+			for _, sec := range a.Structure {
+				fmt.Fprintf(&b, "  #%-2d %8s → %8ss : %s %s\n", sec.Index, p.sec(sec.Start), p.sec(sec.End), fmtOpt(sec.Loudness, p.lufs), loudnessUnit(a.Loudness))
+			}
+		}
 	}
-	fmt.Fprintf(&b, "Stereo: Mid RMS %.2f dB | Side RMS %.2f dB | Side/Mid %.2f dB",
-		a.Stereo.MidRMS, a.Stereo.SideRMS, a.Stereo.SideMidRatioDB)
-	if a.Stereo.Correlation != nil {
-		fmt.Fprintf(&b, " | Corr %.2f", *a.Stereo.Correlation)
+	if cfg.Explain {
+		writeExplainTXT(&b, p, a, "loudness")
+	}
+	if ss := a.Subset; ss != nil {
+		fmt.Fprintf(&b, "Channels %s: Peak %s dBFS | RMS %s dBFS | Integrated %s LUFS | TruePeak %s dBTP\n",
+			ss.Channels, p.db(ss.PeakDB), p.db(ss.RMSDB), fmtOpt(ss.Integrated, p.lufs), fmtOpt(ss.TruePeakDBTP, p.db))
+	}
+	if a.Mono {
+		fmt.Fprintf(&b, "Stereo: skipped (measured on mono sum)\n")
+	} else if a.Probe.Channels == 1 {
+		fmt.Fprintf(&b, "Stereo: skipped (mono source)\n")
+	} else if su := a.Surround; su != nil {
+		fmt.Fprintf(&b, "Surround: Layout %s | LFE %s dB | Center %s dB | Front/Rear %s dB", orNA(su.Layout), fmtOpt(su.LFEDB, p.db), fmtOpt(su.CenterDB, p.db), fmtOpt(su.FrontRearDB, p.db))
+		if dm := su.Downmix; dm != nil {
+			fmt.Fprintf(&b, " | Downmix Peak %s dBFS | TruePeak %s dBTP | %s LUFS (Δ %s LU)", p.db(dm.PeakDB), fmtOpt(dm.TruePeakDBTP, p.db), fmtOpt(dm.Integrated, p.lufs), fmtOpt(dm.DeltaLU, p.lu))
+		}
+		fmt.Fprintf(&b, "\n")
+	} else {
+		fmt.Fprintf(&b, "Stereo: Mid RMS %s dB | Side RMS %s dB | Side/Mid %s dB",
+			p.db(a.Stereo.MidRMS), p.db(a.Stereo.SideRMS), p.db(a.Stereo.SideMidRatioDB))
+		if a.Stereo.Correlation != nil {
+			fmt.Fprintf(&b, " | Corr %s", p.corr(*a.Stereo.Correlation))
+		}
+		if a.Stereo.MonoPeakDB != nil {
+			fmt.Fprintf(&b, " | MonoSumPeak %s dBFS", p.db(*a.Stereo.MonoPeakDB))
+		}
+		if a.Stereo.BalanceDB != nil {
+			fmt.Fprintf(&b, " | Balance L-R %s dB", p.db(*a.Stereo.BalanceDB))
+		}
+		if a.Stereo.Fault != "" {
+			fmt.Fprintf(&b, " | FAULT %s", a.Stereo.Fault)
+		}
+		fmt.Fprintf(&b, "\n")
+		if a.Stereo.CorrMin != nil {
+			fmt.Fprintf(&b, "Correlation over time (%ss windows): min %s | avg %s", p.sec(cfg.CorrWin), p.corr(*a.Stereo.CorrMin), fmtOpt(a.Stereo.CorrAvg, p.corr))
+			if len(a.Stereo.LowCorr) > 0 {
+				fmt.Fprintf(&b, " | below %s:", p.corr(cfg.CorrThresh))
+				for _, s := range a.Stereo.LowCorr {
+					fmt.Fprintf(&b, " %ss-%ss (min %s)", p.sec(s.Start), p.sec(s.End), p.corr(s.Min))
+				}
+			}
+			fmt.Fprintf(&b, "\n")
+		}
+		if cfg.Explain {
+			writeExplainTXT(&b, p, a, "stereo")
+		}
+		if a.PhaseScope != nil {
+			fmt.Fprintf(&b, "Phase scope: width %s %% | %dx%d L/R histogram (JSON)\n",
+				fmtOpt(a.PhaseScope.WidthPct, p.shape), a.PhaseScope.Bins, a.PhaseScope.Bins)
+		}
 	}
-	fmt.Fprintf(&b, "\n")
 	if a.Spectral.Centroid != nil || a.Spectral.Flatness != nil || a.Spectral.Rolloff95 != nil {
 		fmt.Fprintf(&b, "Spectral:")
 		if a.Spectral.Centroid != nil {
-			fmt.Fprintf(&b, " Centroid %.0f Hz", *a.Spectral.Centroid)
+			fmt.Fprintf(&b, " Centroid %s Hz", p.hz(*a.Spectral.Centroid))
 		}
 		if a.Spectral.Rolloff95 != nil {
-			fmt.Fprintf(&b, " | Rolloff95 %.0f Hz", *a.Spectral.Rolloff95)
+			fmt.Fprintf(&b, " | Rolloff95 %s Hz", p.hz(*a.Spectral.Rolloff95))
 		}
 		if a.Spectral.Flatness != nil {
-			fmt.Fprintf(&b, " | Flatness %.3f", *a.Spectral.Flatness)
+			fmt.Fprintf(&b, " | Flatness %s", p.shape(*a.Spectral.Flatness))
 		}
 		if a.Spectral.Spread != nil {
-			fmt.Fprintf(&b, " | Spread %.3f", *a.Spectral.Spread)
+			fmt.Fprintf(&b, " | Spread %s", p.shape(*a.Spectral.Spread))
 		}
 		if a.Spectral.Skewness != nil {
-			fmt.Fprintf(&b, " | Skew %.3f", *a.Spectral.Skewness)
+			fmt.Fprintf(&b, " | Skew %s", p.shape(*a.Spectral.Skewness))
 		}
 		if a.Spectral.Kurtosis != nil {
-			fmt.Fprintf(&b, " | Kurt %.3f", *a.Spectral.Kurtosis)
+			fmt.Fprintf(&b, " | Kurt %s", p.shape(*a.Spectral.Kurtosis))
+		}
+		if a.Spectral.TiltDB != nil {
+			fmt.Fprintf(&b, " | Tilt %s dB", p.lu(*a.Spectral.TiltDB))
+		}
+		if a.Spectral.CutoffHz != nil {
+			fmt.Fprintf(&b, " | Cutoff %s Hz", p.hz(*a.Spectral.CutoffHz))
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+	if r := a.Resolution; r != nil {
+		fmt.Fprintf(&b, "Resolution: claimed %d-bit/%s kHz | measured %s-bit, bandwidth %s Hz (~%s kHz source)\n",
+			r.ClaimedBits, khz(r.ClaimedRate), fmtOpt(r.MeasuredBits, bits), p.hz(r.BandwidthHz), khz(r.SourceRate))
+	}
+	if h := a.Hum; h != nil {
+		fmt.Fprintf(&b, "Hum: %g Hz at %s dBFS | lines", h.FundamentalHz, p.db(h.LevelDB))
+		for _, c := range h.Harmonics {
+			fmt.Fprintf(&b, " %g (%s dB, +%s)", c.Hz, p.db(c.LevelDB), p.db(c.ProminenceDB))
+		}
+		fmt.Fprintf(&b, "\n  notch: -af %s\n", h.Notch)
+	}
+	if cfg.Explain {
+		writeExplainTXT(&b, p, a, "spectral")
+	}
+	if c := a.Content; c != nil {
+		fmt.Fprintf(&b, "Content: %s (%.0f%% speech)", c.Label, c.SpeechPct)
+		if c.Label == "mixed" {
+			for _, s := range c.Spans {
+				fmt.Fprintf(&b, " | %ss-%ss %s", p.sec(s.Start), p.sec(s.End), s.Label)
+			}
 		}
 		fmt.Fprintf(&b, "\n")
 	}
 	if a.Tempo != nil {
 		fmt.Fprintf(&b, "Tempo: ")
 		if a.Tempo.BPMMedian != nil {
-			fmt.Fprintf(&b, "BPM med %.2f", *a.Tempo.BPMMedian)
+			fmt.Fprintf(&b, "BPM med %s", p.bpm(*a.Tempo.BPMMedian))
 		}
 		if a.Tempo.BPMMean != nil {
-			fmt.Fprintf(&b, " | mean %.2f", *a.Tempo.BPMMean)
+			fmt.Fprintf(&b, " | mean %s", p.bpm(*a.Tempo.BPMMean))
 		}
 		if a.Tempo.BPMStd != nil {
-			fmt.Fprintf(&b, " | std %.2f", *a.Tempo.BPMStd)
+			fmt.Fprintf(&b, " | std %s", p.bpm(*a.Tempo.BPMStd))
 		}
 		fmt.Fprintf(&b, " | events %d", a.Tempo.Events)
 		if a.Tempo.OnsetPerMin != nil {
-			fmt.Fprintf(&b, " | onsets/min %.2f", *a.Tempo.OnsetPerMin)
+			fmt.Fprintf(&b, " | onsets/min %s", p.bpm(*a.Tempo.OnsetPerMin))
 		}
 		fmt.Fprintf(&b, "\n")
 	}
+	if cfg.Explain {
+		writeExplainTXT(&b, p, a, "tempo")
+	}
 	if a.Pitch != nil && (a.Pitch.HzMedian != nil || a.Pitch.Note != nil) {
 		fmt.Fprintf(&b, "Pitch: ")
 		if a.Pitch.HzMedian != nil {
-			fmt.Fprintf(&b, "median %.2f Hz", *a.Pitch.HzMedian)
+			fmt.Fprintf(&b, "median %s Hz", p.pitch(*a.Pitch.HzMedian))
 		}
 		if a.Pitch.HzMean != nil {
-			fmt.Fprintf(&b, " | mean %.2f Hz", *a.Pitch.HzMean)
+			fmt.Fprintf(&b, " | mean %s Hz", p.pitch(*a.Pitch.HzMean))
 		}
 		if a.Pitch.HzMin != nil && a.Pitch.HzMax != nil {
-			fmt.Fprintf(&b, " | min/max %.2f/%.2f Hz", *a.Pitch.HzMin, *a.Pitch.HzMax)
+			fmt.Fprintf(&b, " | min/max %s/%s Hz", p.pitch(*a.Pitch.HzMin), p.pitch(*a.Pitch.HzMax))
 		}
 		if a.Pitch.MIDIMedian != nil {
 			fmt.Fprintf(&b, " | MIDI %.1f", *a.Pitch.MIDIMedian)
@@ -118,107 +444,376 @@ func renderTXT(a *Analysis) string {
 			fmt.Fprintf(&b, " %s", *a.Key.Scale)
 		}
 		if a.Key.Conf != nil {
-			fmt.Fprintf(&b, " (conf %.2f)", *a.Key.Conf)
+			fmt.Fprintf(&b, " (conf %s)", p.corr(*a.Key.Conf))
 		}
 		fmt.Fprintf(&b, "\n")
 	}
+	if fp := a.Fingerprint; fp != nil {
+		fmt.Fprintf(&b, "Fingerprint: %s (Chromaprint, %ss)\n", shortPrint(fp.Chromaprint), p.sec(fp.Duration))
+		if m := fp.AcoustID; m != nil {
+			fmt.Fprintf(&b, "AcoustID: %s – %s (score %s) | recording %s\n", orNA(m.Artist), orNA(m.Title), p.corr(m.Score), orNA(m.RecordingMBID))
+		}
+	}
 	if len(a.Bands) > 0 {
 		fmt.Fprintf(&b, "\nBand Loudness (dBFS):\n")
 		for _, bs := range a.Bands {
-			fmt.Fprintf(&b, "  %6.0f-%-6.0f Hz : peak %7.2f | rms %7.2f\n", bs.Band.Lo, bs.Band.Hi, bs.PeakDB, bs.RMSDB)
+			fmt.Fprintf(&b, "  %6.0f-%-6.0f Hz : peak %7s | rms %7s", bs.Band.Lo, bs.Band.Hi, p.db(bs.PeakDB), p.db(bs.RMSDB))
+			if bs.TruePeakDBTP != nil {
+				fmt.Fprintf(&b, " | tp %7s dBTP", p.db(*bs.TruePeakDBTP))
+			}
+			if bs.Correlation != nil {
+				fmt.Fprintf(&b, " | corr %s", p.corr(*bs.Correlation))
+			}
+			if bs.SideMidDB != nil {
+				fmt.Fprintf(&b, " | S/M %s dB", p.db(*bs.SideMidDB))
+			}
+			fmt.Fprintf(&b, "\n")
+		}
+		if a.MonoSafety != "" {
+			fmt.Fprintf(&b, "  Mono safety (<= %d Hz): %s\n", monoSafetyMaxHz, a.MonoSafety)
+		}
+	}
+	if len(a.Windows) > 0 {
+		lo, hi := a.Windows[0].RMSDB, a.Windows[0].RMSDB
+		for _, w := range a.Windows {
+			lo, hi = math.Min(lo, w.RMSDB), math.Max(hi, w.RMSDB)
+		}
+		fmt.Fprintf(&b, "\nEnvelope: %d windows of %ss | RMS %s → %s dBFS (per-window values in JSON)\n",
+			len(a.Windows), p.sec(cfg.AstatsWin), p.db(lo), p.db(hi))
+	}
+	if d := a.Defects; d != nil {
+		fmt.Fprintf(&b, "\nDefects: %d clicks | %d dropouts | %d glitches\n", d.Clicks, d.Dropouts, d.Glitches)
+		for _, e := range d.Events {
+			fmt.Fprintf(&b, "  %ss ch%d %-7s %-5s %s dBFS", p.sec(e.Time), e.Channel, e.Kind, e.Severity, p.db(e.LevelDB))
+			if e.Duration > 0 {
+				fmt.Fprintf(&b, " for %.1f ms", e.Duration*1000)
+			}
+			b.WriteString("\n")
+		}
+		if n := d.Clicks + d.Dropouts + d.Glitches; n > len(d.Events) {
+			fmt.Fprintf(&b, "  ... first %d of %d listed\n", len(d.Events), n)
+		}
+	}
+	if w := a.Waveform; w != nil {
+		fmt.Fprintf(&b, "\nWaveform (%d columns):", w.Width)
+		if w.PNG != "" {
+			fmt.Fprintf(&b, " PNG %s", w.PNG)
+		}
+		if w.Peaks != "" {
+			fmt.Fprintf(&b, " | peaks %s", w.Peaks)
 		}
+		b.WriteString("\n")
 	}
 	if len(a.Silence) > 0 {
-		fmt.Fprintf(&b, "\nSilence spans (threshold ~%.1f dBFS):\n", a.Level.NoiseFloor)
+		fmt.Fprintf(&b, "\nSilence spans (threshold ~%s dBFS):\n", p.db(a.Level.NoiseFloor))
 		for _, s := range a.Silence {
-			fmt.Fprintf(&b, "  %.3f → %.3f (%.3fs)\n", s.Start, s.End, s.End-s.Start)
+			fmt.Fprintf(&b, "  %s → %s (%ss)\n", p.sec(s.Start), p.sec(s.End), p.sec(s.End-s.Start))
 		}
 		if a.SilenceTotal != nil {
-			fmt.Fprintf(&b, "Total silence: %.3fs\n", *a.SilenceTotal)
+			fmt.Fprintf(&b, "Total silence: %ss\n", p.sec(*a.SilenceTotal))
 		}
 		if a.SilenceRatio != nil {
 			fmt.Fprintf(&b, "Silence ratio: %.2f%% of duration\n", *a.SilenceRatio*100)
 		}
 	}
-	if len(a.Notes) > 0 {
-		fmt.Fprintf(&b, "\nNotes:\n")
-		for _, n := range a.Notes {
-			fmt.Fprintf(&b, "  - %s\n", n)
+	writeNotesTXT(&b, a.Notes)
+	return b.String()
+}
+
+func loudnessUnit(l *LUFS) string {
+	if l.Standard == "atsc" {
+		return "LKFS"
+	}
+	return "LUFS"
+}
+
+func loudnessStdName(l *LUFS) string {
+	if l.Standard == "atsc" {
+		return "ATSC A/85"
+	}
+	return "EBU R128"
+}
+
+func writeNotesTXT(b *strings.Builder, notes []Note) {
+	if len(notes) > 0 {
+		fmt.Fprintf(b, "\nNotes:\n")
+		for _, n := range notes {
+			fmt.Fprintf(b, "  - %s\n", n.Message)
 		}
 	}
+}
+
+// footer documenting how the numbers were produced
+func renderFooter(cfg *Config, a *Analysis, path string) string {
+	onOff := func(v bool) string {
+		if v {
+			return "on"
+		}
+		return "off"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n--\n")
+	fmt.Fprintf(&b, "Input: %s\nReport: %s\nElapsed: %s\n", a.File, path, a.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "Engines: aubio %s | ebur128 %s | bands %s\n",
+		onOff(strings.ToLower(cfg.BPMEngine) == "aubio"), onOff(cfg.UseEBUR128), onOff(cfg.UseBands))
 	return b.String()
 }
 
-func renderMD(a *Analysis) string {
+func renderMD(cfg *Config, a *Analysis) string {
+	p := cfg.Precision
 	var b strings.Builder
 	fmt.Fprintf(&b, "# Analysis: %s\n\n", filepath.Base(a.File))
-	fmt.Fprintf(&b, "- When: `%s`\n- Format: `%s`\n- Duration: `%.3fs`\n- Sample Rate: `%d Hz`\n- Channels: `%d`\n- Bit Depth: `%d`\n\n",
-		a.When, a.Probe.FormatName, a.Probe.Duration, a.Probe.SampleRate, a.Probe.Channels, a.Probe.BitDepth)
+	fmt.Fprintf(&b, "- When: `%s`\n- Format: `%s`\n- Codec: `%s`\n- Duration: `%ss`\n- Sample Rate: `%d Hz`\n- Channels: `%d`\n- Bit Depth: `%d`\n",
+		a.When, a.Probe.FormatName, a.Probe.CodecName, p.sec(a.Probe.Duration), a.Probe.SampleRate, a.Probe.Channels, a.Probe.BitDepth)
+	if v := a.Probe.Video; v != nil {
+		fmt.Fprintf(&b, "- Video: `%s %dx%d, %s`\n", v.Codec, v.Width, v.Height, fmtOpt(v.Duration, secs(p)))
+	}
+	if a.Probe.AudioStreams > 1 {
+		fmt.Fprintf(&b, "- Stream: `a:%d` (of %d audio streams)\n", a.Probe.Stream, a.Probe.AudioStreams)
+	}
+	if r := a.Range; r != nil {
+		fmt.Fprintf(&b, "- Range: `%ss-%ss`\n", p.sec(r.Start), p.sec(r.End))
+	}
+	if a.Decoded != nil {
+		fmt.Fprintf(&b, "- Decoded: `%ss`\n", p.sec(*a.Decoded))
+	}
+	if a.DecodeErrors > 0 {
+		fmt.Fprintf(&b, "- Decode errors: `%d`\n", a.DecodeErrors)
+	}
+	if a.Probe.EncoderDelay != nil {
+		fmt.Fprintf(&b, "- Encoder delay: `%d samples`\n", *a.Probe.EncoderDelay)
+	}
+	if a.Probe.EncoderPadding != nil {
+		fmt.Fprintf(&b, "- Encoder padding: `%d samples`\n", *a.Probe.EncoderPadding)
+	}
+	if a.Probe.OpusOutputGainDB != nil {
+		fmt.Fprintf(&b, "- Opus output gain: `%s dB`\n- Opus mapping family: `%d`\n", p.lu(*a.Probe.OpusOutputGainDB), *a.Probe.OpusMappingFamily)
+	}
+	fmt.Fprintf(&b, "\n")
+	if m := a.Metadata; m != nil && (metadataLine(m) != "" || m.ReplayGain != nil) {
+		fmt.Fprintf(&b, "## Metadata\n")
+		for _, f := range [][2]string{{"Title", m.Title}, {"Artist", m.Artist}, {"Album", m.Album}, {"ISRC", m.ISRC}, {"Encoder", m.Encoder}} {
+			if f[1] != "" {
+				fmt.Fprintf(&b, "- %s: `%s`\n", f[0], f[1])
+			}
+		}
+		if rg := m.ReplayGain; rg != nil {
+			fmt.Fprintf(&b, "- Tagged ReplayGain: `%s`\n", taggedReplayGain(p, rg))
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+	if len(a.Probe.Tracks) > 0 {
+		fmt.Fprintf(&b, "| Track | Codec | Language | Channels | Layout | Duration (s) |\n|---|---|---|---:|---|---:|\n")
+		for _, t := range a.Probe.Tracks {
+			name := fmt.Sprintf("a:%d", t.Stream)
+			if t.Stream == a.Probe.Stream {
+				name = "**" + name + "** (analyzed)"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %d | %s | %s |\n", name, t.Codec, orNA(t.Language), t.Channels, orNA(t.Layout), fmtOpt(t.Duration, p.sec))
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+
+	if a.Silent {
+		fmt.Fprintf(&b, "**File is silent** (peak ≤ `%s dBFS`); no further measurements.\n\n", p.db(a.Level.PeakDB))
+		writeNotesMD(&b, a.Notes)
+		return b.String()
+	}
 
 	fmt.Fprintf(&b, "## Levels\n")
-	fmt.Fprintf(&b, "- Peak: `%.2f dBFS`\n- RMS: `%.2f dBFS`\n- Crest: `%.2f dB`\n- Headroom: `%.2f dB`\n",
-		a.Level.PeakDB, a.Level.RMSDB, a.Level.CrestDB, a.Level.HeadroomDB)
+	fmt.Fprintf(&b, "- Peak: `%s dBFS`\n- RMS: `%s dBFS`\n- Crest: `%s dB`\n- Headroom: `%s dB`\n",
+		p.db(a.Level.PeakDB), p.db(a.Level.RMSDB), p.db(a.Level.CrestDB), p.db(a.Level.HeadroomDB))
 	if a.Level.TruePeakDBTP != nil {
-		fmt.Fprintf(&b, "- True Peak: `%.2f dBTP`\n", *a.Level.TruePeakDBTP)
+		fmt.Fprintf(&b, "- True Peak: `%s dBTP`\n", p.db(*a.Level.TruePeakDBTP))
+	}
+	if a.Level.TrueCrestDB != nil {
+		fmt.Fprintf(&b, "- True Crest: `%s dB`\n", p.db(*a.Level.TrueCrestDB))
+	}
+	if a.Level.PLR != nil {
+		fmt.Fprintf(&b, "- PLR: `%s dB`\n", p.db(*a.Level.PLR))
+	}
+	if a.Level.PSR != nil {
+		fmt.Fprintf(&b, "- PSR: `%s dB`\n", p.db(*a.Level.PSR))
+	}
+	if a.Level.DR != nil {
+		fmt.Fprintf(&b, "- DR: `DR%d`\n", *a.Level.DR)
 	}
 	if a.Level.ClipSamples != nil && a.Level.ClipPercent != nil {
 		fmt.Fprintf(&b, "- Clipped samples: `%d (%.3f%%)`\n", *a.Level.ClipSamples, *a.Level.ClipPercent)
 	}
-	fmt.Fprintf(&b, "- DC Offset: `%.4f`\n- Zero-Crossing Rate: `%.2f`\n- Noise Floor: `%.2f dBFS`\n\n",
-		a.Level.DCOffset, a.Level.ZeroXRate, a.Level.NoiseFloor)
+	if a.Level.SustainedPeakRatio != nil {
+		fmt.Fprintf(&b, "- Sustained near-max (±1 dB short-term): `%.1f%%`\n", *a.Level.SustainedPeakRatio*100)
+	}
+	if a.Level.PinnedRatio != nil {
+		fmt.Fprintf(&b, "- Samples at ceiling: `%.3f%%`\n- Brickwalled: `%t`\n", *a.Level.PinnedRatio*100, a.Level.Brickwalled)
+	}
+	if a.Level.Clicks != nil {
+		fmt.Fprintf(&b, "- Clicks: `%d`\n", *a.Level.Clicks)
+		if a.Level.ClicksPerMin != nil && a.Level.ClickGrade != nil {
+			fmt.Fprintf(&b, "- Clicks/min: `%.1f` (%s)\n", *a.Level.ClicksPerMin, *a.Level.ClickGrade)
+		}
+	}
+	if a.Level.EffectiveBits != nil {
+		fmt.Fprintf(&b, "- Effective bits: `%.0f`\n", *a.Level.EffectiveBits)
+	}
+	fmt.Fprintf(&b, "- DC Offset: `%.4f`\n- Zero-Crossing Rate: `%.2f`\n- Noise Floor: `%s dBFS`\n\n",
+		a.Level.DCOffset, a.Level.ZeroXRate, p.db(a.Level.NoiseFloor))
+	if len(a.Level.PerChannel) >= 2 {
+		fmt.Fprintf(&b, "| Channel | Name | Peak (dBFS) | RMS (dBFS) | True Peak (dBTP) | DC |\n|---:|---|---:|---:|---:|---:|\n")
+		for _, ch := range a.Level.PerChannel {
+			fmt.Fprintf(&b, "| %d | %s | %s | %s | %s | %.4f |\n", ch.Channel, orNA(ch.Name), p.db(ch.PeakDB), p.db(ch.RMSDB), fmtOpt(ch.TruePeakDBTP, p.db), ch.DCOffset)
+		}
+		fmt.Fprintf(&b, "\n")
+	}
 
 	if a.Loudness != nil {
-		fmt.Fprintf(&b, "## Loudness (EBU R128)\n- Integrated: `%.2f LUFS`\n- Range: `%.2f LU`\n", a.Loudness.Integrated, a.Loudness.Range)
+		fmt.Fprintf(&b, "## Loudness (%s)\n- Integrated: `%s %s`\n- Range: `%s LU`\n", loudnessStdName(a.Loudness), p.lufs(a.Loudness.Integrated), loudnessUnit(a.Loudness), p.lufs(a.Loudness.Range))
+		if a.Loudness.Relative != nil && a.Loudness.Target != nil {
+			fmt.Fprintf(&b, "- Relative: `%s LU` (ref `%.1f LUFS`)\n", p.lu(*a.Loudness.Relative), *a.Loudness.Target)
+		}
+		if a.Loudness.Dialog != nil {
+			fmt.Fprintf(&b, "- Dialog (300-3400 Hz): `%s %s`\n", p.lufs(*a.Loudness.Dialog), loudnessUnit(a.Loudness))
+		}
 		if a.Loudness.TruePeak != nil {
-			fmt.Fprintf(&b, "- True Peak: `%.2f dBTP`\n", *a.Loudness.TruePeak)
+			fmt.Fprintf(&b, "- True Peak: `%s dBTP`\n", p.db(*a.Loudness.TruePeak))
+		}
+		if a.Loudness.SamplePeak != nil {
+			fmt.Fprintf(&b, "- Sample Peak: `%s dBFS`\n", p.db(*a.Loudness.SamplePeak))
+		}
+		if a.Loudness.MaxMomentary != nil || a.Loudness.MaxShortTerm != nil {
+			fmt.Fprintf(&b, "- Max Momentary / Short-term: `%s` / `%s %s`\n", fmtOpt(a.Loudness.MaxMomentary, p.lufs), fmtOpt(a.Loudness.MaxShortTerm, p.lufs), loudnessUnit(a.Loudness))
+		}
+		if rg := a.ReplayGain; rg != nil {
+			fmt.Fprintf(&b, "- ReplayGain: `%s dB` (ref `%.0f LUFS`) → `%s LUFS`, true peak `%s dBTP`", p.lu(rg.TrackGainDB), rg.ReferenceLUFS, p.lufs(rg.ResultLUFS), p.db(rg.ResultTruePeakDBTP))
+			if rg.Clips {
+				fmt.Fprintf(&b, " **clips**")
+			}
+			fmt.Fprintf(&b, "\n")
+		}
+		if pt := a.Target; pt != nil {
+			fmt.Fprintf(&b, "- Target %s (`%.0f LUFS`, `%.0f dBTP`): %s → `%s LUFS`, true peak `%s dBTP`\n", pt.Name, pt.TargetLUFS, pt.CeilingDBTP, platformSummary(pt), p.lufs(pt.ResultLUFS), p.db(pt.ResultTruePeakDBTP))
+		}
+		for _, sec := range a.Sections {
+			fmt.Fprintf(&b, "- %s (%s-%ss): `%s %s`\n", sec.Name, p.sec(sec.Start), p.sec(sec.End), fmtOpt(sec.Integrated, p.lufs), loudnessUnit(a.Loudness))
+		}
+		if len(a.Structure) > 0 {
+			fmt.Fprintf(&b, "- Structure: `%d sections`\n", len(a.Structure))
+			for _, sec := range a.Structure {
+				fmt.Fprintf(&b, "  - #%d `%s → %ss`: `%s %s`\n", sec.Index, p.sec(sec.Start), p.sec(sec.End), fmtOpt(sec.Loudness, p.lufs), loudnessUnit(a.Loudness))
+			}
 		}
 		fmt.Fprintf(&b, "\n")
 	}
 
-	fmt.Fprintf(&b, "## Stereo\n- Mid RMS: `%.2f dB`\n- Side RMS: `%.2f dB`\n- Side/Mid: `%.2f dB`\n",
-		a.Stereo.MidRMS, a.Stereo.SideRMS, a.Stereo.SideMidRatioDB)
-	if a.Stereo.Correlation != nil {
-		fmt.Fprintf(&b, "- Correlation: `%.2f`\n", *a.Stereo.Correlation)
+	if ss := a.Subset; ss != nil {
+		fmt.Fprintf(&b, "## Channels %s\n- Peak: `%s dBFS`\n- RMS: `%s dBFS`\n- Integrated: `%s LUFS`\n- True Peak: `%s dBTP`\n\n",
+			ss.Channels, p.db(ss.PeakDB), p.db(ss.RMSDB), fmtOpt(ss.Integrated, p.lufs), fmtOpt(ss.TruePeakDBTP, p.db))
+	}
+	if a.Mono {
+		fmt.Fprintf(&b, "## Stereo\n- skipped (measured on mono sum)\n\n")
+	} else if a.Probe.Channels == 1 {
+		fmt.Fprintf(&b, "## Stereo\n- skipped (mono source)\n\n")
+	} else if su := a.Surround; su != nil {
+		fmt.Fprintf(&b, "## Surround\n- Layout: `%s`\n- LFE (vs front pair): `%s dB`\n- Center (vs front pair): `%s dB`\n- Front/Rear: `%s dB`\n",
+			orNA(su.Layout), fmtOpt(su.LFEDB, p.db), fmtOpt(su.CenterDB, p.db), fmtOpt(su.FrontRearDB, p.db))
+		if dm := su.Downmix; dm != nil {
+			fmt.Fprintf(&b, "- Stereo downmix (Lo/Ro): peak `%s dBFS`, true peak `%s dBTP`, `%s LUFS` (Δ `%s LU`)\n",
+				p.db(dm.PeakDB), fmtOpt(dm.TruePeakDBTP, p.db), fmtOpt(dm.Integrated, p.lufs), fmtOpt(dm.DeltaLU, p.lu))
+		}
+		fmt.Fprintf(&b, "\n")
+	} else {
+		fmt.Fprintf(&b, "## Stereo\n- Mid RMS: `%s dB`\n- Side RMS: `%s dB`\n- Side/Mid: `%s dB`\n",
+			p.db(a.Stereo.MidRMS), p.db(a.Stereo.SideRMS), p.db(a.Stereo.SideMidRatioDB))
+		if a.Stereo.Correlation != nil {
+			fmt.Fprintf(&b, "- Correlation: `%s`\n", p.corr(*a.Stereo.Correlation))
+		}
+		if a.Stereo.MonoPeakDB != nil {
+			fmt.Fprintf(&b, "- Mono sum peak (L+R): `%s dBFS`\n", p.db(*a.Stereo.MonoPeakDB))
+		}
+		if a.Stereo.BalanceDB != nil {
+			fmt.Fprintf(&b, "- Balance (L-R RMS): `%s dB`\n", p.db(*a.Stereo.BalanceDB))
+		}
+		if a.Stereo.Fault != "" {
+			fmt.Fprintf(&b, "- Fault: `%s`\n", a.Stereo.Fault)
+		}
+		if a.Stereo.CorrMin != nil {
+			fmt.Fprintf(&b, "- Correlation over time (`%ss` windows): min `%s`, avg `%s`\n", p.sec(cfg.CorrWin), p.corr(*a.Stereo.CorrMin), fmtOpt(a.Stereo.CorrAvg, p.corr))
+			for _, s := range a.Stereo.LowCorr {
+				fmt.Fprintf(&b, "  - below `%s`: `%ss → %ss` (min `%s`)\n", p.corr(cfg.CorrThresh), p.sec(s.Start), p.sec(s.End), p.corr(s.Min))
+			}
+		}
+		if a.PhaseScope != nil {
+			fmt.Fprintf(&b, "- Width: `%s %%`\n", fmtOpt(a.PhaseScope.WidthPct, p.shape))
+		}
+		fmt.Fprintf(&b, "\n")
 	}
-	fmt.Fprintf(&b, "\n")
 
 	if a.Spectral.Centroid != nil || a.Spectral.Rolloff95 != nil || a.Spectral.Flatness != nil {
 		fmt.Fprintf(&b, "## Spectral\n")
 		if a.Spectral.Centroid != nil {
-			fmt.Fprintf(&b, "- Centroid: `%.0f Hz`\n", *a.Spectral.Centroid)
+			fmt.Fprintf(&b, "- Centroid: `%s Hz`\n", p.hz(*a.Spectral.Centroid))
 		}
 		if a.Spectral.Rolloff95 != nil {
-			fmt.Fprintf(&b, "- Rolloff (95%%): `%.0f Hz`\n", *a.Spectral.Rolloff95)
+			fmt.Fprintf(&b, "- Rolloff (95%%): `%s Hz`\n", p.hz(*a.Spectral.Rolloff95))
 		}
 		if a.Spectral.Flatness != nil {
-			fmt.Fprintf(&b, "- Flatness: `%.3f`\n", *a.Spectral.Flatness)
+			fmt.Fprintf(&b, "- Flatness: `%s`\n", p.shape(*a.Spectral.Flatness))
 		}
 		if a.Spectral.Spread != nil {
-			fmt.Fprintf(&b, "- Spread: `%.3f`\n", *a.Spectral.Spread)
+			fmt.Fprintf(&b, "- Spread: `%s`\n", p.shape(*a.Spectral.Spread))
 		}
 		if a.Spectral.Skewness != nil {
-			fmt.Fprintf(&b, "- Skewness: `%.3f`\n", *a.Spectral.Skewness)
+			fmt.Fprintf(&b, "- Skewness: `%s`\n", p.shape(*a.Spectral.Skewness))
 		}
 		if a.Spectral.Kurtosis != nil {
-			fmt.Fprintf(&b, "- Kurtosis: `%.3f`\n", *a.Spectral.Kurtosis)
+			fmt.Fprintf(&b, "- Kurtosis: `%s`\n", p.shape(*a.Spectral.Kurtosis))
+		}
+		if a.Spectral.TiltDB != nil {
+			fmt.Fprintf(&b, "- Tilt: `%s dB`\n", p.lu(*a.Spectral.TiltDB))
+		}
+		if a.Spectral.CutoffHz != nil {
+			fmt.Fprintf(&b, "- Cutoff: `%s Hz`\n", p.hz(*a.Spectral.CutoffHz))
 		}
 		fmt.Fprintf(&b, "\n")
 	}
+	if r := a.Resolution; r != nil {
+		fmt.Fprintf(&b, "## Resolution\n\n| | Claimed | Measured |\n|---|---:|---:|\n")
+		fmt.Fprintf(&b, "| Bit depth | %d | %s |\n", r.ClaimedBits, fmtOpt(r.MeasuredBits, bits))
+		fmt.Fprintf(&b, "| Sample rate | %s kHz | ~%s kHz (bandwidth %s Hz) |\n\n", khz(r.ClaimedRate), khz(r.SourceRate), p.hz(r.BandwidthHz))
+	}
+	if h := a.Hum; h != nil {
+		fmt.Fprintf(&b, "## Hum\n\n- Mains: `%g Hz`\n- Level: `%s dBFS`\n- Notch: `-af %s`\n\n", h.FundamentalHz, p.db(h.LevelDB), h.Notch)
+		fmt.Fprintf(&b, "| Line (Hz) | Level (dBFS) | Prominence (dB) |\n|---:|---:|---:|\n")
+		for _, c := range h.Harmonics {
+			fmt.Fprintf(&b, "| %g | %s | %s |\n", c.Hz, p.db(c.LevelDB), p.db(c.ProminenceDB))
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+	if c := a.Content; c != nil {
+		fmt.Fprintf(&b, "## Content\n\n- Label: `%s`\n- Speech: `%.0f %%`\n\n", c.Label, c.SpeechPct)
+		if c.Label == "mixed" {
+			fmt.Fprintf(&b, "| Start (s) | End (s) | Label |\n|---:|---:|---|\n")
+			for _, s := range c.Spans {
+				fmt.Fprintf(&b, "| %s | %s | %s |\n", p.sec(s.Start), p.sec(s.End), s.Label)
+			}
+			fmt.Fprintf(&b, "\n")
+		}
+	}
 
 	if a.Tempo != nil {
 		fmt.Fprintf(&b, "## Tempo\n")
 		if a.Tempo.BPMMedian != nil {
-			fmt.Fprintf(&b, "- BPM (median): `%.2f`\n", *a.Tempo.BPMMedian)
+			fmt.Fprintf(&b, "- BPM (median): `%s`\n", p.bpm(*a.Tempo.BPMMedian))
 		}
 		if a.Tempo.BPMMean != nil {
-			fmt.Fprintf(&b, "- BPM (mean): `%.2f`\n", *a.Tempo.BPMMean)
+			fmt.Fprintf(&b, "- BPM (mean): `%s`\n", p.bpm(*a.Tempo.BPMMean))
 		}
 		if a.Tempo.BPMStd != nil {
-			fmt.Fprintf(&b, "- BPM (stddev): `%.2f`\n", *a.Tempo.BPMStd)
+			fmt.Fprintf(&b, "- BPM (stddev): `%s`\n", p.bpm(*a.Tempo.BPMStd))
 		}
 		fmt.Fprintf(&b, "- Tempo events: `%d`\n", a.Tempo.Events)
 		if a.Tempo.OnsetPerMin != nil {
-			fmt.Fprintf(&b, "- Onsets/min: `%.2f`\n", *a.Tempo.OnsetPerMin)
+			fmt.Fprintf(&b, "- Onsets/min: `%s`\n", p.bpm(*a.Tempo.OnsetPerMin))
 		}
 		fmt.Fprintf(&b, "\n")
 	}
@@ -226,13 +821,13 @@ func renderMD(a *Analysis) string {
 	if a.Pitch != nil && (a.Pitch.HzMedian != nil || a.Pitch.Note != nil) {
 		fmt.Fprintf(&b, "## Pitch\n")
 		if a.Pitch.HzMedian != nil {
-			fmt.Fprintf(&b, "- Median: `%.2f Hz`\n", *a.Pitch.HzMedian)
+			fmt.Fprintf(&b, "- Median: `%s Hz`\n", p.pitch(*a.Pitch.HzMedian))
 		}
 		if a.Pitch.HzMean != nil {
-			fmt.Fprintf(&b, "- Mean: `%.2f Hz`\n", *a.Pitch.HzMean)
+			fmt.Fprintf(&b, "- Mean: `%s Hz`\n", p.pitch(*a.Pitch.HzMean))
 		}
 		if a.Pitch.HzMin != nil && a.Pitch.HzMax != nil {
-			fmt.Fprintf(&b, "- Min/Max: `%.2f / %.2f Hz`\n", *a.Pitch.HzMin, *a.Pitch.HzMax)
+			fmt.Fprintf(&b, "- Min/Max: `%s / %s Hz`\n", p.pitch(*a.Pitch.HzMin), p.pitch(*a.Pitch.HzMax))
 		}
 		if a.Pitch.MIDIMedian != nil {
 			fmt.Fprintf(&b, "- MIDI: `%.1f`\n", *a.Pitch.MIDIMedian)
@@ -252,26 +847,65 @@ func renderMD(a *Analysis) string {
 			fmt.Fprintf(&b, "- Scale: `%s`\n", *a.Key.Scale)
 		}
 		if a.Key.Conf != nil {
-			fmt.Fprintf(&b, "- Confidence: `%.2f`\n", *a.Key.Conf)
+			fmt.Fprintf(&b, "- Confidence: `%s`\n", p.corr(*a.Key.Conf))
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+
+	if fp := a.Fingerprint; fp != nil {
+		fmt.Fprintf(&b, "## Fingerprint\n- Chromaprint: `%s` (`%ss`; full value in JSON)\n", shortPrint(fp.Chromaprint), p.sec(fp.Duration))
+		if m := fp.AcoustID; m != nil {
+			fmt.Fprintf(&b, "- AcoustID: %s – %s (score `%s`)\n- Recording MBID: `%s`\n", orNA(m.Artist), orNA(m.Title), p.corr(m.Score), orNA(m.RecordingMBID))
 		}
 		fmt.Fprintf(&b, "\n")
 	}
 
 	if len(a.Bands) > 0 {
-		fmt.Fprintf(&b, "## Band Loudness\n\n| Band (Hz) | Peak (dBFS) | RMS (dBFS) |\n|---:|---:|---:|\n")
+		fmt.Fprintf(&b, "## Band Loudness\n\n| Band (Hz) | Peak (dBFS) | RMS (dBFS) | True Peak (dBTP) | Corr | Side/Mid (dB) |\n|---:|---:|---:|---:|---:|---:|\n")
 		for _, bs := range a.Bands {
-			fmt.Fprintf(&b, "| %.0f–%.0f | %.2f | %.2f |\n", bs.Band.Lo, bs.Band.Hi, bs.PeakDB, bs.RMSDB)
+			fmt.Fprintf(&b, "| %.0f–%.0f | %s | %s | %s | %s | %s |\n", bs.Band.Lo, bs.Band.Hi, p.db(bs.PeakDB), p.db(bs.RMSDB), fmtOpt(bs.TruePeakDBTP, p.db), fmtOpt(bs.Correlation, p.corr), fmtOpt(bs.SideMidDB, p.db))
+		}
+		if a.MonoSafety != "" {
+			fmt.Fprintf(&b, "\nMono safety (<= %d Hz): **%s**\n", monoSafetyMaxHz, a.MonoSafety)
 		}
 		fmt.Fprintf(&b, "\n")
 	}
 
+	if d := a.Defects; d != nil {
+		fmt.Fprintf(&b, "## Defects\n\n- Clicks: `%d`\n- Dropouts: `%d`\n- Glitches: `%d`\n", d.Clicks, d.Dropouts, d.Glitches)
+		if len(d.Events) > 0 {
+			fmt.Fprintf(&b, "\n| Time (s) | Ch | Kind | Severity | Level (dBFS) | Length (ms) |\n|---:|---:|---|---|---:|---:|\n")
+			for _, e := range d.Events {
+				length := ""
+				if e.Duration > 0 {
+					length = fmt.Sprintf("%.1f", e.Duration*1000)
+				}
+				fmt.Fprintf(&b, "| %s | %d | %s | %s | %s | %s |\n", p.sec(e.Time), e.Channel, e.Kind, e.Severity, p.db(e.LevelDB), length)
+			}
+		}
+		if n := d.Clicks + d.Dropouts + d.Glitches; n > len(d.Events) {
+			fmt.Fprintf(&b, "\nFirst %d of %d listed.\n", len(d.Events), n)
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+
+	if w := a.Waveform; w != nil {
+		fmt.Fprintf(&b, "## Waveform\n\n")
+		if w.PNG != "" {
+			fmt.Fprintf(&b, "![waveform](%s)\n\n", w.PNG)
+		}
+		if w.Peaks != "" {
+			fmt.Fprintf(&b, "- Peaks: `%s` (%d columns)\n\n", w.Peaks, w.Width)
+		}
+	}
+
 	if len(a.Silence) > 0 {
 		fmt.Fprintf(&b, "## Silence\n")
 		for _, s := range a.Silence {
-			fmt.Fprintf(&b, "- `%.3f → %.3f` (%.3fs)\n", s.Start, s.End, s.End-s.Start)
+			fmt.Fprintf(&b, "- `%s → %s` (%ss)\n", p.sec(s.Start), p.sec(s.End), p.sec(s.End-s.Start))
 		}
 		if a.SilenceTotal != nil {
-			fmt.Fprintf(&b, "- Total silence: `%.3fs`\n", *a.SilenceTotal)
+			fmt.Fprintf(&b, "- Total silence: `%ss`\n", p.sec(*a.SilenceTotal))
 		}
 		if a.SilenceRatio != nil {
 			fmt.Fprintf(&b, "- Silence ratio: `%.2f%%`\n", *a.SilenceRatio*100)
@@ -279,49 +913,256 @@ func renderMD(a *Analysis) string {
 		fmt.Fprintf(&b, "\n")
 	}
 
-	if len(a.Notes) > 0 {
-		fmt.Fprintf(&b, "## Notes\n")
-		for _, n := range a.Notes {
-			fmt.Fprintf(&b, "- %s\n", n)
+	writeNotesMD(&b, a.Notes)
+	return b.String()
+}
+
+func writeNotesMD(b *strings.Builder, notes []Note) {
+	if len(notes) > 0 {
+		fmt.Fprintf(b, "## Notes\n")
+		for _, n := range notes {
+			fmt.Fprintf(b, "- %s\n", n.Message)
+		}
+		fmt.Fprintf(b, "\n")
+	}
+}
+
+var csvHeader = append([]string{"file"}, metricNames()...)
+
+// CSV decimals for the columns that don't take the default 2
+var csvDigits = map[string]int{MetricDuration: 3, MetricSampleRate: 0, MetricChannels: 0}
+
+// csvRow follows csvHeader: the file, then every registered metric
+func csvRow(a *Analysis) []string {
+	row := []string{streamName(a)}
+	for _, m := range metricRegistry {
+		var cell string
+		if m.Value == nil {
+			if t := metricText[m.Name]; t != nil {
+				cell = t(a)
+			}
+		} else if v, ok := m.Value(a); ok {
+			d, set := csvDigits[m.Name]
+			if !set {
+				d = 2
+			}
+			cell = strconv.FormatFloat(v, 'f', d, 64)
 		}
-		fmt.Fprintf(&b, "\n")
+		row = append(row, cell)
 	}
+	return row
+}
+
+func renderCSV(as ...*Analysis) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(csvHeader)
+	for _, a := range as {
+		w.Write(csvRow(a))
+	}
+	w.Flush()
 	return b.String()
 }
 
-func renderDiff(cfg *Config, d *Diff) string {
+func renderDiff(cfg *Config, d *Diff) (string, error) {
 	switch strings.ToLower(cfg.Report) {
-	case "json":
-		buf, _ := json.MarshalIndent(d, "", "  ")
-		return string(buf) + "\n"
+	case "json", "yaml":
+		return renderJSON(cfg, d)
 	case "md":
 		var b strings.Builder
 		fmt.Fprintf(&b, "# Compare: %s ↔ %s\n\n", filepath.Base(d.A.File), filepath.Base(d.B.File))
 		fmt.Fprintf(&b, "| Metric | %s | %s | Δ (B-A) |\n|---|---:|---:|---:|\n", filepath.Base(d.A.File), filepath.Base(d.B.File))
-		row := func(name string, av, bv, dv float64, fmtStr string) {
-			fmt.Fprintf(&b, "| %s | "+fmtStr+" | "+fmtStr+" | "+fmtStr+" |\n", name, av, bv, dv)
+		p := cfg.Precision
+		row := func(name string, av, bv, dv float64, f func(float64) string) {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", name, f(av), f(bv), f(dv))
 		}
-		row("Peak dBFS", d.A.Level.PeakDB, d.B.Level.PeakDB, d.Delta["peak_db"], "%.2f")
-		row("RMS dBFS", d.A.Level.RMSDB, d.B.Level.RMSDB, d.Delta["rms_db"], "%.2f")
-		row("Crest dB", d.A.Level.CrestDB, d.B.Level.CrestDB, d.Delta["crest_db"], "%.2f")
+		row("Peak dBFS", d.A.Level.PeakDB, d.B.Level.PeakDB, d.Delta[MetricPeak], p.db)
+		row("RMS dBFS", d.A.Level.RMSDB, d.B.Level.RMSDB, d.Delta[MetricRMS], p.db)
+		row("Crest dB", d.A.Level.CrestDB, d.B.Level.CrestDB, d.Delta[MetricCrest], p.db)
 		if d.A.Loudness != nil && d.B.Loudness != nil {
-			row("LUFS (integr.)", d.A.Loudness.Integrated, d.B.Loudness.Integrated, d.Delta["lufs_integrated"], "%.2f")
-			row("LUFS Range", d.A.Loudness.Range, d.B.Loudness.Range, d.Delta["lufs_range"], "%.2f")
+			row("LUFS (integr.)", d.A.Loudness.Integrated, d.B.Loudness.Integrated, d.Delta[MetricLUFS], p.lufs)
+			row("LUFS Range", d.A.Loudness.Range, d.B.Loudness.Range, d.Delta[MetricLRA], p.lufs)
 		}
-		row("Side/Mid dB", d.A.Stereo.SideMidRatioDB, d.B.Stereo.SideMidRatioDB, d.Delta["stereo_side_mid_db"], "%.2f")
+		row("Side/Mid dB", d.A.Stereo.SideMidRatioDB, d.B.Stereo.SideMidRatioDB, d.Delta[MetricSideMid], p.db)
 		if d.A.Tempo != nil && d.B.Tempo != nil && d.A.Tempo.BPMMedian != nil && d.B.Tempo.BPMMedian != nil {
-			row("BPM (median)", *d.A.Tempo.BPMMedian, *d.B.Tempo.BPMMedian, d.Delta["bpm_median"], "%.2f")
+			row("BPM (median)", *d.A.Tempo.BPMMedian, *d.B.Tempo.BPMMedian, d.Delta[MetricBPM], p.bpm)
 		}
-		row("Duration (s)", d.A.Probe.Duration, d.B.Probe.Duration, d.Delta["duration_s"], "%.3f")
-		return b.String()
+		row("Duration (s)", d.A.Probe.Duration, d.B.Probe.Duration, d.Delta[MetricDuration], p.sec)
+		return b.String(), nil
 	default:
 		var b strings.Builder
 		fmt.Fprintf(&b, "COMPARE: %s vs %s\n\n", d.A.File, d.B.File)
-		for _, k := range []string{"peak_db", "rms_db", "crest_db", "lufs_integrated", "lufs_range", "stereo_side_mid_db", "bpm_median", "duration_s"} {
+		for _, k := range diffKeys {
 			if v, ok := d.Delta[k]; ok && !math.IsNaN(v) && !math.IsInf(v, 0) {
 				fmt.Fprintf(&b, "%-20s : %+8.3f\n", k, v)
 			}
 		}
-		return b.String()
+		return b.String(), nil
+	}
+}
+
+func renderStability(cfg *Config, s *Stability) (string, error) {
+	return renderSpread(cfg, s, "Stability", "captures")
+}
+
+// renderSpread is the multi-file overview: one row per file, then the
+// spread of each metric across them and any files that failed. csv and
+// ndjson carry the per-file rows and failures only.
+func renderSpread(cfg *Config, s *Stability, title, noun string) (string, error) {
+	p := cfg.Precision
+	var b strings.Builder
+	switch strings.ToLower(cfg.Report) {
+	case "json", "yaml":
+		return renderJSON(cfg, s)
+	case "ndjson":
+		// one compact line per file, failures included
+		lines := make([]any, 0, len(s.Rows)+len(s.Failed))
+		for _, r := range s.Rows {
+			lines = append(lines, r)
+		}
+		for _, f := range s.Failed {
+			lines = append(lines, f)
+		}
+		for _, l := range lines {
+			buf, err := json.Marshal(l)
+			if err != nil {
+				return "", err
+			}
+			b.Write(append(buf, '\n'))
+		}
+		return b.String(), nil
+	case "csv":
+		w := csv.NewWriter(&b)
+		w.Write([]string{"file", "duration_s", "peak_dbfs", "lufs", "bpm", "error"})
+		opt := func(v *float64) string {
+			if v == nil {
+				return ""
+			}
+			return strconv.FormatFloat(*v, 'f', 2, 64)
+		}
+		for _, r := range s.Rows {
+			w.Write([]string{r.File, strconv.FormatFloat(r.Duration, 'f', 3, 64),
+				strconv.FormatFloat(r.PeakDB, 'f', 2, 64), opt(r.LUFS), opt(r.BPM), ""})
+		}
+		for _, f := range s.Failed {
+			w.Write([]string{f.File, "", "", "", "", f.Error})
+		}
+		w.Flush()
+		return b.String(), w.Error()
+	case "md":
+		fmt.Fprintf(&b, "# %s: %d %s", title, len(s.Files), noun)
+		if len(s.Failed) > 0 {
+			fmt.Fprintf(&b, ", %d failed", len(s.Failed))
+		}
+		fmt.Fprintf(&b, "\n\n| File | Duration (s) | Peak (dBFS) | LUFS | BPM |\n|---|---:|---:|---:|---:|\n")
+		for _, r := range s.Rows {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", filepath.Base(r.File), p.sec(r.Duration), p.db(r.PeakDB), fmtOpt(r.LUFS, p.lufs), fmtOpt(r.BPM, p.bpm))
+		}
+		fmt.Fprintf(&b, "\n| Metric | Mean | Std | Min | Max | Range |\n|---|---:|---:|---:|---:|---:|\n")
+		for _, m := range s.Metrics {
+			fmt.Fprintf(&b, "| %s | %.3f | %.3f | %.3f | %.3f | %.3f |\n", m.Name, m.Mean, m.Std, m.Min, m.Max, m.Range)
+		}
+		if len(s.Failed) > 0 {
+			fmt.Fprintf(&b, "\n## Failed\n\n| File | Error |\n|---|---|\n")
+			for _, f := range s.Failed {
+				fmt.Fprintf(&b, "| %s | %s |\n", f.File, strings.ReplaceAll(f.Error, "|", "\\|"))
+			}
+		}
+		return b.String(), nil
+	default:
+		fmt.Fprintf(&b, "%s: %d %s", strings.ToUpper(title), len(s.Files), noun)
+		if len(s.Failed) > 0 {
+			fmt.Fprintf(&b, ", %d failed", len(s.Failed))
+		}
+		b.WriteString("\n")
+		writeSummaryTXT(&b, p, s.Rows)
+		fmt.Fprintf(&b, "\n%-20s : %9s %8s %9s %9s %8s\n", "metric", "mean", "std", "min", "max", "range")
+		for _, m := range s.Metrics {
+			fmt.Fprintf(&b, "%-20s : %9.3f %8.3f %9.3f %9.3f %8.3f\n", m.Name, m.Mean, m.Std, m.Min, m.Max, m.Range)
+		}
+		if len(s.Failed) > 0 {
+			fmt.Fprintf(&b, "\nfailed:\n")
+			for _, f := range s.Failed {
+				fmt.Fprintf(&b, "  %-40s : %s\n", f.File, f.Error)
+			}
+		}
+		return b.String(), nil
+	}
+}
+
+func writeSummaryTXT(b *strings.Builder, p Precision, rows []SummaryRow) {
+	for _, r := range rows {
+		fmt.Fprintf(b, "  %-40s : %9ss | peak %7s dBFS | LUFS %7s | BPM %7s\n", r.File,
+			p.sec(r.Duration), p.db(r.PeakDB), fmtOpt(r.LUFS, p.lufs), fmtOpt(r.BPM, p.bpm))
+	}
+}
+
+func renderStems(cfg *Config, r *StemsReport) (string, error) {
+	p := cfg.Precision
+	var b strings.Builder
+	switch strings.ToLower(cfg.Report) {
+	case "json", "yaml":
+		return renderJSON(cfg, r)
+	case "md":
+		fmt.Fprintf(&b, "# Stems: %s\n\n", r.Dir)
+		fmt.Fprintf(&b, "| Stem | LUFS | RMS (dBFS) | Energy share | Rel. to sum (LU) |\n|---|---:|---:|---:|---:|\n")
+		for _, s := range r.Stems {
+			fmt.Fprintf(&b, "| %s | %s | %s | %.1f%% | %s |\n", filepath.Base(s.File),
+				fmtOpt(s.Integrated, p.lufs), p.db(s.RMSDB), s.EnergyShare*100, fmtOpt(s.RelativeLU, p.lu))
+		}
+		fmt.Fprintf(&b, "\n- Sum: `%s LUFS`\n", fmtOpt(r.SumLUFS, p.lufs))
+		if r.Mix != "" {
+			fmt.Fprintf(&b, "- Mix (%s): `%s LUFS`\n", filepath.Base(r.Mix), fmtOpt(r.MixLUFS, p.lufs))
+			fmt.Fprintf(&b, "- Deviation (sum - mix): `%s LU`\n", fmtOpt(r.DeviationLU, p.lu))
+			fmt.Fprintf(&b, "- Residual RMS: `%s dBFS` (`%s dB` rel. mix)\n", fmtOpt(r.ResidualRMSDB, p.db), fmtOpt(r.ResidualRelDB, p.db))
+		}
+		if len(r.Notes) > 0 {
+			fmt.Fprintf(&b, "\n## Notes\n")
+			for _, n := range r.Notes {
+				fmt.Fprintf(&b, "- %s\n", n.Message)
+			}
+		}
+		return b.String(), nil
+	default:
+		fmt.Fprintf(&b, "STEMS: %s\nWhen: %s\n\n", r.Dir, r.When)
+		for _, s := range r.Stems {
+			fmt.Fprintf(&b, "  %-30s : LUFS %8s | RMS %7s dBFS | share %5.1f%% | rel %7s LU\n", filepath.Base(s.File),
+				fmtOpt(s.Integrated, p.lufs), p.db(s.RMSDB), s.EnergyShare*100, fmtOpt(s.RelativeLU, p.lu))
+		}
+		fmt.Fprintf(&b, "\nSum: %s LUFS\n", fmtOpt(r.SumLUFS, p.lufs))
+		if r.Mix != "" {
+			fmt.Fprintf(&b, "Mix: %s LUFS (%s)\n", fmtOpt(r.MixLUFS, p.lufs), r.Mix)
+			fmt.Fprintf(&b, "Deviation (sum - mix): %s LU\n", fmtOpt(r.DeviationLU, p.lu))
+			fmt.Fprintf(&b, "Residual: %s dBFS (%s dB rel. mix)\n", fmtOpt(r.ResidualRMSDB, p.db), fmtOpt(r.ResidualRelDB, p.db))
+		}
+		if len(r.Notes) > 0 {
+			fmt.Fprintf(&b, "\nNotes:\n")
+			for _, n := range r.Notes {
+				fmt.Fprintf(&b, "  - %s\n", n.Message)
+			}
+		}
+		return b.String(), nil
+	}
+}
+
+func fmtOpt(v *float64, f func(float64) string) string {
+	if v == nil {
+		return "n/a"
+	}
+	return f(*v)
+}
+
+// bits formats a bit count, for fmtOpt
+func bits(v float64) string { return fmt.Sprintf("%.0f", v) }
+
+// secs formats a duration with its unit, for fmtOpt
+func secs(p Precision) func(float64) string {
+	return func(v float64) string { return p.sec(v) + "s" }
+}
+
+func orNA(s string) string {
+	if s == "" {
+		return "n/a"
 	}
+	return s
 }
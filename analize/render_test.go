@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// every registered metric has its column, in header order
+func TestCSVRowFollowsRegistry(t *testing.T) {
+	key, scale, dr := "A", "minor", 11
+	a := &Analysis{File: "x.wav", Probe: ProbeInfo{Duration: 12.3456, SampleRate: 44100, Channels: 2},
+		Level: LevelStats{PeakDB: -1, DR: &dr}, Key: &KeyInfo{Key: &key, Scale: &scale}, Content: &Content{Label: "music"}}
+	row := csvRow(a)
+	if len(row) != len(csvHeader) {
+		t.Fatalf("row has %d cells, header %d", len(row), len(csvHeader))
+	}
+	want := map[string]string{"file": "x.wav", MetricDuration: "12.346", MetricSampleRate: "44100", MetricPeak: "-1.00",
+		MetricLUFS: "", MetricKey: "A minor", MetricContent: "music", MetricSpeechPct: "0.00", MetricDR: "11.00"}
+	for i, name := range csvHeader {
+		if w, ok := want[name]; ok && row[i] != w {
+			t.Errorf("%s: got %q, want %q", name, row[i], w)
+		}
+	}
+}
+
+// failed batch files get their own rows, with the error in the last column
+func TestSpreadCSVListsFailures(t *testing.T) {
+	lufs := -14.0
+	s := &Stability{Files: []string{"a.wav"}, Rows: []SummaryRow{{File: "a.wav", Duration: 1, PeakDB: -1, LUFS: &lufs}},
+		Failed: []FileError{{File: "b.wav", Error: "ffprobe: no audio stream"}}}
+	out, err := renderSpread(&Config{Report: "csv"}, s, "Batch", "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "file,duration_s,peak_dbfs,lufs,bpm,error\na.wav,1.000,-1.00,-14.00,,\nb.wav,,,,,ffprobe: no audio stream\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+// a run's first line replaces an earlier run's file, later lines append
+func TestAppendNDJSONTruncatesOncePerRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.ndjson")
+	if err := os.WriteFile(path, []byte("{\"File\":\"stale\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"a.wav", "b.wav"} {
+		if err := appendNDJSON(path, FileError{File: f}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf, _ := os.ReadFile(path)
+	want := "{\"File\":\"a.wav\",\"Error\":\"\"}\n{\"File\":\"b.wav\",\"Error\":\"\"}\n"
+	if string(buf) != want {
+		t.Errorf("got %q, want %q", buf, want)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReplayGain 2.0 reference loudness
+const replayGainRefLUFS = -18.0
+
+// replayGain computes the track gain and previews its result: loudness
+// lands on the reference by construction, but true peak moves by the same
+// gain, which is how a quiet-but-peaky track gets boosted into clipping
+func replayGain(lufs *LUFS, lv LevelStats) *ReplayGain {
+	if lufs == nil {
+		return nil
+	}
+	rg := &ReplayGain{
+		ReferenceLUFS: replayGainRefLUFS,
+		TrackGainDB:   replayGainRefLUFS - lufs.Integrated,
+		ResultLUFS:    replayGainRefLUFS,
+	}
+	peak := lv.PeakDB // sample peak when true peak is off
+	if lv.TruePeakDBTP != nil {
+		peak = *lv.TruePeakDBTP
+	}
+	tp := peak + rg.TrackGainDB
+	rg.ResultTruePeakDBTP = tp
+	rg.Clips = tp > 0
+	return rg
+}
+
+func replayGainNotes(rg *ReplayGain) []Note {
+	if rg == nil || !rg.Clips {
+		return nil
+	}
+	return []Note{newNote(SevWarn, "REPLAYGAIN_CLIPS", "ReplayGain %+.2f dB would put true peak at %+.2f dBTP; players without peak protection will clip (max clean gain %+.2f dB).",
+		rg.TrackGainDB, rg.ResultTruePeakDBTP, rg.TrackGainDB-rg.ResultTruePeakDBTP)}
+}
+
+// formats whose tags ffmpeg can rewrite with a stream copy; Ogg keeps them
+// on the stream, the others in the file header
+var rgTaggable = map[string]bool{"mp3": true, "flac": true, "opus": true, "vorbis": true}
+
+// replayGainTrack measures in for its track tags: integrated loudness and
+// true peak from one ebur128 pass over the whole file, as loudgain does
+func replayGainTrack(cfg *Config, in string) (RGTrack, ProbeInfo, error) {
+	t := RGTrack{File: in}
+	probe, err := ffprobeInfo(cfg, in)
+	if err != nil {
+		return t, probe, err
+	}
+	lufs, err := ffmpegEBUR128(cfg, in, probe.Channels)
+	if err != nil {
+		return t, probe, fmt.Errorf("ebur128: %w", err)
+	}
+	if lufs.TruePeak == nil {
+		return t, probe, fmt.Errorf("ebur128 reported no true peak")
+	}
+	t.Integrated = lufs.Integrated
+	t.TrackGainDB = replayGain(&lufs, LevelStats{TruePeakDBTP: lufs.TruePeak}).TrackGainDB
+	t.TrackPeak = math.Pow(10, *lufs.TruePeak/20)
+	return t, probe, nil
+}
+
+// r128Gain is an Opus R128_TRACK_GAIN: Q7.8 dB to -23 LUFS (RFC 7845),
+// on top of the header output gain the measurement already includes
+func r128Gain(integrated float64) int {
+	return int(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round((-23-integrated)*256))))
+}
+
+// writeReplayGainTags stream-copies in with the track tags set and renames
+// the copy over the original, so a failed write leaves the file untouched
+func writeReplayGainTags(cfg *Config, in string, probe ProbeInfo, t RGTrack) error {
+	if !rgTaggable[probe.CodecName] {
+		return fmt.Errorf("can't tag %s (mp3, flac, opus, vorbis only)", probe.CodecName)
+	}
+	meta := []string{"REPLAYGAIN_TRACK_GAIN=" + fmt.Sprintf("%.2f dB", t.TrackGainDB), "REPLAYGAIN_TRACK_PEAK=" + fmt.Sprintf("%.6f", t.TrackPeak)}
+	if probe.CodecName == "opus" {
+		meta = []string{"R128_TRACK_GAIN=" + strconv.Itoa(r128Gain(t.Integrated))}
+	}
+	where := "-metadata"
+	if strings.HasPrefix(probe.FormatName, "ogg") {
+		where = "-metadata:s:a:0"
+	}
+	tmp := filepath.Join(filepath.Dir(in), ".rg-"+filepath.Base(in))
+	args := []string{"-y", "-hide_banner", "-nostats", "-loglevel", "error", "-i", in, "-map", "0", "-c", "copy"}
+	for _, m := range meta {
+		args = append(args, where, m)
+	}
+	if out, err := runCmd(cfg.FFmpegBin, append(args, tmp)...); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("ffmpeg tag: %w\n%s", err, out)
+	}
+	return os.Rename(tmp, in)
+}
+
+// replayGainFiles computes (and with write, tags) every file, jobs at a
+// time. A failing file fills its Error column instead of stopping the run.
+func replayGainFiles(cfg *Config, ins []string, jobs int, write bool) ([]RGTrack, error) {
+	rc := *cfg
+	rc.EBUPeak, rc.Mono, rc.Start, rc.End, rc.Stream = "true", false, 0, 0, 0
+	rows := make([]RGTrack, len(ins))
+	done, err := runBatch(len(ins), jobs, func(i int) error {
+		t, probe, err := replayGainTrack(&rc, ins[i])
+		if err == nil && write {
+			if err = writeReplayGainTags(&rc, ins[i], probe, t); err == nil {
+				t.Written = true
+			}
+		}
+		if err != nil {
+			t.Error = err.Error()
+		}
+		rows[i] = t
+		return nil
+	})
+	var out []RGTrack
+	for i, r := range rows {
+		if done[i] {
+			out = append(out, r)
+		}
+	}
+	return out, err
+}
+
+func renderReplayGain(cfg *Config, rows []RGTrack) (string, error) {
+	p := cfg.Precision
+	var b strings.Builder
+	switch strings.ToLower(cfg.Report) {
+	case "json", "yaml":
+		return renderJSON(cfg, rows)
+	case "csv":
+		w := csv.NewWriter(&b)
+		w.Write([]string{"file", "lufs_integrated", "track_gain_db", "track_peak", "written", "error"})
+		for _, t := range rows {
+			w.Write([]string{t.File, ff(t.Integrated, 2), ff(t.TrackGainDB, 2), ff(t.TrackPeak, 6), strconv.FormatBool(t.Written), t.Error})
+		}
+		w.Flush()
+		return b.String(), nil
+	}
+	fmt.Fprintf(&b, "ReplayGain 2.0 (ref %.0f LUFS)\n\n", replayGainRefLUFS)
+	for _, t := range rows {
+		if t.Error != "" {
+			fmt.Fprintf(&b, "  %-30s : error: %s\n", filepath.Base(t.File), t.Error)
+			continue
+		}
+		tagged := ""
+		if t.Written {
+			tagged = " | tagged"
+		}
+		fmt.Fprintf(&b, "  %-30s : LUFS %8s | gain %7s dB | peak %.6f%s\n", filepath.Base(t.File), p.lufs(t.Integrated), p.lu(t.TrackGainDB), t.TrackPeak, tagged)
+	}
+	return b.String(), nil
+}
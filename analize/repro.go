@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// measurementChains records the exact ffmpeg filter graph (and aubio command
+// line) behind every measurement of a run, keyed by stage, so a result can be
+// reproduced by hand. Every stage is deterministic for a given ffmpeg/aubio
+// build and input: none of the filters used take a random seed, and running
+// the aubio passes concurrently does not change their output.
+func measurementChains(cfg *Config, in string, probe ProbeInfo, silent bool) map[string]string {
+	// volumedetect, astats, ebur128 and silencedetect share one decode
+	m := map[string]string{"single_pass": singlePassChain(cfg, probe.Channels)}
+	if hasRange(cfg) {
+		// every ffmpeg pass reads the input with these seek options
+		m["input"] = strings.Join(rangeArgs(cfg, []string{"-i", in}), " ")
+	}
+	if silent {
+		return m
+	}
+	if cfg.AstatsWin > 0 {
+		m["astats_windowed"] = windowedAstatsChain(cfg, probe.SampleRate, cfg.AstatsWin)
+	}
+	m["channel_true_peak"] = truePeakChain(cfg, probe.SampleRate)
+	m["dr"] = windowedAstatsChain(cfg, probe.SampleRate, drWindowSec)
+	m["spectral"] = spectralChain(cfg)
+	m["hum"] = strings.Join(spectrumArgs(cfg, in, humRate), " ") + fmt.Sprintf(" (%d-point Hann FFT, averaged)", humFFT)
+	m["content"] = strings.Join(spectrumArgs(cfg, in, classifyRate), " ") + fmt.Sprintf(" (%gs segments of %gms frames, LSTER/HZCRR speech/music rule)", classifySegSec, classifyFrameSec*1000)
+	m["spectrum"] = strings.Join(spectrumArgs(cfg, in, 0), " ") + fmt.Sprintf(" (%d-point Hann FFT, averaged)", spectrumFFT)
+	if cfg.TiltFreq > 0 {
+		tc, bands := tiltChain(cfg, cfg.TiltFreq)
+		m["tilt"] = bandsChain(tc, bands, 1)
+	}
+	if cfg.Defects || cfg.UseClicks {
+		m["defects"] = strings.Join(rangeArgs(cfg, []string{"-i", in, "-vn", "-map", streamSpec(cfg), "-af", defectsChain, "-f", "f32le", "-"}), " ") + " (second-difference click and equal-sample run scan)"
+	}
+	if cfg.UseEBUR128 && cfg.DialogGate {
+		m["dialog"] = dialogChain(cfg, probe.Channels)
+	}
+	if len(cfg.Channels) > 0 {
+		m["channels"] = subsetChain(cfg, cfg.Channels)
+	}
+	if !cfg.Mono && probe.Channels == 2 {
+		m["mono_sum_peak"] = monoSumChain
+		m["stereo"] = stereoChain
+		if cfg.CorrWin > 0 {
+			m["correlation_timeline"] = corrTimelineChain(probe.SampleRate, cfg.CorrWin)
+		}
+		if cfg.PhaseScope {
+			m["phase_scope"] = phaseScopeChain
+		}
+	}
+	if names := channelNames(probe.Layout, probe.Channels); !cfg.Mono && probe.Channels > 2 && names != nil {
+		m["downmix"] = downmixChain(cfg, names)
+	}
+	if cfg.UseBands && len(cfg.Bands) > 0 {
+		m["bands"] = bandsChain(cfg, cfg.Bands, probe.Channels)
+	}
+	if cfg.Fingerprint {
+		m["fingerprint"] = cfg.FpcalcBin + " -json " + in
+	}
+	if mustHave(cfg.AubioBin) == nil {
+		subs := []string{"pitch", "key"}
+		if strings.ToLower(cfg.BPMEngine) == "aubio" {
+			subs = append(subs, "tempo", "onset")
+		}
+		for _, sub := range subs {
+			m["aubio_"+sub] = cfg.AubioBin + " " + strings.Join(aubioArgs(cfg, sub, in), " ")
+		}
+	}
+	return m
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// gatedLoudness is BS.1770 integrated loudness over a run of ebur128 frames:
+// each 100 ms frame's momentary value is a 400 ms block with 75% overlap,
+// gated at -70 LUFS absolute and 10 LU below the absolute-gated mean
+func gatedLoudness(frames []loudnessFrame) *float64 {
+	power := func(l float64) float64 { return math.Pow(10, l/10) }
+	mean := func(gate float64) (float64, int) {
+		sum, n := 0.0, 0
+		for _, f := range frames {
+			if f.M > gate {
+				sum += power(f.M)
+				n++
+			}
+		}
+		if n == 0 {
+			return 0, 0
+		}
+		return 10 * math.Log10(sum/float64(n)), n
+	}
+	abs, n := mean(-70)
+	if n == 0 {
+		return nil
+	}
+	v, n := mean(math.Max(-70, abs-10))
+	if n == 0 {
+		return nil
+	}
+	return &v
+}
+
+// sectionLoudness splits frames into head/body/tail by duration fractions
+// and measures each; nil when the split is disabled or nothing was measured
+func sectionLoudness(frames []loudnessFrame, dur, headFrac, tailFrac float64) []SectionLoudness {
+	if len(frames) == 0 || dur <= 0 || headFrac <= 0 && tailFrac <= 0 {
+		return nil
+	}
+	headEnd, tailStart := dur*headFrac, dur*(1-tailFrac)
+	secs := []SectionLoudness{
+		{Name: "head", Start: 0, End: headEnd},
+		{Name: "body", Start: headEnd, End: tailStart},
+		{Name: "tail", Start: tailStart, End: dur},
+	}
+	var out []SectionLoudness
+	for _, s := range secs {
+		if s.End <= s.Start {
+			continue
+		}
+		var fs []loudnessFrame
+		for _, f := range frames {
+			// a frame's block ends at T; keep blocks that lie inside the section
+			if f.T-0.4 >= s.Start-1e-6 && f.T <= s.End+1e-6 {
+				fs = append(fs, f)
+			}
+		}
+		s.Integrated = gatedLoudness(fs)
+		out = append(out, s)
+	}
+	return out
+}
+
+// sectionNotes warns when the head or tail sits 3 LU or more off the body
+func sectionNotes(secs []SectionLoudness) []Note {
+	var body *float64
+	for _, s := range secs {
+		if s.Name == "body" {
+			body = s.Integrated
+		}
+	}
+	if body == nil {
+		return nil
+	}
+	var notes []Note
+	for _, s := range secs {
+		if s.Name == "body" || s.Integrated == nil {
+			continue
+		}
+		if d := *s.Integrated - *body; math.Abs(d) >= 3 {
+			notes = append(notes, newNote(SevWarn, "SECTION_JUMP", "%s is %+.1f LU vs body (%.1f vs %.1f LUFS).", s.Name, d, *s.Integrated, *body))
+		}
+	}
+	return notes
+}
+
+// parseSections reads "-sections head,tail" fractions; "0" disables
+func parseSections(s string) (head, tail float64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("want head,tail fractions, got %q", s)
+	}
+	head, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	tail, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err1 != nil || err2 != nil || head < 0 || tail < 0 || head+tail >= 1 {
+		return 0, 0, fmt.Errorf("want head,tail fractions in [0,1) summing below 1, got %q", s)
+	}
+	return head, tail, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// selftest signals: lavfi sources rendered to WAV, then analyzed end to end
+type selfCase struct {
+	name   string
+	source string // lavfi graph
+	secs   float64
+	checks []selfCheck
+	aubio  bool // needs -bpm-engine aubio
+}
+
+type selfCheck struct {
+	what      string
+	want, tol float64
+	got       func(a *Analysis) *float64
+}
+
+func fptr(v float64) *float64 { return &v }
+
+var selfCases = []selfCase{
+	{
+		name: "sine 1 kHz, -6 dBFS", source: "aevalsrc=0.5*sin(2*PI*1000*t):s=48000", secs: 5,
+		checks: []selfCheck{
+			{"peak dBFS", -6.02, 0.2, func(a *Analysis) *float64 { return fptr(a.Level.PeakDB) }},
+			{"RMS dBFS", -9.03, 0.2, func(a *Analysis) *float64 { return fptr(a.Level.RMSDB) }},
+			{"crest dB", 3.01, 0.3, func(a *Analysis) *float64 { return fptr(a.Level.CrestDB) }},
+		},
+	},
+	{
+		// EBU Tech 3341 case 1: stereo 1 kHz at -23 dBFS reads -23 LUFS
+		name: "stereo sine, EBU 3341 #1", source: "aevalsrc=0.0708*sin(2*PI*1000*t)|0.0708*sin(2*PI*1000*t):s=48000", secs: 20,
+		checks: []selfCheck{
+			{"integrated LUFS", -23, 0.3, func(a *Analysis) *float64 {
+				if a.Loudness == nil {
+					return nil
+				}
+				return fptr(a.Loudness.Integrated)
+			}},
+			{"correlation", 1, 0.05, func(a *Analysis) *float64 { return a.Stereo.Correlation }},
+		},
+	},
+	{
+		// uniform white noise in [-a,a] has RMS a/√3
+		name: "white noise, a=0.5", source: "anoisesrc=c=white:a=0.5:r=48000:s=1", secs: 5,
+		checks: []selfCheck{
+			{"RMS dBFS", -10.79, 0.5, func(a *Analysis) *float64 { return fptr(a.Level.RMSDB) }},
+		},
+	},
+	{
+		name: "digital silence", source: "anullsrc=r=48000:cl=stereo", secs: 3,
+		checks: []selfCheck{
+			{"silent", 1, 0, func(a *Analysis) *float64 {
+				if a.Silent {
+					return fptr(1)
+				}
+				return fptr(0)
+			}},
+		},
+	},
+	{
+		name: "click track, 120 BPM", source: "aevalsrc=if(lt(mod(t\\,0.5)\\,0.01)\\,0.8*sin(2*PI*1000*t)\\,0):s=44100", secs: 30, aubio: true,
+		checks: []selfCheck{
+			{"BPM", 120, 2, func(a *Analysis) *float64 {
+				if a.Tempo == nil {
+					return nil
+				}
+				return a.Tempo.BPMMedian
+			}},
+		},
+	},
+}
+
+// runSelftest renders each synthetic signal, analyzes it with cfg and checks
+// the readings against known values; it returns the number of failures
+func runSelftest(cfg *Config) int {
+	dir, err := os.MkdirTemp("", "analit-selftest-")
+	if err != nil {
+		fail("selftest: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	failed := 0
+	t0 := time.Now()
+	for i, c := range selfCases {
+		if c.aubio && cfg.BPMEngine != "aubio" {
+			warnf("%s: skipped (needs -bpm-engine aubio)", c.name)
+			continue
+		}
+		path := filepath.Join(dir, fmt.Sprintf("case%d.wav", i))
+		args := []string{"-y", "-hide_banner", "-f", "lavfi", "-i", c.source, "-t", fmt.Sprintf("%g", c.secs), "-c:a", "pcm_f32le", path}
+		if out, err := runCmd(cfg.FFmpegBin, args...); err != nil {
+			infof("[-] %s: generate failed: %v\n%s", c.name, err, out)
+			failed++
+			continue
+		}
+		ts := time.Now()
+		a, err := analyzeFile(cfg, path)
+		if err != nil {
+			infof("[-] %s: analysis failed: %v", c.name, err)
+			failed++
+			continue
+		}
+		for _, ck := range c.checks {
+			got := ck.got(a)
+			switch {
+			case got == nil:
+				infof("[-] %s: %s not measured (want %g±%g)", c.name, ck.what, ck.want, ck.tol)
+				failed++
+			case math.Abs(*got-ck.want) > ck.tol:
+				infof("[-] %s: %s %.2f (want %g±%g)", c.name, ck.what, *got, ck.want, ck.tol)
+				failed++
+			default:
+				infof("[+] %s: %s %.2f ok", c.name, ck.what, *got)
+			}
+		}
+		infof("    %s analyzed in %s", c.name, time.Since(ts).Round(time.Millisecond))
+	}
+	infof("selftest: %d failure(s), %s total", failed, time.Since(t0).Round(time.Millisecond))
+	return failed
+}
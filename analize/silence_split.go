@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"path/filepath"
@@ -11,8 +12,11 @@ type segment struct{ start, end float64 }
 
 // splitBySilence splits input file into segments based on silence spans longer
 // than minSilDur seconds. It trims `trim` seconds from the start and end of
-// each segment. Returned slice contains paths of created files.
-func splitBySilence(cfg *Config, in string, a *Analysis, minSilDur, trim float64) ([]string, error) {
+// each segment. Returned slice describes the created files, including each
+// segment's integrated loudness when ebur128 is enabled. Parts are named
+// after name, which differs from in when the input was buffered to a temp
+// file (stdin, -download-first).
+func splitBySilence(cfg *Config, in, name string, a *Analysis, minSilDur, trim float64) ([]SegmentInfo, error) {
 	spans := a.Silence
 	dur := a.Probe.Duration
 	// build segments between silence spans
@@ -30,9 +34,12 @@ func splitBySilence(cfg *Config, in string, a *Analysis, minSilDur, trim float64
 	if len(segs) <= 1 { // nothing to split
 		return nil, nil
 	}
-	base := strings.TrimSuffix(in, filepath.Ext(in))
-	ext := filepath.Ext(in)
-	var outs []string
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	ext := filepath.Ext(name)
+	// the parts are already cut, so measure them without -start/-end
+	pc := *cfg
+	pc.Start, pc.End = 0, 0
+	var outs []SegmentInfo
 	for i, sg := range segs {
 		s := sg.start
 		e := sg.end
@@ -41,12 +48,33 @@ func splitBySilence(cfg *Config, in string, a *Analysis, minSilDur, trim float64
 			e = math.Max(s, e-trim)
 		}
 		out := fmt.Sprintf("%s-part%02d%s", base, i+1, ext)
-		args := []string{"-y", "-i", in, "-ss", fmt.Sprintf("%f", s), "-to", fmt.Sprintf("%f", e), "-c", "copy", out}
+		args := []string{"-y", "-i", in, "-ss", fmt.Sprintf("%f", cfg.Start+s), "-to", fmt.Sprintf("%f", cfg.Start+e), "-vn", "-map", streamSpec(cfg), "-c", "copy", out}
 		if _, err := runCmd(cfg.FFmpegBin, args...); err != nil {
 			return outs, fmt.Errorf("ffmpeg split: %w", err)
 		}
-		fmt.Printf("[+] wrote %s\n", out)
-		outs = append(outs, out)
+		wrote(out)
+		si := SegmentInfo{Index: i + 1, Start: s, End: e, Duration: e - s, Path: out}
+		// quick loudness pass on the written file so segments can be normalized
+		// consistently; too-short segments simply get no value
+		if cfg.UseEBUR128 {
+			if l, err := ffmpegEBUR128(&pc, out, a.Probe.Channels); err == nil {
+				v := l.Integrated
+				si.Integrated = &v
+			}
+		}
+		outs = append(outs, si)
 	}
 	return outs, nil
 }
+
+// writeSegmentsJSON writes the segment map for downstream tools
+func writeSegmentsJSON(path string, segs []SegmentInfo) error {
+	if segs == nil {
+		segs = []SegmentInfo{}
+	}
+	buf, err := json.MarshalIndent(segs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, append(buf, '\n'))
+}
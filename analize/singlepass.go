@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The core measurements (volumedetect, astats, ebur128, silencedetect) share
+// one decode: asplit fans the input out to each filter, and the combined log
+// is demuxed per filter before the usual parsers see it. On long files this
+// replaces four full decodes with one.
+
+// singlePassChain is the filter_complex for the combined pass; the last
+// branch is left unlabeled so it becomes the (null) output stream
+func singlePassChain(cfg *Config, channels int) string {
+	branches := []string{volumedetectChain(cfg), astatsChain(cfg)}
+	if cfg.UseEBUR128 {
+		branches = append(branches, ebur128Chain(cfg, channels))
+	}
+	branches = append(branches, silenceChain(cfg))
+	var b strings.Builder
+	fmt.Fprintf(&b, "[0:a]asplit=%d", len(branches))
+	for i := range branches {
+		fmt.Fprintf(&b, "[sp%d]", i)
+	}
+	for i, f := range branches {
+		fmt.Fprintf(&b, ";[sp%d]%s", i, f)
+		if i < len(branches)-1 {
+			b.WriteString(",anullsink")
+		}
+	}
+	return b.String()
+}
+
+// singlePass holds the parsed results of the combined pass
+type singlePass struct {
+	PeakDB, RMSDB float64
+	Astats        map[string]float64
+	AstatsCh      []map[string]float64
+	Loudness      *LUFS // nil with -no-ebur128 or on parse failure
+	Silences      []SilenceSpan
+	Decoded       *float64 // seconds astats saw; nil if unparsed
+	DecodeErrors  int
+	SampleRates   []int // only when the rate changed midstream
+}
+
+func ffmpegSinglePass(cfg *Config, in string, probe ProbeInfo) (*singlePass, error) {
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-filter_complex", singlePassChain(cfg, probe.Channels), "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	return parseSinglePass(cfg, out, probe.SampleRate)
+}
+
+// parseSinglePass demuxes the combined log and parses each filter's part.
+// The decode itself is accounted for from the same log: astats' sample
+// count over rate is the decoded duration, and decoder errors and rate
+// changes are logged alongside the filters.
+func parseSinglePass(cfg *Config, out string, rate int) (*singlePass, error) {
+	logs := demuxFilterLog(out)
+	sp := &singlePass{}
+	var err error
+	if sp.PeakDB, sp.RMSDB, err = parseVolumedetect(logs.filter("volumedetect")); err != nil {
+		return nil, err
+	}
+	sp.Astats, sp.AstatsCh = parseAstats(logs.filter("astats"))
+	if cfg.UseEBUR128 {
+		if l, err := parseEBUR128(logs.filter("ebur128")); err == nil {
+			sp.Loudness = &l
+		}
+	}
+	sp.Silences = parseSilences(logs.filter("silencedetect"))
+	if n, ok := sp.Astats["number_of_samples"]; ok && rate > 0 {
+		d := n / float64(rate)
+		sp.Decoded = &d
+	}
+	sp.DecodeErrors = countDecodeErrors(out)
+	sp.SampleRates = parseRateChanges(out, rate)
+	return sp, nil
+}
+
+var reFilterTag = regexp.MustCompile(`^\[(?:Parsed_([a-z0-9_]+?_\d+)|([a-z][a-z0-9_]*)) @ [^\]]*\]`)
+
+// filterLogs is an ffmpeg log split by the filter instance that wrote each
+// line, keyed by instance name ("astats_3")
+type filterLogs map[string]string
+
+// demuxFilterLog splits an ffmpeg log by filter instance ("[Parsed_astats_3
+// @ 0x...] ..." → "astats_3"). Some filters log through their private
+// context instead ("[silencedetect @ 0x...]"); those lines go under the bare
+// name. Untagged lines continue the previous tagged one, since multi-line
+// reports such as the ebur128 summary carry the tag on their first line
+// only; lines before any tag go under "".
+func demuxFilterLog(out string) filterLogs {
+	bufs := map[string]*strings.Builder{}
+	cur := ""
+	sc := bufio.NewScanner(strings.NewReader(out))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if m := reFilterTag.FindStringSubmatch(line); m != nil {
+			cur = m[1] + m[2]
+		}
+		b, ok := bufs[cur]
+		if !ok {
+			b = &strings.Builder{}
+			bufs[cur] = b
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	logs := make(filterLogs, len(bufs))
+	for k, b := range bufs {
+		logs[k] = b.String()
+	}
+	return logs
+}
+
+// instances returns the log of every instance of filter name in graph
+// order: ffmpeg numbers instances as they appear in the graph string, so the
+// i-th entry belongs to the i-th such filter written
+func (l filterLogs) instances(name string) []string {
+	var idx []int
+	for k := range l {
+		i := strings.LastIndexByte(k, '_')
+		if i > 0 && k[:i] == name {
+			idx = append(idx, parseInt(k[i+1:]))
+		}
+	}
+	sort.Ints(idx)
+	out := make([]string, len(idx))
+	for j, n := range idx {
+		out[j] = l[fmt.Sprintf("%s_%d", name, n)]
+	}
+	return out
+}
+
+// filter returns the logs of every instance of name, concatenated, then
+// whatever name logged through its private context
+func (l filterLogs) filter(name string) string { return strings.Join(l.instances(name), "") + l[name] }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// silencedetect logs through its private context, not a Parsed_ instance
+func TestDemuxFilterLogSilences(t *testing.T) {
+	out, err := os.ReadFile("testdata/singlepass.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := demuxFilterLog(string(out))
+	got := parseSilences(logs.filter("silencedetect"))
+	want := []SilenceSpan{{0, 1.2504}, {5.5, 7.25}}
+	if len(got) != len(want) {
+		t.Fatalf("silences: got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("silence %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+	if pk, _, err := parseVolumedetect(logs.filter("volumedetect")); err != nil || pk != -3.1 {
+		t.Errorf("volumedetect: peak %v, err %v", pk, err)
+	}
+	ov, chans := parseAstats(logs.filter("astats"))
+	if len(chans) != 2 || ov["rms_level_db"] != -21.3 {
+		t.Errorf("astats: overall %v, %d channels", ov, len(chans))
+	}
+	l, err := parseEBUR128(logs.filter("ebur128"))
+	if err != nil || l.Integrated != -20.1 || l.Range != 4.2 {
+		t.Errorf("ebur128: %+v, err %v", l, err)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+	"os/exec"
+	"strconv"
+)
+
+// long-term spectrum resolution: 4096-point frames, ~11 Hz bins at 44.1 kHz
+const spectrumFFT = 4096
+
+// spectrumArgs decodes the mono downmix as raw floats, resampled to rate
+// unless it is 0
+func spectrumArgs(cfg *Config, in string, rate int) []string {
+	args := []string{"-i", in, "-vn", "-map", streamSpec(cfg), "-ac", "1"}
+	if rate > 0 {
+		args = append(args, "-ar", strconv.Itoa(rate))
+	}
+	return rangeArgs(cfg, append(args, "-f", "f32le", "-acodec", "pcm_f32le", "-"))
+}
+
+// longTermSpectrum averages the Hann-windowed power spectrum of every
+// n-sample frame (n a power of 2); the result is dB per bin, bin k at
+// k*rate/n Hz. ffmpeg has no filter that exposes a long-term spectrum as
+// numbers, so like the phase scope it reads raw samples.
+func longTermSpectrum(cfg *Config, in string, rate, n int) ([]float64, error) {
+	cmd := exec.Command(cfg.FFmpegBin, append([]string{"-hide_banner", "-nostats", "-loglevel", "error"}, spectrumArgs(cfg, in, rate)...)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	acquireProc()
+	defer releaseProc()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	win := make([]float64, n)
+	for i := range win {
+		win[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	power := make([]float64, n/2+1)
+	frame := make([]complex128, n)
+	buf := make([]byte, 4*n)
+	r := bufio.NewReaderSize(stdout, 1<<16)
+	frames := 0
+	for {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			break
+		}
+		for i := range frame {
+			v := math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+			frame[i] = complex(float64(v)*win[i], 0)
+		}
+		fft(frame)
+		for k := range power {
+			a := cmplx.Abs(frame[k])
+			power[k] += a * a
+		}
+		frames++
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg decode: %w", err)
+	}
+	if frames == 0 {
+		return nil, fmt.Errorf("shorter than one %d-sample frame", n)
+	}
+	db := make([]float64, len(power))
+	for k, p := range power {
+		db[k] = 10 * math.Log10(p/float64(frames)+1e-30)
+	}
+	return db, nil
+}
+
+// fft is an in-place iterative radix-2 transform; len(x) must be a power of 2
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*wk
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				wk *= w
+			}
+		}
+	}
+}
+
+// shelf detection: a lossy encoder's lowpass drops the spectrum by tens of
+// dB within a few hundred Hz and nothing comes back above it, where a
+// natural rolloff falls a few dB per kHz
+const (
+	shelfWindowHz = 500.0
+	shelfDropDB   = 20.0
+	shelfMinHz    = 10000.0
+)
+
+// spectralCutoff finds the frequency where the long-term spectrum falls off
+// a shelf: the largest drop in mean power between the shelfWindowHz below
+// and above a frequency, if it is at least shelfDropDB and no window
+// above it gets back within shelfDropDB/2 of the level below. nil: no shelf,
+// the content runs up to Nyquist (or tapers off naturally).
+func spectralCutoff(db []float64, sampleRate int) *float64 {
+	binHz := float64(sampleRate) / float64(2*(len(db)-1))
+	w := int(shelfWindowHz / binHz)
+	if w < 1 || len(db) < 2*w+2 {
+		return nil
+	}
+	mean := func(lo, hi int) float64 {
+		var p float64
+		for _, v := range db[lo:hi] {
+			p += math.Pow(10, v/10)
+		}
+		return 10 * math.Log10(p/float64(hi-lo))
+	}
+	best, bestDrop := -1, 0.0
+	for k := max(w, int(shelfMinHz/binHz)); k+w <= len(db); k++ {
+		if d := mean(k-w, k) - mean(k, k+w); d > bestDrop {
+			best, bestDrop = k, d
+		}
+	}
+	if best < 0 || bestDrop < shelfDropDB {
+		return nil
+	}
+	below := mean(best-w, best)
+	for k := best + w; k+w <= len(db); k += w {
+		if mean(k, k+w) > below-shelfDropDB/2 {
+			return nil
+		}
+	}
+	f := float64(best) * binHz
+	return &f
+}
+
+// typical encoder lowpass (LAME/AAC defaults) by bitrate: a shelf at or
+// below hz points to a source around that bitrate
+var lossyCutoffs = []struct {
+	hz   float64
+	kbps string
+}{
+	{12000, "64 kbps or less"},
+	{15500, "~96 kbps"},
+	{17000, "~128 kbps"},
+	{18500, "~160 kbps"},
+	{19700, "~192 kbps"},
+	{20200, "~256 kbps"},
+	{20700, "~320 kbps"},
+}
+
+// lossyNotes flags a lossless file whose spectrum stops at a lossy
+// encoder's lowpass; above ~20.7 kHz a shelf is as likely a mastering filter
+func lossyNotes(p ProbeInfo, cutoff *float64) []Note {
+	if cutoff == nil || !isLossless(p.CodecName) || p.SampleRate < 32000 {
+		return nil
+	}
+	for _, c := range lossyCutoffs {
+		if *cutoff <= c.hz {
+			return []Note{newNote(SevWarn, "LOSSY_SOURCE", "Lossless %s but the spectrum stops dead at %.1f kHz: likely transcoded from a lossy source of %s.", p.CodecName, *cutoff/1000, c.kbps)}
+		}
+	}
+	return nil
+}
+
+var standardRates = []int{8000, 11025, 16000, 22050, 32000, 44100, 48000, 88200, 96000, 176400, 192000, 352800, 384000}
+
+// resolution compares the declared depth and rate with the bits astats saw
+// in use and the bandwidth of the long-term spectrum. Resamplers cut just
+// below the source Nyquist, so a 250 Hz overshoot still counts as inside it.
+func resolution(p ProbeInfo, lv LevelStats, cutoff *float64) *Resolution {
+	if !isLossless(p.CodecName) || p.SampleRate <= 0 {
+		return nil
+	}
+	r := &Resolution{ClaimedBits: p.BitDepth, MeasuredBits: lv.EffectiveBits, ClaimedRate: p.SampleRate,
+		BandwidthHz: float64(p.SampleRate) / 2, SourceRate: p.SampleRate}
+	if cutoff == nil {
+		return r
+	}
+	r.BandwidthHz = *cutoff
+	for _, sr := range standardRates {
+		if sr >= p.SampleRate {
+			break
+		}
+		if float64(sr)/2+250 >= r.BandwidthHz {
+			r.SourceRate = sr
+			break
+		}
+	}
+	return r
+}
+
+// resolutionNotes flags hi-res (above 48 kHz) files whose spectrum fits a
+// lower standard rate; DEPTH_PADDED covers the bit depth on its own
+func resolutionNotes(r *Resolution) []Note {
+	if r == nil || r.ClaimedRate <= 48000 || r.SourceRate >= r.ClaimedRate {
+		return nil
+	}
+	claimed, measured := khz(r.ClaimedRate), khz(r.SourceRate)
+	if r.ClaimedBits > 0 {
+		claimed = fmt.Sprintf("%d-bit/%s", r.ClaimedBits, claimed)
+	}
+	if r.MeasuredBits != nil {
+		measured = fmt.Sprintf("%.0f-bit/%s", *r.MeasuredBits, measured)
+	}
+	return []Note{newNote(SevWarn, "FAKE_HIRES", "Sold as %s kHz but the content is %s kHz: bandwidth stops at %.1f kHz, likely upsampled.", claimed, measured, r.BandwidthHz/1000)}
+}
+
+// khz formats a sample rate the way it is usually written: 44.1, 48, 96
+func khz(sr int) string {
+	return strconv.FormatFloat(float64(sr)/1000, 'f', -1, 64)
+}
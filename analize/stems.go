@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// analyzeStems measures every stem in dir, sums them with amix and, when a
+// reference mix is given, reports how far the sum deviates from it.
+func analyzeStems(cfg *Config, dir, mix string) (*StemsReport, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var stems []string
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		if e.IsDir() || !isAudioFile(p) {
+			continue
+		}
+		if mix != "" && sameFile(p, mix) {
+			continue
+		}
+		stems = append(stems, p)
+	}
+	sort.Strings(stems)
+	if len(stems) == 0 {
+		return nil, fmt.Errorf("no audio stems in %s", dir)
+	}
+
+	r := &StemsReport{Dir: dir, Mix: mix, When: time.Now().Format(time.RFC3339)}
+	var total float64
+	for _, p := range stems {
+		sc := StemContribution{File: p}
+		if _, rms, err := ffmpegVolumedetect(cfg, p); err == nil {
+			sc.RMSDB = rms
+			total += math.Pow(10, rms/10)
+		}
+		if l, err := ffmpegEBUR128(cfg, p, 0); err == nil {
+			v := l.Integrated
+			sc.Integrated = &v
+		}
+		r.Stems = append(r.Stems, sc)
+	}
+
+	if l, err := ffmpegSumLoudness(cfg, stems); err == nil {
+		v := l.Integrated
+		r.SumLUFS = &v
+	}
+	for i := range r.Stems {
+		sc := &r.Stems[i]
+		if total > 0 {
+			sc.EnergyShare = math.Pow(10, sc.RMSDB/10) / total
+		}
+		if sc.Integrated != nil && r.SumLUFS != nil {
+			d := *sc.Integrated - *r.SumLUFS
+			sc.RelativeLU = &d
+		}
+	}
+
+	if mix == "" {
+		return r, nil
+	}
+	if l, err := ffmpegEBUR128(cfg, mix, 0); err == nil {
+		v := l.Integrated
+		r.MixLUFS = &v
+		if r.SumLUFS != nil {
+			d := *r.SumLUFS - v
+			r.DeviationLU = &d
+		}
+	}
+	if res, err := ffmpegSumResidual(cfg, stems, mix); err == nil {
+		r.ResidualRMSDB = &res
+		if _, mixRMS, err := ffmpegVolumedetect(cfg, mix); err == nil {
+			rel := res - mixRMS
+			r.ResidualRelDB = &rel
+			if rel > -20 {
+				r.Notes = append(r.Notes, newNote(SevWarn, "STEM_SUM_RESIDUAL", "Stem sum deviates from mix (residual %.2f dB below mix). Stems may be incomplete, misaligned or processed.", -rel))
+			}
+		}
+	}
+	if r.DeviationLU != nil && math.Abs(*r.DeviationLU) > 1.0 {
+		r.Notes = append(r.Notes, newNote(SevWarn, "STEM_SUM_LOUDNESS", "Stem sum loudness differs from mix by %+.2f LU.", *r.DeviationLU))
+	}
+	r.Notes = filterNotes(r.Notes, cfg.MinSeverity)
+	return r, nil
+}
+
+// amix of all stems without normalization, so the sum is a true sum
+func amixInputs(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "[%d:a]", i)
+	}
+	fmt.Fprintf(&b, "amix=inputs=%d:normalize=0", n)
+	return b.String()
+}
+
+func ffmpegSumLoudness(cfg *Config, stems []string) (LUFS, error) {
+	args := []string{"-hide_banner", "-nostats"}
+	for _, s := range stems {
+		args = append(args, "-i", s)
+	}
+	args = append(args, "-vn", "-filter_complex", amixInputs(len(stems))+","+ebur128Filter(cfg, 0), "-f", "null", "-")
+	out, _ := runCmd(cfg.FFmpegBin, rangeArgs(cfg, args)...)
+	return parseEBUR128(out)
+}
+
+// RMS of (sum of stems - mix), i.e. a null test of the stem sum
+func ffmpegSumResidual(cfg *Config, stems []string, mix string) (float64, error) {
+	args := []string{"-hide_banner", "-nostats"}
+	for _, s := range append(append([]string{}, stems...), mix) {
+		args = append(args, "-i", s)
+	}
+	filter := amixInputs(len(stems)) + "[sum];" +
+		fmt.Sprintf("[sum][%d:a]amix=inputs=2:weights='1 -1':normalize=0,volumedetect", len(stems))
+	args = append(args, "-vn", "-filter_complex", filter, "-f", "null", "-")
+	out, _ := runCmd(cfg.FFmpegBin, rangeArgs(cfg, args)...)
+	_, rms, err := parseVolumedetect(out)
+	return rms, err
+}
@@ -0,0 +1,81 @@
+package main
+
+import "math"
+
+const (
+	structStep   = 1.0 // seconds per envelope point
+	structHold   = 4   // points a new level must hold to count as a change
+	structMinLen = 8.0 // seconds; shorter sections merge into their neighbor
+)
+
+// structureSections segments a track by sustained level changes in the
+// short-term loudness envelope: the envelope is resampled to 1 s, and a
+// boundary is placed where each of the next structHold seconds sits
+// thresholdLU or more away from the running section mean, on the same
+// side. Each section's loudness is gated integrated over its frames.
+func structureSections(frames []loudnessFrame, thresholdLU float64) []Section {
+	if thresholdLU <= 0 || len(frames) == 0 {
+		return nil
+	}
+	// 1 s envelope from short-term values (below the absolute gate = quiet)
+	var env []float64
+	var times []float64
+	next := 0.0
+	for _, f := range frames {
+		if f.T >= next {
+			env = append(env, math.Max(f.S, -70))
+			times = append(times, f.T)
+			next = f.T + structStep
+		}
+	}
+	if len(env) < 2*structHold {
+		return nil
+	}
+	bounds := []int{0}
+	sum, n := 0.0, 0
+	for i := 0; i < len(env); i++ {
+		if n >= structHold && i+structHold <= len(env) {
+			if sustainedShift(env[i:i+structHold], sum/float64(n), thresholdLU) &&
+				times[i]-times[bounds[len(bounds)-1]] >= structMinLen {
+				bounds = append(bounds, i)
+				sum, n = 0, 0
+			}
+		}
+		sum += env[i]
+		n++
+	}
+	end := frames[len(frames)-1].T
+	// a short tail section is folded into the previous one
+	if len(bounds) > 1 && end-times[bounds[len(bounds)-1]] < structMinLen {
+		bounds = bounds[:len(bounds)-1]
+	}
+	var out []Section
+	for k, bi := range bounds {
+		s := Section{Index: k + 1, Start: times[bi], End: end}
+		if k == 0 {
+			s.Start = 0
+		}
+		if k+1 < len(bounds) {
+			s.End = times[bounds[k+1]]
+		}
+		var fs []loudnessFrame
+		for _, f := range frames {
+			if f.T > s.Start && f.T <= s.End {
+				fs = append(fs, f)
+			}
+		}
+		s.Loudness = gatedLoudness(fs)
+		out = append(out, s)
+	}
+	return out
+}
+
+// every point at least th away from ref, all above or all below it
+func sustainedShift(pts []float64, ref, th float64) bool {
+	up, down := true, true
+	for _, v := range pts {
+		up = up && v-ref >= th
+		down = down && ref-v >= th
+	}
+	return up || down
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SummaryRow is one file's line in a multi-file summary
+type SummaryRow struct {
+	File     string
+	Duration float64
+	PeakDB   float64
+	LUFS     *float64
+	BPM      *float64
+}
+
+func summaryRow(a *Analysis) SummaryRow {
+	r := SummaryRow{File: a.File, Duration: a.Probe.Duration, PeakDB: a.Level.PeakDB}
+	if a.Loudness != nil {
+		v := a.Loudness.Integrated
+		r.LUFS = &v
+	}
+	if a.Tempo != nil {
+		r.BPM = a.Tempo.BPMMedian
+	}
+	return r
+}
+
+// sortRows orders rows by lufs|peak|bpm|name|duration; rows missing the
+// metric sort last regardless of direction. Unknown keys keep input order.
+func sortRows(rows []SummaryRow, key string, reverse bool) {
+	val := func(r SummaryRow) float64 {
+		switch key {
+		case "lufs":
+			return derefFloat(r.LUFS)
+		case "peak":
+			return r.PeakDB
+		case "bpm":
+			return derefFloat(r.BPM)
+		case "duration":
+			return r.Duration
+		}
+		return 0
+	}
+	switch key {
+	case "name":
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := strings.ToLower(filepath.Base(rows[i].File)), strings.ToLower(filepath.Base(rows[j].File))
+			if reverse {
+				return a > b
+			}
+			return a < b
+		})
+	case "lufs", "peak", "bpm", "duration":
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := val(rows[i]), val(rows[j])
+			if math.IsNaN(a) || math.IsNaN(b) {
+				return !math.IsNaN(a)
+			}
+			if reverse {
+				return a > b
+			}
+			return a < b
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// channel order of the common ffmpeg layouts, as ffprobe names them
+var layoutChannels = map[string][]string{
+	"mono":           {"FC"},
+	"stereo":         {"FL", "FR"},
+	"2.1":            {"FL", "FR", "LFE"},
+	"3.0":            {"FL", "FR", "FC"},
+	"3.0(back)":      {"FL", "FR", "BC"},
+	"4.0":            {"FL", "FR", "FC", "BC"},
+	"quad":           {"FL", "FR", "BL", "BR"},
+	"quad(side)":     {"FL", "FR", "SL", "SR"},
+	"3.1":            {"FL", "FR", "FC", "LFE"},
+	"5.0":            {"FL", "FR", "FC", "BL", "BR"},
+	"5.0(side)":      {"FL", "FR", "FC", "SL", "SR"},
+	"4.1":            {"FL", "FR", "FC", "LFE", "BC"},
+	"5.1":            {"FL", "FR", "FC", "LFE", "BL", "BR"},
+	"5.1(side)":      {"FL", "FR", "FC", "LFE", "SL", "SR"},
+	"6.0":            {"FL", "FR", "FC", "BC", "SL", "SR"},
+	"6.1":            {"FL", "FR", "FC", "LFE", "BC", "SL", "SR"},
+	"7.0":            {"FL", "FR", "FC", "BL", "BR", "SL", "SR"},
+	"7.1":            {"FL", "FR", "FC", "LFE", "BL", "BR", "SL", "SR"},
+	"7.1(wide)":      {"FL", "FR", "FC", "LFE", "BL", "BR", "FLC", "FRC"},
+	"7.1(wide-side)": {"FL", "FR", "FC", "LFE", "FLC", "FRC", "SL", "SR"},
+}
+
+// channelNames maps a probed layout to per-channel names; nil when the
+// layout is unknown or doesn't match the channel count
+func channelNames(layout string, channels int) []string {
+	names := layoutChannels[layout]
+	if len(names) != channels {
+		return nil
+	}
+	return names
+}
+
+// checkChannels rejects -channels names the stream doesn't have: pan would
+// fail on them, and the subset would be missing from the report without a
+// word. A stream without a layout tag is taken as mono or stereo, as
+// ffmpeg guesses it.
+func checkChannels(sel []string, layout string, channels int) error {
+	if layout == "" && channels <= 2 {
+		layout = map[int]string{1: "mono", 2: "stereo"}[channels]
+	}
+	valid := channelNames(layout, channels)
+	if valid == nil {
+		return fmt.Errorf("-channels: channel layout %q (%d channels) has no known names", layout, channels)
+	}
+	for _, c := range sel {
+		if !slices.Contains(valid, c) {
+			return fmt.Errorf("-channels: no %s in this %s stream; valid: %s", c, layout, strings.Join(valid, ","))
+		}
+	}
+	return nil
+}
+
+// Lo/Ro stereo downmix gains (ITU-R BS.775): centre and surrounds at -3 dB,
+// a back centre split between both sides, LFE dropped
+var downmixGains = map[string][2]float64{
+	"FL":  {1, 0},
+	"FR":  {0, 1},
+	"FLC": {1, 0},
+	"FRC": {0, 1},
+	"FC":  {math.Sqrt2 / 2, math.Sqrt2 / 2},
+	"BL":  {math.Sqrt2 / 2, 0},
+	"SL":  {math.Sqrt2 / 2, 0},
+	"BR":  {0, math.Sqrt2 / 2},
+	"SR":  {0, math.Sqrt2 / 2},
+	"BC":  {0.5, 0.5},
+}
+
+// downmixChain folds the named channels to Lo/Ro at unity in float (no
+// normalization, so a downmix that clips shows up as > 0 dBFS), then
+// measures it like the full mix. astats passes the float samples on
+// untouched, so ebur128 after it sees the overs too; volumedetect would
+// have converted to s16 and clipped them. Channels are addressed by index,
+// so it works whatever layout ffmpeg assigned.
+func downmixChain(cfg *Config, names []string) string {
+	var side [2][]string
+	for i, n := range names {
+		g := downmixGains[n]
+		for s := range side {
+			if g[s] != 0 {
+				side[s] = append(side[s], fmt.Sprintf("%.4g*c%d", g[s], i))
+			}
+		}
+	}
+	f := fmt.Sprintf("aformat=sample_fmts=flt,pan=stereo|c0=%s|c1=%s,aformat=sample_fmts=flt,astats=measure_perchannel=none",
+		strings.Join(side[0], "+"), strings.Join(side[1], "+"))
+	if cfg.UseEBUR128 {
+		f += "," + ebur128Filter(cfg, 2)
+	}
+	return f
+}
+
+func ffmpegDownmix(cfg *Config, in string, names []string) (*Downmix, error) {
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-af", downmixChain(cfg, names), "-f", "null", "-"})
+	out, _ := runCmd(cfg.FFmpegBin, args...)
+	peak, err := parseAstatsPeak(out)
+	if err != nil {
+		return nil, fmt.Errorf("downmix: %w", err)
+	}
+	d := &Downmix{PeakDB: peak}
+	if l, err := parseEBUR128(out); err == nil {
+		d.Integrated, d.TruePeakDBTP = &l.Integrated, l.TruePeak
+	}
+	return d, nil
+}
+
+// surroundStats derives the multichannel balance figures from the
+// per-channel RMS already measured in the level pass: LFE and centre
+// relative to the front pair, and front versus surround (side/back) energy.
+// The downmix comparison needs the full-mix loudness, so it is added here.
+func surroundStats(layout string, names []string, chs []ChannelStats, dm *Downmix, lufs *LUFS) *Surround {
+	s := &Surround{Layout: layout, Downmix: dm}
+	if names == nil || len(chs) != len(names) {
+		return s
+	}
+	power := map[string]float64{}
+	for i, ch := range chs {
+		power[names[i]] = math.Pow(10, ch.RMSDB/10)
+	}
+	db := func(num, den float64) *float64 {
+		if num <= 0 || den <= 0 {
+			return nil
+		}
+		v := 10 * math.Log10(num/den)
+		return &v
+	}
+	front := (power["FL"] + power["FR"]) / 2
+	if p, ok := power["LFE"]; ok {
+		s.LFEDB = db(p, front)
+	}
+	if p, ok := power["FC"]; ok {
+		s.CenterDB = db(p, front)
+	}
+	var rear float64
+	var nRear int
+	for _, n := range []string{"BL", "BR", "SL", "SR", "BC"} {
+		if p, ok := power[n]; ok {
+			rear += p
+			nRear++
+		}
+	}
+	if nRear > 0 {
+		s.FrontRearDB = db(front, rear/float64(nRear))
+	}
+	if dm != nil && dm.Integrated != nil && lufs != nil {
+		d := *dm.Integrated - lufs.Integrated
+		dm.DeltaLU = &d
+	}
+	return s
+}
+
+// surroundNotes; named is false when the layout couldn't be mapped to
+// channel names, which leaves only the per-channel levels
+func surroundNotes(s *Surround, named bool) []Note {
+	if s == nil {
+		return nil
+	}
+	if !named {
+		layout := s.Layout
+		if layout == "" {
+			layout = "not reported"
+		}
+		return []Note{newNote(SevInfo, "LAYOUT_UNKNOWN", "Channel layout %s; per-channel levels only (no LFE, front/rear or downmix figures).", layout)}
+	}
+	var notes []Note
+	if dm := s.Downmix; dm != nil {
+		peak := dm.PeakDB
+		if dm.TruePeakDBTP != nil {
+			peak = *dm.TruePeakDBTP
+		}
+		if peak > 0 {
+			notes = append(notes, newNote(SevWarn, "DOWNMIX_CLIPS", "Stereo downmix (Lo/Ro, -3 dB centre/surrounds) peaks at %+.2f dB; lower the mix %.1f dB or expect clipping on stereo playback.", peak, peak))
+		}
+	}
+	return notes
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckChannels(t *testing.T) {
+	if err := checkChannels([]string{"FL", "LFE"}, "5.1(side)", 6); err != nil {
+		t.Errorf("5.1: %v", err)
+	}
+	if err := checkChannels([]string{"FR"}, "", 2); err != nil {
+		t.Errorf("untagged stereo: %v", err)
+	}
+	err := checkChannels([]string{"FL", "LFE"}, "stereo", 2)
+	if err == nil || !strings.Contains(err.Error(), "LFE") || !strings.Contains(err.Error(), "FL,FR") {
+		t.Errorf("LFE in stereo: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "math"
+
+const timelineStep = 1.0 // seconds per timeline point
+
+// loudnessTimeline resamples the 100 ms ebur128 frames to one momentary /
+// short-term point per timelineStep and finds the loudest momentary and
+// short-term values over all frames. Values ebur128 reports as -inf
+// (silence) are left nil.
+func loudnessTimeline(frames []loudnessFrame) (pts []LoudnessPoint, maxM, maxS *float64) {
+	finite := func(v float64) *float64 {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil
+		}
+		return &v
+	}
+	next := 0.0
+	for _, f := range frames {
+		if m := finite(f.M); m != nil && (maxM == nil || *m > *maxM) {
+			maxM = m
+		}
+		if s := finite(f.S); s != nil && (maxS == nil || *s > *maxS) {
+			maxS = s
+		}
+		if f.T >= next {
+			pts = append(pts, LoudnessPoint{T: f.T, M: finite(f.M), S: finite(f.S)})
+			next = f.T + timelineStep
+		}
+	}
+	return pts, maxM, maxS
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// a silence span touching the file edge within this counts as leading or
+// trailing; silencedetect timestamps are frame-granular
+const trimEdgeSec = 0.05
+
+var reSilenceStart = regexp.MustCompile(`silence_start:\s*([-\d\.]+)`)
+
+// silenceSpans runs silencedetect alone and returns its spans with the
+// decoded length, closing a silence still running at the end
+func silenceSpans(cfg *Config, in string, probe ProbeInfo) ([]SilenceSpan, float64) {
+	args := rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-i", in, "-vn", "-map", streamSpec(cfg), "-af", silenceChain(cfg), "-f", "null", "-"})
+	o, _ := runCmd(cfg.FFmpegBin, args...)
+	dur := rangeDuration(cfg, probe.Duration)
+	if d, _, _, err := ffmpegDecodedDuration(cfg, in); err == nil {
+		dur = d
+	}
+	spans := parseSilences(o)
+	// a silence still running at end of stream may have no silence_end
+	if starts := reSilenceStart.FindAllStringSubmatch(o, -1); len(starts) > len(spans) {
+		spans = append(spans, SilenceSpan{parseFloat(starts[len(starts)-1][1]), dur})
+	}
+	return spans, dur
+}
+
+// trimPoints finds where the audio starts and ends: after a silence span
+// that opens the file and before one that closes it, widened by pad
+// seconds. ok is false when the whole file is one silence.
+func trimPoints(spans []SilenceSpan, dur, pad float64) (start, end float64, ok bool) {
+	start, end = 0, dur
+	if len(spans) > 0 && spans[0].Start <= trimEdgeSec {
+		start = spans[0].End
+	}
+	if n := len(spans); n > 0 && spans[n-1].End >= dur-trimEdgeSec {
+		end = spans[n-1].Start
+	}
+	if end <= start {
+		return 0, 0, false
+	}
+	return max(0, start-pad), min(dur, end+pad), true
+}
+
+// trimChain cuts to [start, end) and fades fade seconds into and out of the
+// kept audio, at the edges that were cut only
+func trimChain(start, end, dur, fade float64) string {
+	chain := fmt.Sprintf("atrim=start=%.6f:end=%.6f,asetpts=PTS-STARTPTS", start, end)
+	fade = min(fade, (end-start)/2)
+	if fade <= 0 {
+		return chain
+	}
+	if start > 0 {
+		chain += fmt.Sprintf(",afade=t=in:st=0:d=%.3f", fade)
+	}
+	if end < dur {
+		chain += fmt.Sprintf(",afade=t=out:st=%.6f:d=%.3f", end-start-fade, fade)
+	}
+	return chain
+}
+
+// trimSilence writes in without its leading and trailing silence, as
+// silencedetect sees it at -silence-threshold, and probes the result so
+// the report has the written length, not the planned one
+func trimSilence(cfg *Config, in, out string, pad, fade float64) (*TrimReport, error) {
+	probe, err := ffprobeInfo(cfg, in)
+	if err != nil {
+		return nil, err
+	}
+	spans, dur := silenceSpans(cfg, in, probe)
+	start, end, ok := trimPoints(spans, dur, pad)
+	if !ok {
+		return nil, fmt.Errorf("%s: silent below %.1f dBFS throughout; nothing to keep", in, cfg.SilThresDB)
+	}
+	r := &TrimReport{In: in, Out: out, ThresholdDB: cfg.SilThresDB, PadSec: pad, FadeSec: fade,
+		Duration: dur, Start: start, End: end, LeadSec: start, TailSec: dur - end}
+	if start == 0 && end == dur {
+		return r, nil
+	}
+	r.Filter = trimChain(start, end, dur, fade)
+	if err := ensureParent(out); err != nil {
+		return nil, err
+	}
+	args := rangeArgs(cfg, []string{"-y", "-hide_banner", "-nostats", "-loglevel", "error", "-i", in, "-vn", "-map", streamSpec(cfg), "-af", r.Filter})
+	args = append(append(args, pcmCodec(out, probe)...), out)
+	if o, err := runCmd(cfg.FFmpegBin, args...); err != nil {
+		return nil, fmt.Errorf("ffmpeg trim: %w\n%s", err, o)
+	}
+	wrote(out)
+	r.Written = true
+	qc := *cfg
+	qc.Start, qc.End, qc.Stream = 0, 0, 0
+	if p, err := ffprobeInfo(&qc, out); err == nil {
+		r.Kept = &p.Duration
+	}
+	return r, nil
+}
+
+func renderTrim(cfg *Config, r *TrimReport) (string, error) {
+	p := cfg.Precision
+	var b strings.Builder
+	kept := fmtOpt(r.Kept, p.sec)
+	switch strings.ToLower(cfg.Report) {
+	case "json", "yaml":
+		return renderJSON(cfg, r)
+	case "md":
+		fmt.Fprintf(&b, "# Trim: %s\n\n- Threshold: `%s dBFS`\n- Pad: `%ss`\n- Fade: `%ss`\n", filepath.Base(r.In), p.db(r.ThresholdDB), p.sec(r.PadSec), p.sec(r.FadeSec))
+		if r.Written {
+			fmt.Fprintf(&b, "- Output: `%s`\n", r.Out)
+		} else {
+			fmt.Fprintf(&b, "- Output: none, no leading or trailing silence\n")
+		}
+		fmt.Fprintf(&b, "\n| | Seconds |\n|---|---:|\n")
+		fmt.Fprintf(&b, "| Input | %s |\n| Removed at start | %s |\n| Removed at end | %s |\n| Kept | %s |\n",
+			p.sec(r.Duration), p.sec(r.LeadSec), p.sec(r.TailSec), kept)
+	default:
+		if r.Written {
+			fmt.Fprintf(&b, "TRIM: %s -> %s (threshold %s dBFS, pad %ss, fade %ss)\n\n", r.In, r.Out, p.db(r.ThresholdDB), p.sec(r.PadSec), p.sec(r.FadeSec))
+		} else {
+			fmt.Fprintf(&b, "TRIM: %s: no leading or trailing silence below %s dBFS, nothing written\n\n", r.In, p.db(r.ThresholdDB))
+		}
+		fmt.Fprintf(&b, "%-18s %10ss\n", "Input", p.sec(r.Duration))
+		fmt.Fprintf(&b, "%-18s %10ss\n", "Removed at start", p.sec(r.LeadSec))
+		fmt.Fprintf(&b, "%-18s %10ss\n", "Removed at end", p.sec(r.TailSec))
+		fmt.Fprintf(&b, "%-18s %10ss\n", "Kept", kept)
+	}
+	return b.String(), nil
+}
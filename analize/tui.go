@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// plain ANSI, no TUI dependency: alternate screen, colors, a key to quit
+const (
+	ansiAltOn  = "\x1b[?1049h\x1b[H\x1b[2J"
+	ansiAltOff = "\x1b[?1049l"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiReset  = "\x1b[0m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiRed    = "\x1b[31m"
+)
+
+type specState int
+
+const (
+	specNone specState = iota // no verdict, value only
+	specOK
+	specWarn
+	specBad
+)
+
+func (s specState) dot() string {
+	switch s {
+	case specOK:
+		return ansiGreen + "●" + ansiReset
+	case specWarn:
+		return ansiYellow + "●" + ansiReset
+	case specBad:
+		return ansiRed + "●" + ansiReset
+	}
+	return " "
+}
+
+// reference loudness the dashboard checks against: -lufs-relative if set,
+// else the standard's own (R128 -23, A/85 -24)
+func tuiTarget(cfg *Config) float64 {
+	switch {
+	case cfg.LUFSTarget != 0:
+		return cfg.LUFSTarget
+	case cfg.LoudnessStd == "atsc":
+		return -24
+	}
+	return -23
+}
+
+// renderTUI lays the key metrics out as a one-screen dashboard
+func renderTUI(cfg *Config, a *Analysis) string {
+	p := cfg.Precision
+	var b strings.Builder
+	row := func(label, value string, s specState) {
+		fmt.Fprintf(&b, "  %s %-16s %s\n", s.dot(), label, value)
+	}
+	head := func(title string) { fmt.Fprintf(&b, "\n %s%s%s\n", ansiBold, title, ansiReset) }
+
+	fmt.Fprintf(&b, "%s analit · %s%s\n", ansiBold, filepath.Base(a.File), ansiReset)
+	fmt.Fprintf(&b, " %s%s | %s | %ss | %d Hz | %d ch%s\n", ansiDim,
+		a.Probe.FormatName, a.Probe.CodecName, p.sec(a.Probe.Duration), a.Probe.SampleRate, a.Probe.Channels, ansiReset)
+	if a.Silent {
+		head("Levels")
+		row("Peak", p.db(a.Level.PeakDB)+" dBFS", specBad)
+	} else {
+		head("Levels")
+		row("Peak", p.db(a.Level.PeakDB)+" dBFS", grade(a.Level.PeakDB <= -1, a.Level.PeakDB < 0))
+		if tp := a.Level.TruePeakDBTP; tp != nil {
+			ceil, _ := deliveryCeiling(cfg)
+			row("True peak", p.db(*tp)+" dBTP", grade(*tp <= ceil, *tp <= 0))
+		}
+		row("RMS", p.db(a.Level.RMSDB)+" dBFS", specNone)
+		row("Crest", p.db(a.Level.CrestDB)+" dB", grade(a.Level.CrestDB >= 8, a.Level.CrestDB >= 6))
+		if c := a.Level.ClipSamples; c != nil {
+			row("Clipped samples", fmt.Sprintf("%d", *c), grade(*c == 0, *c < 10))
+		}
+		if l := a.Loudness; l != nil {
+			t := tuiTarget(cfg)
+			d := l.Integrated - t
+			head(fmt.Sprintf("Loudness (target %.0f %s)", t, loudnessUnit(l)))
+			row("Integrated", fmt.Sprintf("%s %s (%s LU)", p.lufs(l.Integrated), loudnessUnit(l), p.lu(d)), grade(d >= -1 && d <= 1, d >= -2 && d <= 2))
+			row("Range", p.lufs(l.Range)+" LU", specNone)
+			for _, s := range a.Sections {
+				row(s.Name, fmtOpt(s.Integrated, p.lufs)+" "+loudnessUnit(l), specNone)
+			}
+		}
+		if !a.Mono {
+			head("Stereo")
+			if c := a.Stereo.Correlation; c != nil {
+				row("Correlation", p.corr(*c), grade(*c >= 0.2, *c >= 0))
+			}
+			row("Side/Mid", p.db(a.Stereo.SideMidRatioDB)+" dB", specNone)
+			if a.MonoSafety != "" {
+				row("Mono safety", a.MonoSafety, grade(a.MonoSafety == "safe", a.MonoSafety == "caution"))
+			}
+		}
+		if a.Tempo != nil || a.Key != nil {
+			head("Music")
+			if a.Tempo != nil {
+				row("BPM", fmtOpt(a.Tempo.BPMMedian, p.bpm), specNone)
+			}
+			if a.Key != nil && a.Key.Key != nil {
+				k := *a.Key.Key
+				if a.Key.Scale != nil {
+					k += " " + *a.Key.Scale
+				}
+				row("Key", k, specNone)
+			}
+		}
+	}
+	if len(a.Notes) > 0 {
+		head("Notes")
+		for _, n := range a.Notes {
+			s := specWarn
+			switch n.Severity {
+			case SevInfo:
+				s = specNone
+			case SevError:
+				s = specBad
+			}
+			row(n.Code, n.Message, s)
+		}
+	}
+	return b.String()
+}
+
+func grade(ok, warn bool) specState {
+	switch {
+	case ok:
+		return specOK
+	case warn:
+		return specWarn
+	}
+	return specBad
+}
+
+// showTUI draws the dashboard on the alternate screen until Enter
+func showTUI(cfg *Config, a *Analysis) {
+	fmt.Print(ansiAltOn + renderTUI(cfg, a))
+	fmt.Printf("\n %spress Enter to quit%s", ansiDim, ansiReset)
+	bufio.NewReader(os.Stdin).ReadString('\n')
+	fmt.Print(ansiAltOff)
+}
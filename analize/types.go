@@ -1,44 +1,289 @@
 package main
 
+import "time"
+
 type ProbeInfo struct {
 	FormatName string
+	CodecName  string
+	SampleFmt  string // s16, s32, flt, fltp, ...
 	Duration   float64
 	SampleRate int
 	Channels   int
+	Layout     string // ffprobe channel_layout (stereo, 5.1(side), ...); "" if not reported
 	BitRate    int64
 	BitDepth   int
+
+	Stream       int               // index of the analyzed stream among the audio streams (ffmpeg 0:a:N)
+	AudioStreams int               // audio streams in the file
+	Tracks       []AudioTrack      `json:",omitempty"` // every audio stream, for video and multi-stream files
+	Video        *VideoInfo        `json:",omitempty"` // first video stream (not cover art)
+	Tags         map[string]string `json:"-"`          // format tags merged with the stream's, keys lowercased
+
+	EncoderDelay   *int // samples (mp3/aac priming)
+	EncoderPadding *int // samples
+
+	OpusOutputGainDB  *float64 // OpusHead output gain, applied by the decoder
+	OpusMappingFamily *int     // OpusHead channel mapping family (0 mono/stereo, 1 Vorbis order, 255 undefined)
+}
+
+// Resolution sets what the file claims against what its content uses:
+// 24-bit/96 kHz that measures 16 bits and stops at 22 kHz is a padded,
+// upsampled CD
+type Resolution struct {
+	ClaimedBits  int      // container/codec bit depth; 0 if not reported
+	MeasuredBits *float64 // bits actually used (astats)
+	ClaimedRate  int
+	BandwidthHz  float64 // spectral cutoff, or Nyquist when the spectrum has no shelf
+	SourceRate   int     // smallest standard rate whose Nyquist holds the bandwidth
+}
+
+// Fingerprint is the Chromaprint of the (first two minutes of the) audio, as
+// fpcalc prints it; equal fingerprints mean the same recording
+type Fingerprint struct {
+	Chromaprint string
+	Duration    float64        // seconds, as fpcalc decoded them
+	AcoustID    *AcoustIDMatch `json:",omitempty"` // -acoustid-key lookup
+}
+
+// AcoustIDMatch is the best-scoring AcoustID result with a MusicBrainz
+// recording attached
+type AcoustIDMatch struct {
+	ID            string
+	Score         float64
+	RecordingMBID string   `json:",omitempty"`
+	Title         string   `json:",omitempty"`
+	Artist        string   `json:",omitempty"`
+	ArtistMBIDs   []string `json:",omitempty"`
+	ReleaseGroups []string `json:",omitempty"` // MBIDs
+}
+
+// Metadata identifies the track from its tags; Tags is the raw map
+type Metadata struct {
+	Title      string              `json:",omitempty"`
+	Artist     string              `json:",omitempty"`
+	Album      string              `json:",omitempty"`
+	ISRC       string              `json:",omitempty"`
+	Encoder    string              `json:",omitempty"`
+	ReplayGain *EmbeddedReplayGain `json:",omitempty"` // as tagged, not measured
+	Tags       map[string]string
+}
+
+type EmbeddedReplayGain struct {
+	TrackGainDB *float64 `json:",omitempty"`
+	TrackPeak   *float64 `json:",omitempty"` // linear
+	AlbumGainDB *float64 `json:",omitempty"`
+	AlbumPeak   *float64 `json:",omitempty"`
+}
+
+// AudioTrack is one audio stream of a container, as ffprobe lists it
+type AudioTrack struct {
+	Stream   int // 0:a:N
+	Codec    string
+	Language string `json:",omitempty"`
+	Channels int
+	Layout   string   `json:",omitempty"`
+	Duration *float64 `json:",omitempty"` // nil if the container doesn't say
+}
+
+type VideoInfo struct {
+	Codec         string
+	Width, Height int
+	Duration      *float64 `json:",omitempty"`
 }
 
 type LevelStats struct {
+	PeakDB             float64
+	RMSDB              float64
+	CrestDB            float64
+	TrueCrestDB        *float64 // true peak - RMS
+	PLR                *float64 `json:",omitempty"` // peak-to-loudness ratio: true peak - integrated
+	PSR                *float64 `json:",omitempty"` // peak-to-short-term ratio: true peak - max short-term
+	DR                 *int     `json:",omitempty"` // TT DR meter style score over 3 s blocks
+	TruePeakDBTP       *float64
+	TruePeakEngine     string `json:",omitempty"` // ebur128|oversample4x|oversample8x
+	HeadroomDB         float64
+	DCOffset           float64
+	ZeroXRate          float64
+	NoiseFloor         float64
+	EffectiveBits      *float64 // bits actually used (astats bit depth)
+	ClipSamples        *int64
+	ClipPercent        *float64
+	PinnedRatio        *float64 // share of samples at the ceiling (0..1)
+	SustainedPeakRatio *float64 // share of time short-term level is within 1 dB of its max
+	Brickwalled        bool
+	Clicks             *int64 // click events, merged across channels
+	ClicksPerMin       *float64
+	ClickGrade         *string        // clean|light|heavy
+	PerChannel         []ChannelStats `json:",omitempty"`
+}
+
+// ChannelStats is one channel's levels (astats per-channel section)
+type ChannelStats struct {
+	Channel      int    // 1-based, as astats numbers them
+	Name         string `json:",omitempty"` // FL, FR, FC, LFE, ... when the layout is known
 	PeakDB       float64
 	RMSDB        float64
-	CrestDB      float64
-	TruePeakDBTP *float64
-	HeadroomDB   float64
 	DCOffset     float64
-	ZeroXRate    float64
-	NoiseFloor   float64
-	ClipSamples  *int64
-	ClipPercent  *float64
+	TruePeakDBTP *float64 // oversampled peak (-tp-oversample)
 }
 
 type LUFS struct {
+	Standard   string // ebu|atsc
 	Integrated float64
 	Range      float64
 	TruePeak   *float64
+	SamplePeak *float64 // only with -ebur128-peak sample
+	Target     *float64 // reference LUFS for Relative
+	Relative   *float64 // Integrated - Target (LU)
+	Dialog     *float64 `json:",omitempty"` // -dialog-gate: integrated over 300-3400 Hz (approximate dialnorm)
+
+	MaxMomentary *float64        `json:",omitempty"` // loudest 400 ms window
+	MaxShortTerm *float64        `json:",omitempty"` // loudest 3 s window
+	Timeline     []LoudnessPoint `json:",omitempty"` // per-second momentary/short-term
+
+	frames []loudnessFrame // per-100ms momentary/short-term values
 }
 
-type BandStat struct {
-	Band   Bandspec
+// LoudnessPoint is one timeline sample; nil where ebur128 reports -inf
+type LoudnessPoint struct {
+	T    float64 // seconds
+	M, S *float64
+}
+
+type loudnessFrame struct {
+	T    float64 // seconds
+	M, S float64 // momentary / short-term LUFS
+}
+
+// SubsetStats measures only the channels picked with -channels
+type SubsetStats struct {
+	Channels     string // e.g. "FL,FR"
+	PeakDB       float64
+	RMSDB        float64
+	Integrated   *float64
+	TruePeakDBTP *float64
+}
+
+// Section is one stretch of roughly constant level (verse, chorus, drop).
+// Analysis lists them as Structure: its Sections field already holds the
+// head/body/tail split, whose name is part of the JSON output.
+type Section struct {
+	Index      int
+	Start, End float64
+	Loudness   *float64 // integrated over the section
+}
+
+// ReplayGain is the track gain to the ReplayGain 2.0 reference and what
+// the file would measure after applying it
+type ReplayGain struct {
+	ReferenceLUFS      float64
+	TrackGainDB        float64
+	ResultLUFS         float64
+	ResultTruePeakDBTP float64 // measured true (or sample) peak + gain
+	Clips              bool    // result true peak > 0 dBTP
+}
+
+// RGTrack is one row of the replaygain subcommand: the RG 2.0 track tags
+// for a file and whether they were written into it
+type RGTrack struct {
+	File        string
+	Integrated  float64 `json:",omitempty"`
+	TrackGainDB float64 `json:",omitempty"`
+	TrackPeak   float64 `json:",omitempty"` // linear true peak
+	Written     bool    `json:",omitempty"`
+	Error       string  `json:",omitempty"`
+}
+
+// PlatformTarget is the -target check: what the platform's loudness
+// normalization does to the file (or, for broadcast, how far it is from spec)
+type PlatformTarget struct {
+	Platform           string // -target value
+	Name               string
+	TargetLUFS         float64
+	CeilingDBTP        float64
+	ToleranceLU        *float64 `json:",omitempty"` // broadcast only
+	TruePeakDBTP       float64  // measured true (or sample) peak
+	NeededDB           float64  // target - integrated
+	GainDB             float64  // what the platform applies (0 for broadcast)
+	ResultLUFS         float64
+	ResultTruePeakDBTP float64
+}
+
+// WindowStat is one -astats-window slice of the level envelope
+type WindowStat struct {
+	Time   float64 // window start, seconds
 	PeakDB float64
 	RMSDB  float64
 }
 
+// SectionLoudness is the integrated loudness of one part of the file
+type SectionLoudness struct {
+	Name       string // head|body|tail
+	Start, End float64
+	Integrated *float64
+}
+
+type BandStat struct {
+	Band         Bandspec
+	PeakDB       float64 // float pipeline; may exceed 0 dBFS
+	RMSDB        float64
+	TruePeakDBTP *float64
+	Correlation  *float64 // L/R correlation within the band (stereo only)
+	SideMidDB    *float64 // side RMS minus mid RMS within the band (stereo only)
+}
+
 type StereoStats struct {
 	MidRMS         float64
 	SideRMS        float64
 	SideMidRatioDB float64
 	Correlation    *float64
+	MonoPeakDB     *float64 // peak of L+R summed at unity; > 0 clips in mono
+	BalanceDB      *float64 // left RMS minus right RMS; + leans left
+	Fault          string   `json:",omitempty"` // dual-mono, inverted, left-silent or right-silent
+
+	// correlation over -corr-window windows
+	CorrMin      *float64    `json:",omitempty"`
+	CorrAvg      *float64    `json:",omitempty"`
+	LowCorr      []CorrSpan  `json:",omitempty"` // runs of windows below -corr-threshold
+	CorrTimeline []CorrPoint `json:",omitempty"`
+}
+
+// CorrPoint is the L/R correlation of the window starting at Time
+type CorrPoint struct {
+	Time float64
+	Corr float64
+}
+
+// CorrSpan is a stretch of windows below the correlation threshold
+type CorrSpan struct {
+	Start, End float64
+	Min        float64
+}
+
+// Surround is the multichannel (3+ channel) counterpart of StereoStats;
+// levels are relative to the mean power of the FL/FR pair
+type Surround struct {
+	Layout      string   // as probed; "" if not reported
+	LFEDB       *float64 `json:",omitempty"`
+	CenterDB    *float64 `json:",omitempty"`
+	FrontRearDB *float64 `json:",omitempty"` // front pair minus mean surround (side/back) channel
+	Downmix     *Downmix `json:",omitempty"`
+}
+
+// Downmix measures the Lo/Ro stereo fold-down of a surround file
+type Downmix struct {
+	PeakDB       float64 // unity-gain float sum; > 0 clips
+	TruePeakDBTP *float64
+	Integrated   *float64
+	DeltaLU      *float64 // downmix minus full-mix integrated loudness
+}
+
+// PhaseScope is a Lissajous (L vs R) histogram for goniometer displays
+type PhaseScope struct {
+	Bins     int
+	Samples  int64
+	WidthPct *float64 // side/(mid+side) energy, %
+	Hist     [][]int64
 }
 
 type SpectralStats struct {
@@ -48,6 +293,8 @@ type SpectralStats struct {
 	Spread    *float64
 	Skewness  *float64
 	Kurtosis  *float64
+	TiltDB    *float64 `json:",omitempty"` // -tilt-freq: RMS above minus RMS below the crossover (+ bright, - dark)
+	CutoffHz  *float64 `json:",omitempty"` // lowpass shelf in the long-term spectrum (lossy encoders leave one)
 }
 
 type TempoStats struct {
@@ -78,25 +325,256 @@ type SilenceSpan struct {
 	End   float64
 }
 
+type SegmentInfo struct {
+	Index      int
+	Start, End float64 // seconds in the source
+	Duration   float64
+	Path       string
+	Integrated *float64 `json:",omitempty"` // segment loudness (LUFS/LKFS), nil if not measured
+}
+
+// Chapter is one indexed track of a long recording, in seconds relative to
+// the analyzed range
+type Chapter struct {
+	Number     int
+	Title      string
+	Start, End float64
+}
+
+// TimeRange is the analyzed section of a file, in seconds
+type TimeRange struct{ Start, End float64 }
+
 type Analysis struct {
 	File         string
 	When         string
+	Range        *TimeRange `json:",omitempty"` // -start/-end section; all times are relative to its start
+	Metadata     *Metadata  `json:",omitempty"` // title/artist/... and the raw tag map
 	Probe        ProbeInfo
+	Silent       bool // peak below silentFloorDB; other sections skipped
+	Mono         bool // measured on the mono sum (-mono); Stereo left empty
 	Level        LevelStats
 	Loudness     *LUFS
+	ReplayGain   *ReplayGain       `json:",omitempty"`
+	Target       *PlatformTarget   `json:",omitempty"`
+	Sections     []SectionLoudness `json:",omitempty"` // head/body/tail loudness
+	Structure    []Section         `json:",omitempty"` // sections by level change; see Section
+	Subset       *SubsetStats      `json:",omitempty"`
 	Stereo       StereoStats
+	Surround     *Surround   `json:",omitempty"` // 3+ channels: replaces the stereo figures
+	PhaseScope   *PhaseScope `json:",omitempty"`
 	Spectral     SpectralStats
 	Bands        []BandStat
+	MonoSafety   string `json:",omitempty"` // safe|caution|unsafe, from low-band correlation
 	Tempo        *TempoStats
 	Pitch        *PitchStats
 	Key          *KeyInfo
+	Fingerprint  *Fingerprint `json:",omitempty"` // -fingerprint
+	Resolution   *Resolution  `json:",omitempty"` // claimed vs measured depth and bandwidth, lossless only
+	Hum          *Hum         `json:",omitempty"` // 50/60 Hz mains hum, when detected
+	Defects      *Defects     `json:",omitempty"` // -defects: clicks, dropouts, glitches
+	Content      *Content     `json:",omitempty"` // speech, music or mixed, with a timeline
+	Windows      []WindowStat `json:",omitempty"` // -astats-window envelope
+	Waveform     *Waveform    `json:",omitempty"` // -waveform/-peaks files written with the report
 	Silence      []SilenceSpan
 	SilenceRatio *float64
 	SilenceTotal *float64
-	Notes        []string // warnings/suggestions
+	Decoded      *float64 // seconds actually decoded (vs Probe.Duration)
+	DecodeErrors int
+	SampleRates  []int             `json:",omitempty"` // decoded rates in order, only when they change midstream
+	Notes        []Note            // warnings/suggestions
+	Filters      map[string]string `json:",omitempty"` // stage → exact filter graph / command used
+
+	Elapsed time.Duration `json:"-"` // wall time of analyzeFile, txt footer only
+}
+
+// Waveform names the overview files of one run and their width in pixel
+// columns
+type Waveform struct {
+	PNG   string `json:",omitempty"`
+	Peaks string `json:",omitempty"`
+	Width int
 }
 
 type Diff struct {
 	A, B  *Analysis
 	Delta map[string]float64
 }
+
+// NullTest is B subtracted from A: what is left is everything that differs
+type NullTest struct {
+	A, B           string
+	Aligned        bool
+	OffsetSamples  int // B runs late by this many samples (negative: early)
+	OffsetSec      float64
+	ResidualPeakDB float64
+	ResidualRMSDB  float64
+	ReferenceRMSDB float64 // RMS of A
+	DepthDB        float64 // reference RMS - residual RMS
+	Verdict        string
+	Notes          []Note
+}
+
+// DCFixReport is one fix-dc run: each channel's offset before and after
+type DCFixReport struct {
+	In, Out   string
+	Method    string // shift|highpass
+	Threshold float64
+	Filter    string `json:",omitempty"`
+	Written   bool   // false: no channel over the threshold, no output
+	Channels  []DCFixChannel
+}
+
+type DCFixChannel struct {
+	Channel int
+	Name    string `json:",omitempty"`
+	Before  float64
+	After   *float64 `json:",omitempty"`
+	Fixed   bool
+}
+
+// TrimReport is one trim run: the kept range and how much silence was
+// cut from either end, in seconds of the input
+type TrimReport struct {
+	In, Out     string
+	ThresholdDB float64
+	PadSec      float64
+	FadeSec     float64
+	Duration    float64  // decoded input length
+	Start, End  float64  // kept range
+	LeadSec     float64  // removed at the start
+	TailSec     float64  // removed at the end
+	Filter      string   `json:",omitempty"`
+	Written     bool     // false: no leading or trailing silence, no output
+	Kept        *float64 `json:",omitempty"` // probed length of the output
+}
+
+// Hum is a comb of mains lines: the fundamental and the harmonics that
+// stand out of the spectrum around them
+type Hum struct {
+	FundamentalHz float64 // 50 or 60
+	LevelDB       float64 // strongest line, sine RMS in dBFS
+	Harmonics     []HumHarmonic
+	Notch         string // ffmpeg -af chain removing every line
+}
+
+type HumHarmonic struct {
+	Hz           float64
+	LevelDB      float64 // sine RMS in dBFS
+	ProminenceDB float64 // above the median spectrum a few Hz either side
+}
+
+// Defects is the -defects scan: counts of every event found and the first
+// defectMaxList of them in time order
+type Defects struct {
+	Clicks   int
+	Dropouts int
+	Glitches int
+	Events   []Defect
+
+	clickEvents int // clicks merged across channels, for -clicks
+}
+
+// Defect is one time-localized fault on one channel
+type Defect struct {
+	Time     float64 // seconds
+	Channel  int     // 1-based
+	Kind     string  // click|dropout|glitch
+	Severity Severity
+	Duration float64 `json:",omitempty"` // dropout/glitch length, seconds
+	LevelDB  float64 // click: spike size dBFS; dropout/glitch: RMS dBFS just before
+}
+
+// Content labels the file speech, music or mixed from its 1 s segments
+type Content struct {
+	Label     string  // speech|music|mixed
+	SpeechPct float64 // share of the non-silent segments labelled speech
+	Spans     []ContentSpan
+}
+
+// ContentSpan is a run of segments with one label (speech|music|silence)
+type ContentSpan struct {
+	Start, End float64
+	Label      string
+}
+
+// DeclipReport is one declip run: where adeclip was applied and the
+// clipping and peak figures of the input and the repaired output. After's
+// levels have HeadroomDB added back, so the two compare at the same gain.
+type DeclipReport struct {
+	In, Out    string
+	Filter     string
+	Sections   []TimeRange
+	HeadroomDB float64 // gain taken off before declipping
+	CeilingDB  float64 // input peak; samples at it count as clipped
+	Before     DeclipStats
+	After      DeclipStats
+}
+
+type DeclipStats struct {
+	PeakDB       float64
+	TruePeakDBTP *float64
+	ClipSamples  *int64
+	Clicks       *int64 `json:",omitempty"` // -clicks
+}
+
+type MetricSpread struct {
+	Name      string
+	Mean, Std float64
+	Min, Max  float64
+	Range     float64 // max - min (jitter)
+}
+
+// FileError is a file a batch couldn't analyze, and why
+type FileError struct {
+	File  string
+	Error string
+}
+
+type Stability struct {
+	Files   []string
+	Rows    []SummaryRow
+	Metrics []MetricSpread
+	Failed  []FileError `json:",omitempty"` // batch: files that failed, in input order
+}
+
+// AlbumTrack is one track's line in an album report
+type AlbumTrack struct {
+	File         string
+	Duration     float64
+	Integrated   *float64 // LUFS
+	TruePeakDBTP *float64
+	DeviationLU  *float64 // track integrated - album integrated
+	TrackGainDB  *float64 // ReplayGain 2.0
+}
+
+type AlbumReport struct {
+	When         string
+	Tracks       []AlbumTrack
+	Integrated   *float64 // LUFS over all tracks back to back
+	Range        *float64 // LRA over all tracks
+	TruePeakDBTP *float64 // loudest track
+	AlbumGainDB  *float64 // ReplayGain 2.0 album gain
+	SpreadLU     *float64 // loudest - quietest track
+	Notes        []Note
+}
+
+type StemContribution struct {
+	File        string
+	Integrated  *float64 // LUFS
+	RMSDB       float64
+	EnergyShare float64  // 0..1 of summed stem energy
+	RelativeLU  *float64 // stem integrated - sum integrated
+}
+
+type StemsReport struct {
+	Dir           string
+	Mix           string
+	When          string
+	Stems         []StemContribution
+	SumLUFS       *float64
+	MixLUFS       *float64
+	DeviationLU   *float64 // sum - mix
+	ResidualRMSDB *float64 // RMS of sum - mix
+	ResidualRelDB *float64 // residual relative to mix RMS
+	Notes         []Note
+}
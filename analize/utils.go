@@ -5,27 +5,92 @@ import (
 	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
+// atExit holds cleanups for fail to run, newest first: os.Exit skips the
+// deferred ones
+var atExit []func()
+
 func fail(fmtStr string, a ...any) {
-	fmt.Fprintf(os.Stderr, "[-] "+fmtStr+"\n", a...)
+	logTo(os.Stderr, "[-] "+fmtStr, a...)
+	for i := len(atExit) - 1; i >= 0; i-- {
+		atExit[i]()
+	}
 	os.Exit(1)
 }
 
 func mustHave(bin string) error { _, err := exec.LookPath(bin); return err }
 
+// ensureParent creates the directory an output path lives in, so
+// "-o results/run1/out.json" works without a prior mkdir
+func ensureParent(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create output directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// writeFile creates missing parent directories and replaces path
+// atomically: data goes to a temp file in the same directory which is then
+// renamed over path, so readers never see a half-written report
+func writeFile(path string, data []byte) error {
+	if err := ensureParent(path); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr == nil {
+		werr = cerr
+	}
+	if werr == nil {
+		werr = os.Chmod(tmp.Name(), 0644)
+	}
+	if werr == nil {
+		werr = os.Rename(tmp.Name(), path)
+	}
+	if werr != nil {
+		os.Remove(tmp.Name())
+	}
+	return werr
+}
+
 func runCmd(bin string, args ...string) (string, error) {
 	cmd := exec.Command(bin, args...)
 	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	acquireProc()
+	defer releaseProc()
 	out, err := cmd.CombinedOutput()
 	return string(out), err
 }
 
-func parseInt(s string) int       { i, _ := strconv.Atoi(strings.TrimSpace(s)); return i }
-func parseInt64(s string) int64   { v, _ := strconv.ParseInt(strings.TrimSpace(s), 10, 64); return v }
+func parseInt(s string) int     { i, _ := strconv.Atoi(strings.TrimSpace(s)); return i }
+func parseInt64(s string) int64 { v, _ := strconv.ParseInt(strings.TrimSpace(s), 10, 64); return v }
+func joinInts(xs []int, sep string) string {
+	parts := make([]string, len(xs))
+	for i, x := range xs {
+		parts[i] = strconv.Itoa(x)
+	}
+	return strings.Join(parts, sep)
+}
+
 func parseFloat(s string) float64 { f, _ := strconv.ParseFloat(strings.TrimSpace(s), 64); return f }
+
+// parseDB is parseFloat that understands ffmpeg's "-inf" for digital silence
+func parseDB(s string) float64 {
+	if strings.TrimSpace(s) == "-inf" {
+		return math.Inf(-1)
+	}
+	return parseFloat(s)
+}
+
 func clamp01(x float64) float64 {
 	if x < 0 {
 		return 0
@@ -71,3 +136,16 @@ func derefFloat(p *float64) float64 {
 	}
 	return *p
 }
+
+var audioExts = map[string]bool{
+	".wav": true, ".flac": true, ".mp3": true, ".m4a": true, ".aac": true,
+	".ogg": true, ".opus": true, ".aif": true, ".aiff": true, ".wv": true,
+}
+
+func isAudioFile(p string) bool { return audioExts[strings.ToLower(filepath.Ext(p))] }
+
+func sameFile(a, b string) bool {
+	fa, err1 := os.Stat(a)
+	fb, err2 := os.Stat(b)
+	return err1 == nil && err2 == nil && os.SameFile(fa, fb)
+}
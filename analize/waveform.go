@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os/exec"
+)
+
+// WavePeaks is a min/max overview in audiowaveform's JSON format (version 2,
+// 16-bit), so web players such as peaks.js can draw it without the audio
+type WavePeaks struct {
+	Version         int   `json:"version"`
+	Channels        int   `json:"channels"`
+	SampleRate      int   `json:"sample_rate"`
+	SamplesPerPixel int   `json:"samples_per_pixel"`
+	Bits            int   `json:"bits"`
+	Length          int   `json:"length"`
+	Data            []int `json:"data"` // min, max per pixel column
+}
+
+// wavePeaks decodes the mono downmix and keeps the min and max of every
+// block of samples that lands in one of width columns
+func wavePeaks(cfg *Config, in string, sampleRate int, duration float64, width int) (*WavePeaks, error) {
+	if duration <= 0 || sampleRate <= 0 {
+		return nil, fmt.Errorf("unknown duration or sample rate; can't size %d columns", width)
+	}
+	spp := max(1, int(math.Ceil(duration*float64(sampleRate)/float64(width))))
+	cmd := exec.Command(cfg.FFmpegBin, rangeArgs(cfg, []string{"-hide_banner", "-nostats", "-loglevel", "error",
+		"-i", in, "-vn", "-map", streamSpec(cfg), "-ac", "1", "-f", "f32le", "-acodec", "pcm_f32le", "-"})...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	acquireProc()
+	defer releaseProc()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	pk := &WavePeaks{Version: 2, Channels: 1, SampleRate: sampleRate, SamplesPerPixel: spp, Bits: 16}
+	scale := func(v float32) int { return int(math.Round(math.Max(-1, math.Min(1, float64(v))) * 32767)) }
+	lo, hi, n := float32(0), float32(0), 0
+	flush := func() {
+		pk.Data = append(pk.Data, scale(lo), scale(hi))
+		lo, hi, n = 0, 0, 0
+	}
+	r := bufio.NewReaderSize(stdout, 1<<16)
+	var frame [4]byte
+	for {
+		if _, err := io.ReadFull(r, frame[:]); err != nil {
+			break
+		}
+		v := math.Float32frombits(binary.LittleEndian.Uint32(frame[:]))
+		if n == 0 || v < lo {
+			lo = v
+		}
+		if n == 0 || v > hi {
+			hi = v
+		}
+		if n++; n == spp {
+			flush()
+		}
+	}
+	if n > 0 {
+		flush()
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg decode: %w", err)
+	}
+	if len(pk.Data) == 0 {
+		return nil, fmt.Errorf("no samples decoded")
+	}
+	pk.Length = len(pk.Data) / 2
+	return pk, nil
+}
+
+func writePeaksJSON(path string, pk *WavePeaks) error {
+	buf, err := json.Marshal(pk)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, buf)
+}
+
+var (
+	waveBG   = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	waveFG   = color.RGBA{0x34, 0x65, 0xa4, 0xff}
+	waveAxis = color.RGBA{0xc0, 0xc0, 0xc0, 0xff}
+)
+
+// writeWaveformPNG draws one vertical min-max line per peaks column,
+// full scale filling the height
+func writeWaveformPNG(path string, pk *WavePeaks, height int) error {
+	w := pk.Length
+	img := image.NewRGBA(image.Rect(0, 0, w, height))
+	mid := height / 2
+	for x := 0; x < w; x++ {
+		for y := 0; y < height; y++ {
+			img.SetRGBA(x, y, waveBG)
+		}
+		img.SetRGBA(x, mid, waveAxis)
+		y := func(v int) int { return max(0, min(height-1, mid-v*(height/2)/32767)) }
+		top, bot := y(pk.Data[2*x+1]), y(pk.Data[2*x])
+		for yy := top; yy <= bot; yy++ {
+			img.SetRGBA(x, yy, waveFG)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return writeFile(path, buf.Bytes())
+}
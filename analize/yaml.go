@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// YAML output is the JSON report re-emitted as block YAML, so both formats
+// always carry the same field names and values. Strings stay JSON-quoted,
+// which is valid YAML; lists of scalars use flow style ([1, 2, 3]). Floats
+// always carry a decimal point, and keys a YAML 1.1 parser would read as a
+// boolean or null are quoted, so 1.1 and 1.2 parsers load the same document.
+
+type yamlNode struct {
+	keys   []string    // object keys in order (nil for arrays/scalars)
+	items  []*yamlNode // object values or array elements
+	scalar string      // JSON text of a scalar
+	isObj  bool
+	isArr  bool
+}
+
+func toYAML(v any) (string, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	n, err := readYAMLNode(dec)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	writeYAMLNode(&b, n, 0)
+	return b.String(), nil
+}
+
+func readYAMLNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		n := &yamlNode{isObj: t == '{', isArr: t == '['}
+		for dec.More() {
+			if n.isObj {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, k.(string))
+			}
+			c, err := readYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, c)
+		}
+		_, err := dec.Token() // closing delim
+		return n, err
+	case string:
+		q, _ := json.Marshal(t)
+		return &yamlNode{scalar: string(q)}, nil
+	case nil:
+		return &yamlNode{scalar: "null"}, nil
+	case bool:
+		if t {
+			return &yamlNode{scalar: "true"}, nil
+		}
+		return &yamlNode{scalar: "false"}, nil
+	case json.Number:
+		return &yamlNode{scalar: yamlNumber(t.String())}, nil
+	}
+	return nil, fmt.Errorf("yaml: unsupported JSON token %v", tok)
+}
+
+// yamlNumber keeps integers as they are and writes floats in plain
+// notation with a decimal point ("1e-07" would be a string to YAML 1.1);
+// very large or small ones use an exponent, still with the point
+func yamlNumber(s string) string {
+	if !strings.ContainsAny(s, ".eE") {
+		return s
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	f := strconv.FormatFloat(v, 'f', -1, 64)
+	if len(f) > 21 {
+		f = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	if !strings.Contains(f, ".") {
+		if i := strings.IndexByte(f, 'e'); i >= 0 {
+			return f[:i] + ".0" + f[i:]
+		}
+		return f + ".0"
+	}
+	return f
+}
+
+var plainYAMLKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_\-.]*$`)
+
+// YAML 1.1 reads these as booleans or null in any of their cases
+var yaml11Words = map[string]bool{"y": true, "yes": true, "n": true, "no": true, "true": true, "false": true, "on": true, "off": true, "null": true}
+
+func yamlKey(k string) string {
+	if plainYAMLKey.MatchString(k) && !yaml11Words[strings.ToLower(k)] {
+		return k
+	}
+	q, _ := json.Marshal(k)
+	return string(q)
+}
+
+// inline returns the one-line form of n, ok=false if it needs a block
+func (n *yamlNode) inline() (string, bool) {
+	switch {
+	case n.isObj && len(n.items) == 0:
+		return "{}", true
+	case n.isArr:
+		parts := make([]string, len(n.items))
+		for i, c := range n.items {
+			if c.isObj || c.isArr {
+				return "", false
+			}
+			parts[i] = c.scalar
+		}
+		return "[" + strings.Join(parts, ", ") + "]", true
+	case n.isObj:
+		return "", false
+	}
+	return n.scalar, true
+}
+
+func writeYAMLNode(b *strings.Builder, n *yamlNode, indent int) {
+	pad := strings.Repeat(" ", indent)
+	if s, ok := n.inline(); ok {
+		b.WriteString(pad + s + "\n")
+		return
+	}
+	if n.isObj {
+		for i, k := range n.keys {
+			c := n.items[i]
+			if s, ok := c.inline(); ok {
+				b.WriteString(pad + yamlKey(k) + ": " + s + "\n")
+				continue
+			}
+			b.WriteString(pad + yamlKey(k) + ":\n")
+			writeYAMLNode(b, c, indent+2)
+		}
+		return
+	}
+	// block sequence: the item's first line takes the "- " marker
+	for _, c := range n.items {
+		var sub strings.Builder
+		writeYAMLNode(&sub, c, indent+2)
+		s := sub.String()
+		b.WriteString(pad + "- " + s[indent+2:])
+	}
+}
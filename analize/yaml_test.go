@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestYAMLNumber(t *testing.T) {
+	for in, want := range map[string]string{
+		"44100": "44100", "-3.25": "-3.25", "1e-07": "0.0000001", "2.5e-05": "0.000025",
+		"1e+21": "1.0e+21", "1.5e+300": "1.5e+300", "-1e-300": "-1.0e-300",
+	} {
+		if got := yamlNumber(in); got != want {
+			t.Errorf("%s: got %s, want %s", in, got, want)
+		}
+	}
+}
+
+// tag maps can hold keys a YAML 1.1 parser would turn into booleans
+func TestYAMLKeysAndErrors(t *testing.T) {
+	y, err := toYAML(map[string]any{"on": 1, "No": 2, "title": "x", "rate": 1e-7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"No": 2`, `"on": 1`, `title: "x"`, `rate: 0.0000001`} {
+		if !strings.Contains(y, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, y)
+		}
+	}
+	if _, err := toYAML(map[string]float64{"x": math.NaN()}); err == nil {
+		t.Error("NaN: want an error")
+	}
+}
@@ -6,11 +6,26 @@ import (
 )
 
 type cfg struct {
-	engine    string
-	outFormat string
-	bitrate   string
-	ffmpegBin string
-	demucsBin string
+	engine       string
+	outFormat    string
+	container    string // files|single
+	skipExisting bool   // keep stems already newer than the input
+	bitrate      string
+	ffmpegBin    string
+	demucsBin    string
+	demucsModel  string
+	demucsNames  map[string]string // our stem -> demucs file basename overrides
+
+	// sampler tagging
+	tag        bool // run analize on stems and write BPM/key sidecars
+	analizeBin string
+
+	qualityCheck bool   // score stems for cross-bleed after separation
+	manifestPath string // -manifest: JSON list of every output written
+
+	// resampling
+	resampler  string // ""|swr|soxr
+	sampleRate int    // 0=keep
 
 	// stem selection
 	stemsCSV  string
@@ -18,6 +33,10 @@ type cfg struct {
 	wantDrum  bool
 	wantMusic bool
 	wantVox   bool
+	wantGtr   bool // demucs 6-stem models only
+	wantPiano bool
+	badStems  []string // requested names no engine knows
+	badNames  []string // malformed -demucs-names entries
 
 	// preset & gains
 	preset      string // soft|medium|hard
@@ -27,6 +46,7 @@ type cfg struct {
 	gainDrumDB  float64
 	gainMusicDB float64
 	gainVocalDB float64
+	gainCurves  gainCurves // per-stem time-varying gain (-gain-curve)
 
 	// cutoff ranges (will be overridden by preset unless user changes)
 	// bass
@@ -49,12 +69,22 @@ func parseFlags() *cfg {
 	// engine / io
 	flag.StringVar(&c.engine, "engine", "ffmpeg", "separation engine: ffmpeg|demucs")
 	flag.StringVar(&c.outFormat, "out-format", "wav", "output format/extension (wav|mp3|flac|m4a|...)")
+	flag.StringVar(&c.container, "container", "files", "files: one file per stem | single: one multichannel wav / multi-track mka")
+	flag.BoolVar(&c.skipExisting, "skip-existing", false, "skip stems whose output already exists and is newer than the input")
 	flag.StringVar(&c.bitrate, "bitrate", "320k", "bitrate for lossy formats (mp3/aac)")
 	flag.StringVar(&c.ffmpegBin, "ffmpeg", "ffmpeg", "path to ffmpeg")
 	flag.StringVar(&c.demucsBin, "demucs", "demucs", "path to demucs")
+	flag.StringVar(&c.demucsModel, "demucs-model", "htdemucs", "demucs model (-n); htdemucs_6s adds guitar,piano")
+	namesCSV := flag.String("demucs-names", "", "override demucs stem file basenames, stem=name,... (e.g. music=no_vocals for --two-stems output)")
+	flag.BoolVar(&c.tag, "tag", false, "write <stem>.json with BPM (drums) / key (bass, music) via analize")
+	flag.StringVar(&c.analizeBin, "analize", "analize", "path to analize (for -tag)")
+	flag.BoolVar(&c.qualityCheck, "quality-check", false, "estimate per-stem separation quality (energy outside the stem's expected bands, and in other stems' bands relative to them)")
+	flag.StringVar(&c.manifestPath, "manifest", "", "write a JSON manifest of every output (stem, path, format, duration) to this path")
+	flag.StringVar(&c.resampler, "resampler", "", "resampler engine: swr|soxr (default: ffmpeg's)")
+	flag.IntVar(&c.sampleRate, "sample-rate", 0, "output sample rate Hz (0=keep)")
 
 	// stem selection
-	flag.StringVar(&c.stemsCSV, "stems", "bass,drums,music,vocal", "comma list: bass,drums,music,vocal")
+	flag.StringVar(&c.stemsCSV, "stems", "bass,drums,music,vocal", "comma list: bass,drums,music,vocal (+guitar,piano with a 6-stem demucs model)")
 
 	// preset & gains
 	flag.StringVar(&c.preset, "preset", "hard", "split preset: soft|medium|hard")
@@ -65,6 +95,9 @@ func parseFlags() *cfg {
 	flag.Float64Var(&c.gainMusicDB, "gain-music", 4.0, "post-gain for music stem (dB)")
 	flag.Float64Var(&c.gainVocalDB, "gain-vocal", 4.0, "post-gain for vocal stem (dB)")
 
+	c.gainCurves = gainCurves{}
+	flag.Var(c.gainCurves, "gain-curve", "time-varying stem gain, stem:sec=dB,sec=dB,... (repeatable; linear in dB between points)")
+
 	// defaults (will be overridden by preset)
 	flag.Float64Var(&c.bassHP, "bass-hp", 30, "bass highpass Hz")
 	flag.Float64Var(&c.bassLP, "bass-lp", 180, "bass lowpass Hz")
@@ -77,24 +110,45 @@ func parseFlags() *cfg {
 	flag.Float64Var(&c.vocalMid, "vocal-mid", 0.95, "0..1 mid (center) level for vocals (stereotools)")
 
 	flag.Parse()
+	c.resampler = strings.ToLower(strings.TrimSpace(c.resampler))
+	c.container = strings.ToLower(c.container)
+	c.outFormat = strings.ToLower(c.outFormat)
 
 	// normalize stems
 	want := map[string]*bool{
-		"bass":  &c.wantBass,
-		"drums": &c.wantDrum,
-		"music": &c.wantMusic,
-		"vocal": &c.wantVox,
+		"bass":   &c.wantBass,
+		"drums":  &c.wantDrum,
+		"music":  &c.wantMusic,
+		"vocal":  &c.wantVox,
+		"guitar": &c.wantGtr,
+		"piano":  &c.wantPiano,
 	}
 	for _, s := range strings.Split(c.stemsCSV, ",") {
 		s = strings.ToLower(strings.TrimSpace(s))
 		if p, ok := want[s]; ok {
 			*p = true
+		} else if s != "" {
+			c.badStems = append(c.badStems, s)
 		}
 	}
-	if !c.wantBass && !c.wantDrum && !c.wantMusic && !c.wantVox {
+	if !c.wantBass && !c.wantDrum && !c.wantMusic && !c.wantVox && !c.wantGtr && !c.wantPiano {
 		c.wantBass, c.wantDrum, c.wantMusic, c.wantVox = true, true, true, true
 	}
 
+	// demucs file names: defaults, then overrides
+	c.demucsNames = map[string]string{"bass": "bass", "drums": "drums", "vocal": "vocals", "music": "other", "guitar": "guitar", "piano": "piano"}
+	for _, kv := range strings.Split(*namesCSV, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		k = strings.ToLower(strings.TrimSpace(k))
+		if _, known := want[k]; !ok || !known || strings.TrimSpace(v) == "" {
+			if kv = strings.TrimSpace(kv); kv != "" {
+				c.badNames = append(c.badNames, kv)
+			}
+			continue
+		}
+		c.demucsNames[k] = strings.TrimSpace(v)
+	}
+
 	// preset shaping (unless user overrides via flags after; these are just defaults we already set)
 	switch strings.ToLower(c.preset) {
 	case "soft":
@@ -118,6 +172,20 @@ func parseFlags() *cfg {
 	return c
 }
 
+// requestedStems lists the selected stems in output order
+func requestedStems(c *cfg) []string {
+	var req []string
+	for _, s := range []struct {
+		name string
+		ok   bool
+	}{{"bass", c.wantBass}, {"drums", c.wantDrum}, {"vocal", c.wantVox}, {"music", c.wantMusic}, {"guitar", c.wantGtr}, {"piano", c.wantPiano}} {
+		if s.ok {
+			req = append(req, s.name)
+		}
+	}
+	return req
+}
+
 func clamp01(v float64) float64 {
 	if v < 0 {
 		return 0
@@ -5,52 +5,155 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
-func runDemucs(c *cfg, in string) error {
+// stems each known demucs model writes (our names; music = demucs "other")
+var demucsModelStems = map[string][]string{
+	"htdemucs":    {"bass", "drums", "vocal", "music"},
+	"htdemucs_ft": {"bass", "drums", "vocal", "music"},
+	"hdemucs_mmi": {"bass", "drums", "vocal", "music"},
+	"mdx":         {"bass", "drums", "vocal", "music"},
+	"mdx_extra":   {"bass", "drums", "vocal", "music"},
+	"mdx_q":       {"bass", "drums", "vocal", "music"},
+	"mdx_extra_q": {"bass", "drums", "vocal", "music"},
+	"htdemucs_6s": {"bass", "drums", "vocal", "music", "guitar", "piano"},
+}
+
+// validateStems checks the requested stems against what the engine/model can
+// produce, so a missing stem fails now rather than after a long separation
+func validateStems(c *cfg) error {
+	if len(c.badStems) > 0 {
+		return fmt.Errorf("unknown stem(s): %s", strings.Join(c.badStems, ","))
+	}
+	if len(c.badNames) > 0 {
+		return fmt.Errorf("bad -demucs-names entry: %s (want stem=name)", strings.Join(c.badNames, ","))
+	}
+	req := requestedStems(c)
+	avail := []string{"bass", "drums", "vocal", "music"} // ffmpeg engine
+	if c.engine == "demucs" {
+		m, ok := demucsModelStems[c.demucsModel]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "[warn] unknown demucs model %q; can't check its stems up front\n", c.demucsModel)
+			return nil
+		}
+		avail = m
+	}
+	var missing []string
+	for _, r := range req {
+		if !slices.Contains(avail, r) {
+			missing = append(missing, r)
+		}
+	}
+	if len(missing) > 0 {
+		src := "the ffmpeg engine"
+		if c.engine == "demucs" {
+			src = "demucs model " + c.demucsModel
+		}
+		return fmt.Errorf("%s can't produce %s (available: %s)", src, strings.Join(missing, ","), strings.Join(avail, ","))
+	}
+	return nil
+}
+
+func runDemucs(c *cfg, in string) ([]stemOut, error) {
 	if err := mustHave(c.demucsBin); err != nil {
-		return fmt.Errorf("demucs not found in PATH (or via --demucs): %w", err)
+		return nil, fmt.Errorf("demucs not found in PATH (or via --demucs): %w", err)
+	}
+	base := baseNoExt(in)
+	type m struct {
+		name, dem, ours string
+		ok              bool
+	}
+	var mappings []m
+	for _, s := range []struct {
+		name string
+		ok   bool
+	}{{"bass", c.wantBass}, {"drums", c.wantDrum}, {"vocal", c.wantVox}, {"music", c.wantMusic}, {"guitar", c.wantGtr}, {"piano", c.wantPiano}} {
+		mappings = append(mappings, m{s.name, c.demucsNames[s.name], base + "-" + s.name + "." + c.outFormat, s.ok})
+	}
+	skip := func(mm m) bool { return c.skipExisting && upToDate(mm.ours, in) }
+
+	// demucs is the expensive part; only run it if some stem is missing
+	pending := false
+	for _, mm := range mappings {
+		pending = pending || (mm.ok && !skip(mm))
 	}
-	cmd := exec.Command(c.demucsBin, "-n", "1", "-o", "demucs_out", in)
+	if !pending {
+		var outs []stemOut
+		for _, mm := range mappings {
+			if mm.ok {
+				fmt.Printf("[+] kept %s (up to date)\n", mm.ours)
+				outs = append(outs, stemOut{mm.name, mm.ours})
+			}
+		}
+		return outs, nil
+	}
+
+	cmd := exec.Command(c.demucsBin, "-n", c.demucsModel, "-o", "demucs_out", in)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
-		return err
+		return nil, err
 	}
 
-	base := baseNoExt(in)
 	outRoot := "demucs_out"
 	modelDir, err := findSingleChildDir(outRoot)
 	if err != nil {
-		return fmt.Errorf("demucs output not found: %w", err)
+		return nil, fmt.Errorf("demucs output not found: %w", err)
 	}
 	trackDir, err := findSingleChildDir(filepath.Join(outRoot, modelDir))
 	if err != nil {
-		return fmt.Errorf("demucs track dir not found: %w", err)
-	}
-	type m struct {
-		dem, ours string
-		ok        bool
-	}
-	mappings := []m{
-		{"bass.wav", base + "-bass." + c.outFormat, c.wantBass},
-		{"drums.wav", base + "-drums." + c.outFormat, c.wantDrum},
-		{"vocals.wav", base + "-vocal." + c.outFormat, c.wantVox},
-		{"other.wav", base + "-music." + c.outFormat, c.wantMusic},
+		return nil, fmt.Errorf("demucs track dir not found: %w", err)
 	}
+	var outs []stemOut
 	for _, mm := range mappings {
 		if !mm.ok {
 			continue
 		}
-		src := filepath.Join(outRoot, modelDir, trackDir, mm.dem)
-		if _, statErr := os.Stat(src); statErr != nil {
-			fmt.Fprintf(os.Stderr, "[warn] missing demucs stem: %s\n", mm.dem)
+		if skip(mm) {
+			fmt.Printf("[+] kept %s (up to date)\n", mm.ours)
+			outs = append(outs, stemOut{mm.name, mm.ours})
 			continue
 		}
-		if err := transcode(c, src, mm.ours); err != nil {
-			return fmt.Errorf("transcode %s -> %s: %w", mm.dem, mm.ours, err)
+		dir := filepath.Join(outRoot, modelDir, trackDir)
+		src, err := findStemFile(dir, mm.dem)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[warn] missing demucs stem %s for %s: %v (set -demucs-names %s=<name>)\n", mm.dem, mm.name, err, mm.name)
+			continue
+		}
+		if err := transcode(c, src, gainCurveFilter(c, mm.name), mm.ours); err != nil {
+			return outs, fmt.Errorf("transcode %s -> %s: %w", filepath.Base(src), mm.ours, err)
 		}
 		fmt.Printf("[+] wrote %s\n", mm.ours)
+		outs = append(outs, stemOut{mm.name, mm.ours})
 	}
-	return nil
+	return outs, nil
+}
+
+// findStemFile finds <name>.* in dir, so stems demucs wrote as flac/mp3
+// (--flac, --mp3) are picked up like wav; wav wins if several exist. The
+// dir is listed rather than globbed: it is named after the input, which
+// may hold glob metacharacters ("Song [Official Audio]").
+func findStemFile(dir, name string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var matches, have []string
+	for _, e := range entries {
+		have = append(have, e.Name())
+		if !e.IsDir() && strings.HasPrefix(e.Name(), name+".") {
+			matches = append(matches, filepath.Join(dir, e.Name()))
+		}
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no %s.* (found: %s)", name, strings.Join(have, ", "))
+	}
+	for _, p := range matches {
+		if strings.EqualFold(filepath.Ext(p), ".wav") {
+			return p, nil
+		}
+	}
+	return matches[0], nil
 }
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// demucs names its output dir after the input, brackets and all
+func TestFindStemFileBracketDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Song [Official Audio]")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"vocals.flac", "vocals.wav", "no_vocals.wav", "drums.mp3"} {
+		if err := os.WriteFile(filepath.Join(dir, f), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, want := range map[string]string{"vocals": "vocals.wav", "drums": "drums.mp3"} {
+		got, err := findStemFile(dir, name)
+		if err != nil || got != filepath.Join(dir, want) {
+			t.Errorf("%s: got %q, %v; want %s", name, got, err, want)
+		}
+	}
+	if _, err := findStemFile(dir, "bass"); err == nil {
+		t.Error("bass: want an error")
+	}
+}
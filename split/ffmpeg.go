@@ -8,7 +8,7 @@ import (
 	"strings"
 )
 
-func runFfmpegPseudoStems(c *cfg, in string) error {
+func runFfmpegPseudoStems(c *cfg, in string) ([]stemOut, error) {
 	base := baseNoExt(in)
 
 	pre := preChain(c)
@@ -29,6 +29,7 @@ func runFfmpegPseudoStems(c *cfg, in string) error {
 			"acompressor=threshold=-24dB:ratio=4:attack=8:release=140:makeup=0",
 			"alimiter=limit=0.93",
 			volumeDB(c.gainBassDB),
+			gainCurveFilter(c, "bass"),
 		)
 		jobs = append(jobs, job{"bass", f, base + "-bass." + c.outFormat, true})
 	}
@@ -41,6 +42,7 @@ func runFfmpegPseudoStems(c *cfg, in string) error {
 			"acompressor=threshold=-18dB:ratio=6:attack=4:release=80:knee=2",
 			"alimiter=limit=0.93",
 			volumeDB(c.gainDrumDB),
+			gainCurveFilter(c, "drums"),
 		)
 		jobs = append(jobs, job{"drums", f, base + "-drums." + c.outFormat, true})
 	}
@@ -52,6 +54,7 @@ func runFfmpegPseudoStems(c *cfg, in string) error {
 			fmt.Sprintf("lowpass=f=%g", c.musicLP),
 			"alimiter=limit=0.93",
 			volumeDB(c.gainMusicDB),
+			gainCurveFilter(c, "music"),
 		)
 		jobs = append(jobs, job{"music", f, base + "-music." + c.outFormat, true})
 	}
@@ -64,20 +67,116 @@ func runFfmpegPseudoStems(c *cfg, in string) error {
 			fmt.Sprintf("lowpass=f=%g", c.vocalLP),
 			"alimiter=limit=0.93",
 			volumeDB(c.gainVocalDB),
+			gainCurveFilter(c, "vocal"),
 		)
 		jobs = append(jobs, job{"vocal", f, base + "-vocal." + c.outFormat, true})
 	}
 
+	var outs []stemOut
 	for _, j := range jobs {
 		if !j.ok {
 			continue
 		}
+		if c.skipExisting && upToDate(j.out, in) {
+			fmt.Printf("[+] kept %s (up to date)\n", j.out)
+			outs = append(outs, stemOut{j.name, j.out})
+			continue
+		}
 		if err := ffmpegFilterTo(c, in, j.filter, j.out); err != nil {
-			return fmt.Errorf("creating %s failed: %w", j.out, err)
+			return outs, fmt.Errorf("creating %s failed: %w", j.out, err)
 		}
 		fmt.Printf("[+] wrote %s\n", j.out)
+		outs = append(outs, stemOut{j.name, j.out})
 	}
-	return nil
+	return outs, nil
+}
+
+// muxStems packs rendered stems into one file: a multichannel WAV (stems'
+// channels in order, the map also written to its comment tag) for wav
+// output, otherwise a Matroska audio file with one titled track per stem.
+// The per-stem files are removed afterwards. Returns where each stem went.
+func muxStems(c *cfg, in string, stems []stemOut) (string, []muxedStem, error) {
+	if len(stems) == 0 {
+		return "", nil, fmt.Errorf("no stems to mux")
+	}
+	args := []string{"-y"}
+	for _, s := range stems {
+		args = append(args, "-i", s.path)
+	}
+	out := muxPath(c, in)
+	var layout []muxedStem
+	if c.outFormat == "wav" {
+		chans := make([]int, len(stems))
+		for i, s := range stems {
+			n, err := mediaChannels(c, s.path)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: %w", s.path, err)
+			}
+			chans[i] = n
+		}
+		var comment string
+		layout, comment = channelMap(stems, chans)
+		var f strings.Builder
+		for i := range stems {
+			fmt.Fprintf(&f, "[%d:a]", i)
+		}
+		fmt.Fprintf(&f, "amerge=inputs=%d", len(stems))
+		args = append(args, "-filter_complex", f.String(), "-metadata", "comment="+comment, "-c:a", "pcm_s16le")
+	} else {
+		for i, s := range stems {
+			args = append(args, "-map", fmt.Sprintf("%d:a", i),
+				fmt.Sprintf("-metadata:s:a:%d", i), "title="+s.name,
+				fmt.Sprintf("-metadata:s:a:%d", i), "handler_name="+s.name)
+			layout = append(layout, muxedStem{Stem: s.name})
+		}
+		args = append(args, "-c:a", "copy")
+	}
+	if err := renderAtomic(out, func(tmp string) error { return runFfmpeg(c, append(args, tmp)) }); err != nil {
+		return "", nil, err
+	}
+	for _, s := range stems {
+		os.Remove(s.path)
+	}
+	for _, l := range layout {
+		if l.Channels != nil {
+			fmt.Printf("[i] %s: channels %s\n", l.Stem, channelSpan(l.Channels))
+		}
+	}
+	return out, layout, nil
+}
+
+// muxPath is the -container single output for in
+func muxPath(c *cfg, in string) string {
+	if c.outFormat == "wav" {
+		return baseNoExt(in) + "-stems.wav"
+	}
+	return baseNoExt(in) + "-stems.mka"
+}
+
+// channelMap numbers the merged WAV's channels (from 1) per stem, given
+// each stem's channel count, and spells the map as "bass:1-2,drums:3-4"
+func channelMap(stems []stemOut, chans []int) ([]muxedStem, string) {
+	var layout []muxedStem
+	var spec []string
+	next := 1
+	for i, s := range stems {
+		l := muxedStem{Stem: s.name}
+		for range chans[i] {
+			l.Channels = append(l.Channels, next)
+			next++
+		}
+		layout = append(layout, l)
+		spec = append(spec, s.name+":"+channelSpan(l.Channels))
+	}
+	return layout, strings.Join(spec, ",")
+}
+
+// channelSpan is "3-4" for channels 3 and 4, "3" for just 3
+func channelSpan(ch []int) string {
+	if len(ch) == 1 {
+		return fmt.Sprint(ch[0])
+	}
+	return fmt.Sprintf("%d-%d", ch[0], ch[len(ch)-1])
 }
 
 func preChain(c *cfg) string {
@@ -113,26 +212,40 @@ func chain(filters ...string) string {
 }
 
 func ffmpegFilterTo(c *cfg, in, filter, out string) error {
-	args := []string{"-y", "-i", in, "-vn", "-af", filter}
-	switch strings.ToLower(filepath.Ext(out)) {
-	case ".mp3":
-		args = append(args, "-c:a", "libmp3lame", "-b:a", c.bitrate)
-	case ".m4a", ".aac":
-		args = append(args, "-c:a", "aac", "-b:a", c.bitrate)
-	case ".flac":
-		args = append(args, "-c:a", "flac")
-	default: // wav
-		args = append(args, "-c:a", "pcm_s16le")
+	return renderAtomic(out, func(tmp string) error {
+		args := []string{"-y", "-i", in, "-vn", "-af", chain(filter, resampleFilter(c))}
+		return runFfmpeg(c, append(args, outArgs(c, tmp)...))
+	})
+}
+
+func transcode(c *cfg, in, filter, out string) error {
+	return renderAtomic(out, func(tmp string) error {
+		args := []string{"-y", "-i", in, "-vn"}
+		if f := chain(filter, resampleFilter(c)); f != "" {
+			args = append(args, "-af", f)
+		}
+		return runFfmpeg(c, append(args, outArgs(c, tmp)...))
+	})
+}
+
+// resampleFilter selects the resampler engine (e.g. soxr); "" keeps ffmpeg's default
+func resampleFilter(c *cfg) string {
+	if c.resampler == "" {
+		return ""
 	}
-	args = append(args, out)
-	cmd := exec.Command(c.ffmpegBin, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	f := "aresample=resampler=" + c.resampler
+	if c.resampler == "soxr" {
+		f += ":precision=28"
+	}
+	if c.sampleRate > 0 {
+		f += fmt.Sprintf(":osr=%d", c.sampleRate)
+	}
+	return f
 }
 
-func transcode(c *cfg, in, out string) error {
-	args := []string{"-y", "-i", in, "-vn"}
+// codec/rate args by output extension, ending with the output path
+func outArgs(c *cfg, out string) []string {
+	var args []string
 	switch strings.ToLower(filepath.Ext(out)) {
 	case ".mp3":
 		args = append(args, "-c:a", "libmp3lame", "-b:a", c.bitrate)
@@ -140,10 +253,16 @@ func transcode(c *cfg, in, out string) error {
 		args = append(args, "-c:a", "aac", "-b:a", c.bitrate)
 	case ".flac":
 		args = append(args, "-c:a", "flac")
-	default:
+	default: // wav
 		args = append(args, "-c:a", "pcm_s16le")
 	}
-	args = append(args, out)
+	if c.sampleRate > 0 {
+		args = append(args, "-ar", fmt.Sprint(c.sampleRate))
+	}
+	return append(args, out)
+}
+
+func runFfmpeg(c *cfg, args []string) error {
 	cmd := exec.Command(c.ffmpegBin, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
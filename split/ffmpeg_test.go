@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+// stems take consecutive WAV channels in order, mono stems one each
+func TestChannelMap(t *testing.T) {
+	stems := []stemOut{{"bass", "b.wav"}, {"drums", "d.wav"}, {"vocal", "v.wav"}}
+	layout, comment := channelMap(stems, []int{2, 2, 1})
+	if comment != "bass:1-2,drums:3-4,vocal:5" {
+		t.Errorf("comment %q", comment)
+	}
+	if len(layout) != 3 || layout[1].Stem != "drums" || len(layout[1].Channels) != 2 || layout[1].Channels[0] != 3 {
+		t.Errorf("layout %+v", layout)
+	}
+}
+
+func TestParseChannels(t *testing.T) {
+	for banner, want := range map[string]int{
+		"  Stream #0:0: Audio: pcm_s16le ([1][0][0][0] / 0x0001), 44100 Hz, stereo, s16, 1411 kb/s\n": 2,
+		"  Stream #0:0: Audio: flac, 48000 Hz, mono, s16\n":                                           1,
+		"  Stream #0:0: Audio: pcm_s24le, 48000 Hz, 5.1(side), s32 (24 bit)\n":                        6,
+		"  Stream #0:0: Audio: pcm_s16le, 48000 Hz, 10 channels, s16\n":                               10,
+	} {
+		if n, err := parseChannels(banner); err != nil || n != want {
+			t.Errorf("%q: got %d (%v), want %d", banner, n, err, want)
+		}
+	}
+	if _, err := parseChannels("Output #0, null"); err == nil {
+		t.Error("no audio stream: want an error")
+	}
+}
+
+// a kept WAV's map comes back from its comment, an mka's from track titles
+func TestParseMuxLayout(t *testing.T) {
+	wav := "Input #0, wav, from 'song-stems.wav':\n  Metadata:\n    comment         : bass:1-2,vocal:3\n" +
+		"  Stream #0:0: Audio: pcm_s16le, 44100 Hz, 3 channels, s16, 2116 kb/s\n"
+	got := parseMuxLayout(wav)
+	if len(got) != 2 || got[0].Stem != "bass" || len(got[0].Channels) != 2 || got[1].Channels[0] != 3 {
+		t.Errorf("wav: %+v", got)
+	}
+	mka := "Input #0, matroska,webm, from 'song-stems.mka':\n" +
+		"  Stream #0:0: Audio: flac, 44100 Hz, stereo, s16 (default)\n    Metadata:\n      title           : bass\n" +
+		"  Stream #0:1: Audio: flac, 44100 Hz, stereo, s16\n    Metadata:\n      title           : drums\n"
+	got = parseMuxLayout(mka)
+	if len(got) != 2 || got[0].Stem != "bass" || got[1].Stem != "drums" || got[1].Channels != nil {
+		t.Errorf("mka: %+v", got)
+	}
+}
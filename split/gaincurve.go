@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type gainPoint struct{ t, db float64 }
+
+// gainCurves collects repeated -gain-curve stem:time=db,... flags
+type gainCurves map[string][]gainPoint
+
+func (g gainCurves) String() string {
+	var parts []string
+	for stem, pts := range g {
+		var kv []string
+		for _, p := range pts {
+			kv = append(kv, fmt.Sprintf("%g=%g", p.t, p.db))
+		}
+		parts = append(parts, stem+":"+strings.Join(kv, ","))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+func (g gainCurves) Set(v string) error {
+	stem, spec, ok := strings.Cut(v, ":")
+	stem = strings.ToLower(strings.TrimSpace(stem))
+	if !ok || stem == "" {
+		return fmt.Errorf("want stem:time=db,..., got %q", v)
+	}
+	var pts []gainPoint
+	for _, kv := range strings.Split(spec, ",") {
+		ts, ds, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			return fmt.Errorf("bad gain point %q (want time=db)", kv)
+		}
+		t, err1 := strconv.ParseFloat(strings.TrimSpace(ts), 64)
+		db, err2 := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(ds, "dB")), 64)
+		if err1 != nil || err2 != nil || t < 0 {
+			return fmt.Errorf("bad gain point %q", kv)
+		}
+		pts = append(pts, gainPoint{t, db})
+	}
+	sort.Slice(pts, func(i, j int) bool { return pts[i].t < pts[j].t })
+	g[stem] = pts
+	return nil
+}
+
+// gainCurveFilter turns a stem's points into a time-varying volume filter:
+// dB is interpolated linearly between points and held flat before the first
+// and after the last. "" when the stem has no curve.
+func gainCurveFilter(c *cfg, stem string) string {
+	pts := c.gainCurves[stem]
+	if len(pts) == 0 {
+		return ""
+	}
+	expr := strconv.FormatFloat(pts[len(pts)-1].db, 'g', -1, 64)
+	for i := len(pts) - 1; i > 0; i-- {
+		a, b := pts[i-1], pts[i]
+		seg := fmt.Sprintf("%g+(%g)*(t-%g)/%g", a.db, b.db-a.db, a.t, b.t-a.t)
+		if b.t == a.t {
+			seg = fmt.Sprintf("%g", b.db)
+		}
+		expr = fmt.Sprintf("if(lt(t,%g),%s,%s)", b.t, seg, expr)
+	}
+	expr = fmt.Sprintf("if(lt(t,%g),%g,%s)", pts[0].t, pts[0].db, expr)
+	return fmt.Sprintf("volume='pow(10,(%s)/20)':eval=frame", expr)
+}
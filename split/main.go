@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 )
 
@@ -15,17 +16,86 @@ func main() {
 		fail("input not found: %v", err)
 	}
 
+	if err := validateStems(c); err != nil {
+		fail("%v", err)
+	}
+
+	// the stem files of a -container single run are gone once muxed, so
+	// the packed file is what decides whether there is anything to do
+	if c.container == "single" && c.skipExisting && upToDate(muxPath(c, in), in) {
+		keepMuxed(c, in)
+		return
+	}
+
+	var outs []stemOut
+	var err error
 	switch c.engine {
 	case "demucs":
-		if err := runDemucs(c, in); err != nil {
+		if outs, err = runDemucs(c, in); err != nil {
 			fail("demucs engine failed: %v", err)
 		}
 	default:
 		if err := mustHave(c.ffmpegBin); err != nil {
 			fail("ffmpeg not found in PATH (or via --ffmpeg): %v", err)
 		}
-		if err := runFfmpegPseudoStems(c, in); err != nil {
+		if outs, err = runFfmpegPseudoStems(c, in); err != nil {
 			fail("ffmpeg engine failed: %v", err)
 		}
 	}
+
+	m := newManifest(c, in, outs)
+	if c.qualityCheck {
+		m.Quality = checkQuality(c, outs)
+	}
+
+	if c.tag {
+		for _, s := range tagStems(c, outs) {
+			m.add("tags", s.name, s.path)
+		}
+	}
+
+	if c.container == "single" {
+		out, layout, err := muxStems(c, in, outs)
+		if err != nil {
+			fail("mux stems failed: %v", err)
+		}
+		fmt.Printf("[+] wrote %s\n", out)
+		m.muxed(out, layout)
+	}
+
+	if c.manifestPath != "" {
+		if err := m.write(c, c.manifestPath); err != nil {
+			fail("write manifest: %v", err)
+		}
+		fmt.Printf("[+] wrote %s\n", c.manifestPath)
+	}
+}
+
+// keepMuxed finishes a run whose -container single output is up to date:
+// nothing is rendered, and the manifest describes the kept file and the
+// tag sidecars still beside it
+func keepMuxed(c *cfg, in string) {
+	out := muxPath(c, in)
+	fmt.Printf("[+] kept %s (up to date)\n", out)
+	if c.manifestPath == "" {
+		return
+	}
+	layout := muxLayout(c, out)
+	var outs []stemOut
+	for _, l := range layout {
+		outs = append(outs, stemOut{l.Stem, ""})
+	}
+	m := newManifest(c, in, outs)
+	if c.tag {
+		for _, o := range outs {
+			if side := baseNoExt(in) + "-" + o.name + ".json"; upToDate(side, in) {
+				m.add("tags", o.name, side)
+			}
+		}
+	}
+	m.muxed(out, layout)
+	if err := m.write(c, c.manifestPath); err != nil {
+		fail("write manifest: %v", err)
+	}
+	fmt.Printf("[+] wrote %s\n", c.manifestPath)
 }
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// manifest lists everything a split run produced, for orchestrators that
+// would otherwise glob the output directory
+type manifest struct {
+	Input   string          `json:"input"`
+	Engine  string          `json:"engine"`
+	Outputs []manifestEntry `json:"outputs"`
+	Missing []string        `json:"missing,omitempty"` // requested stems not produced
+	Quality []stemQuality   `json:"quality,omitempty"`
+}
+
+type manifestEntry struct {
+	Kind     string      `json:"kind"` // stem|tags|mux
+	Stem     string      `json:"stem,omitempty"`
+	Path     string      `json:"path"`
+	Format   string      `json:"format"`
+	Duration *float64    `json:"duration_s,omitempty"`
+	Stems    []muxedStem `json:"stems,omitempty"` // mux: what each channel/track holds
+}
+
+// muxedStem is where one stem sits in a -container single file: its
+// channels (from 1) of a WAV; in a Matroska file the stems are the tracks,
+// in this order
+type muxedStem struct {
+	Stem     string `json:"stem"`
+	Channels []int  `json:"channels,omitempty"`
+}
+
+func newManifest(c *cfg, in string, outs []stemOut) *manifest {
+	m := &manifest{Input: in, Engine: c.engine}
+	var got []string
+	for _, o := range outs {
+		got = append(got, o.name)
+		m.add("stem", o.name, o.path)
+	}
+	for _, s := range requestedStems(c) {
+		if !slices.Contains(got, s) {
+			m.Missing = append(m.Missing, s)
+		}
+	}
+	return m
+}
+
+func (m *manifest) add(kind, stem, path string) {
+	m.Outputs = append(m.Outputs, manifestEntry{Kind: kind, Stem: stem, Path: path, Format: strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")})
+}
+
+// drop stem entries whose files a -container single mux removed, and
+// record which part of out each stem became
+func (m *manifest) muxed(out string, layout []muxedStem) {
+	m.Outputs = slices.DeleteFunc(m.Outputs, func(e manifestEntry) bool { return e.Kind == "stem" })
+	m.add("mux", "", out)
+	m.Outputs[len(m.Outputs)-1].Stems = layout
+}
+
+// write probes audio durations (only now, so runs without -manifest don't
+// pay for it) and saves the manifest
+func (m *manifest) write(c *cfg, path string) error {
+	for i, e := range m.Outputs {
+		if e.Kind != "tags" {
+			m.Outputs[i].Duration = mediaDuration(c, e.Path)
+		}
+	}
+	buf, _ := json.MarshalIndent(m, "", "  ")
+	return writeFileAtomic(path, append(buf, '\n'))
+}
+
+var reChannels = regexp.MustCompile(`Audio: .*?, \d+ Hz, ([^,\n]+)`)
+
+// channel counts of the layouts ffmpeg names in its banner
+var layoutChannels = map[string]int{"mono": 1, "stereo": 2, "2.1": 3, "3.0": 3, "quad": 4, "4.0": 4, "5.0": 5, "5.1": 6, "6.1": 7, "7.1": 8}
+
+// mediaChannels is the channel count of path's first audio stream, from
+// ffmpeg's input banner
+func mediaChannels(c *cfg, path string) (int, error) {
+	out, _ := exec.Command(c.ffmpegBin, "-hide_banner", "-i", path).CombinedOutput()
+	return parseChannels(string(out))
+}
+
+func parseChannels(banner string) (int, error) {
+	mm := reChannels.FindStringSubmatch(banner)
+	if mm == nil {
+		return 0, fmt.Errorf("no audio stream in ffmpeg's banner")
+	}
+	layout := strings.TrimSpace(mm[1])
+	if n, ok := layoutChannels[strings.TrimSuffix(layout, "(side)")]; ok {
+		return n, nil
+	}
+	var n int
+	if _, err := fmt.Sscanf(layout, "%d channels", &n); err == nil && n > 0 {
+		return n, nil
+	}
+	return 0, fmt.Errorf("unknown channel layout %q", layout)
+}
+
+var (
+	reCommentMap = regexp.MustCompile(`(?m)^\s*comment\s*:\s*(\w+:\d+(?:-\d+)?(?:,\w+:\d+(?:-\d+)?)*)\s*$`)
+	reTrackTitle = regexp.MustCompile(`^\s*title\s*:\s*(\S+)\s*$`)
+)
+
+// muxLayout reads the stem map back from an earlier -container single file,
+// for runs that keep it
+func muxLayout(c *cfg, path string) []muxedStem {
+	out, _ := exec.Command(c.ffmpegBin, "-hide_banner", "-i", path).CombinedOutput()
+	return parseMuxLayout(string(out))
+}
+
+// parseMuxLayout takes the map from a WAV's comment tag ("bass:1-2,...")
+// or, failing that, the titles of the audio tracks in order
+func parseMuxLayout(banner string) []muxedStem {
+	var layout []muxedStem
+	if mm := reCommentMap.FindStringSubmatch(banner); mm != nil {
+		for _, part := range strings.Split(mm[1], ",") {
+			name, span, _ := strings.Cut(part, ":")
+			lo, hi, ranged := strings.Cut(span, "-")
+			first, _ := strconv.Atoi(lo)
+			last := first
+			if ranged {
+				last, _ = strconv.Atoi(hi)
+			}
+			l := muxedStem{Stem: name}
+			for ch := first; ch <= last; ch++ {
+				l.Channels = append(l.Channels, ch)
+			}
+			layout = append(layout, l)
+		}
+		return layout
+	}
+	audio := false
+	for _, line := range strings.Split(banner, "\n") {
+		if strings.Contains(line, "Stream #") {
+			audio = strings.Contains(line, "Audio:")
+			continue
+		}
+		if mm := reTrackTitle.FindStringSubmatch(line); mm != nil && audio {
+			layout = append(layout, muxedStem{Stem: mm[1]})
+		}
+	}
+	return layout
+}
+
+var reDuration = regexp.MustCompile(`Duration:\s*(\d+):(\d+):([\d\.]+)`)
+
+// duration from ffmpeg's input banner; nil if it can't be read
+func mediaDuration(c *cfg, path string) *float64 {
+	out, _ := exec.Command(c.ffmpegBin, "-hide_banner", "-i", path).CombinedOutput()
+	mm := reDuration.FindSubmatch(out)
+	if mm == nil {
+		return nil
+	}
+	h, _ := strconv.Atoi(string(mm[1]))
+	mi, _ := strconv.Atoi(string(mm[2]))
+	s, _ := strconv.ParseFloat(string(mm[3]), 64)
+	d := float64(h*3600+mi*60) + s
+	return &d
+}
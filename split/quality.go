@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// coarse regions a separated stem's energy is sorted into
+var qualityBands = []struct {
+	name   string
+	lo, hi float64
+}{
+	{"low", 20, 200},      // bass, kick
+	{"mid", 300, 3400},    // voice, most instruments
+	{"high", 5000, 16000}, // cymbals, air
+}
+
+// where each stem's energy belongs; energy elsewhere is counted as bleed
+var qualityExpect = map[string][]string{
+	"bass":   {"low"},
+	"drums":  {"low", "high"},
+	"vocal":  {"mid"},
+	"music":  {"mid", "high"},
+	"guitar": {"mid", "high"},
+	"piano":  {"mid", "high"},
+}
+
+// cross-stem bleed above these caps the grade at fair / poor
+const (
+	bleedFairDB = -20.0
+	bleedPoorDB = -10.0
+)
+
+// stemQuality is a rough separation score for one stem: the share of its
+// band energy that sits where that kind of stem should have it, and how
+// loud it is in another stem's band next to that stem
+type stemQuality struct {
+	Stem       string             `json:"stem"`
+	Shares     map[string]float64 `json:"shares"`               // low/mid/high, 0..1
+	Confidence float64            `json:"confidence"`           // expected-band share
+	BleedDB    *float64           `json:"bleed_db,omitempty"`   // worst band power relative to the stem that owns it
+	BleedFrom  string             `json:"bleed_from,omitempty"` // that stem
+	Grade      string             `json:"grade"`                // good|fair|poor
+}
+
+var reMeanVolume = regexp.MustCompile(`mean_volume:\s*(-?[\d\.]+|-inf) dB`)
+
+func bandMeanPower(c *cfg, path string, lo, hi float64) (float64, error) {
+	f := fmt.Sprintf("aformat=sample_fmts=flt,highpass=f=%g,lowpass=f=%g,volumedetect", lo, hi)
+	out, _ := exec.Command(c.ffmpegBin, "-hide_banner", "-nostats", "-i", path, "-vn", "-af", f, "-f", "null", "-").CombinedOutput()
+	m := reMeanVolume.FindSubmatch(out)
+	if m == nil {
+		return 0, fmt.Errorf("no volumedetect output for %s", path)
+	}
+	if string(m[1]) == "-inf" {
+		return 0, nil
+	}
+	db, _ := strconv.ParseFloat(string(m[1]), 64)
+	return math.Pow(10, db/10), nil
+}
+
+// crossBleed compares the stem's power in each band another stem expects
+// and it doesn't (the vocal band in the bass stem) with that stem's power
+// there, and returns the worst ratio in dB; ok is false when no band
+// applies
+func crossBleed(name string, power map[string]map[string]float64) (db float64, from string, ok bool) {
+	own := map[string]bool{}
+	for _, b := range qualityExpect[name] {
+		own[b] = true
+	}
+	worst := 0.0
+	for other, p := range power {
+		if other == name {
+			continue
+		}
+		for _, b := range qualityExpect[other] {
+			if own[b] || p[b] <= 0 {
+				continue
+			}
+			if r := power[name][b] / p[b]; !ok || r > worst {
+				worst, from, ok = r, other, true
+			}
+		}
+	}
+	if !ok {
+		return 0, "", false
+	}
+	return 10 * math.Log10(math.Max(worst, 1e-12)), from, true
+}
+
+// checkQuality scores each stem; a coarse heuristic (a bass line in the
+// vocal stem and a sung bass note look alike), meant to tell a clean
+// separation from a bad one, not to measure it
+func checkQuality(c *cfg, outs []stemOut) []stemQuality {
+	// band powers of every stem first: bleed compares across stems
+	power := map[string]map[string]float64{}
+	var order []string
+	for _, o := range outs {
+		if _, ok := qualityExpect[o.name]; !ok {
+			continue
+		}
+		p := map[string]float64{}
+		failed := false
+		for _, b := range qualityBands {
+			v, err := bandMeanPower(c, o.path, b.lo, b.hi)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[warn] quality %s: %v\n", o.path, err)
+				failed = true
+				break
+			}
+			p[b.name] = v
+		}
+		if !failed {
+			power[o.name] = p
+			order = append(order, o.name)
+		}
+	}
+	var qs []stemQuality
+	for _, name := range order {
+		q := stemQuality{Stem: name, Shares: map[string]float64{}}
+		var total float64
+		for k, p := range power[name] {
+			q.Shares[k] = p
+			total += p
+		}
+		if total == 0 {
+			continue
+		}
+		for k, p := range q.Shares {
+			q.Shares[k] = p / total
+		}
+		for _, k := range qualityExpect[name] {
+			q.Confidence += q.Shares[k]
+		}
+		switch {
+		case q.Confidence >= 0.8:
+			q.Grade = "good"
+		case q.Confidence >= 0.6:
+			q.Grade = "fair"
+		default:
+			q.Grade = "poor"
+		}
+		bleed := ""
+		if db, from, ok := crossBleed(name, power); ok {
+			q.BleedDB, q.BleedFrom = &db, from
+			switch {
+			case db > bleedPoorDB:
+				q.Grade = "poor"
+			case db > bleedFairDB && q.Grade == "good":
+				q.Grade = "fair"
+			}
+			bleed = fmt.Sprintf("  bleed %+.0f dB vs %s", db, from)
+		}
+		fmt.Printf("[+] quality %-6s %.2f (%s)  low %2.0f%%  mid %2.0f%%  high %2.0f%%%s\n",
+			q.Stem, q.Confidence, q.Grade, q.Shares["low"]*100, q.Shares["mid"]*100, q.Shares["high"]*100, bleed)
+		qs = append(qs, q)
+	}
+	for _, q := range qs {
+		if q.Grade == "poor" {
+			fmt.Fprintf(os.Stderr, "[warn] poor separation on %s; try another -demucs-model\n", q.Stem)
+		}
+	}
+	return qs
+}
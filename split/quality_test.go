@@ -0,0 +1,25 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// the vocal band in the bass stem is measured against the vocal stem
+func TestCrossBleed(t *testing.T) {
+	power := map[string]map[string]float64{
+		"bass":  {"low": 1, "mid": 0.01, "high": 0.001},
+		"vocal": {"low": 0.001, "mid": 1, "high": 0.01},
+	}
+	db, from, ok := crossBleed("bass", power)
+	if !ok || from != "vocal" || math.Abs(db+20) > 1e-9 {
+		t.Errorf("bass: %v dB from %q (ok %v); want -20 dB from vocal", db, from, ok)
+	}
+	db, from, ok = crossBleed("vocal", power)
+	if !ok || from != "bass" || math.Abs(db+30) > 1e-9 {
+		t.Errorf("vocal: %v dB from %q (ok %v); want -30 dB from bass", db, from, ok)
+	}
+	if _, _, ok := crossBleed("bass", map[string]map[string]float64{"bass": power["bass"]}); ok {
+		t.Error("a lone stem has no bleed to measure")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// stemTags is the sampler sidecar written next to each tagged stem
+type stemTags struct {
+	Stem    string   `json:"stem"`
+	File    string   `json:"file"`
+	BPM     *float64 `json:"bpm,omitempty"`
+	Key     *string  `json:"key,omitempty"`
+	Scale   *string  `json:"scale,omitempty"`
+	KeyConf *float64 `json:"key_confidence,omitempty"`
+}
+
+// subset of the analyzer's JSON report we read back
+type analizeReport struct {
+	Tempo *struct{ BPMMedian *float64 }
+	Key   *struct {
+		Key   *string
+		Scale *string
+		Conf  *float64
+	}
+}
+
+// tagStems runs the analyzer on each stem where it is meaningful (drums →
+// tempo, bass/music → key) and writes <stem>.json beside it. Failures are
+// warnings; an untagged stem is still a usable stem. Returns the sidecars
+// written.
+func tagStems(c *cfg, outs []stemOut) []stemOut {
+	if err := mustHave(c.analizeBin); err != nil {
+		fmt.Fprintf(os.Stderr, "[warn] analize not found; skipping stem tags\n")
+		return nil
+	}
+	var sides []stemOut
+	for _, o := range outs {
+		if o.name == "vocal" {
+			continue
+		}
+		rep, err := runAnalize(c, o.path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[warn] tag %s: %v\n", o.path, err)
+			continue
+		}
+		t := stemTags{Stem: o.name, File: filepath.Base(o.path)}
+		switch o.name {
+		case "drums":
+			if rep.Tempo != nil {
+				t.BPM = rep.Tempo.BPMMedian
+			}
+		default: // bass, music
+			if rep.Key != nil {
+				t.Key, t.Scale, t.KeyConf = rep.Key.Key, rep.Key.Scale, rep.Key.Conf
+			}
+		}
+		buf, _ := json.MarshalIndent(t, "", "  ")
+		side := baseNoExt(o.path) + ".json"
+		if err := writeFileAtomic(side, append(buf, '\n')); err != nil {
+			fmt.Fprintf(os.Stderr, "[warn] tag %s: %v\n", side, err)
+			continue
+		}
+		fmt.Printf("[+] wrote %s\n", side)
+		sides = append(sides, stemOut{o.name, side})
+	}
+	return sides
+}
+
+// analyzer pass with the slow unrelated sections turned off
+func runAnalize(c *cfg, in string) (*analizeReport, error) {
+	tmp, err := os.CreateTemp("", "split-tag-*.json")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	cmd := exec.Command(c.analizeBin, "-report", "json", "-o", tmp.Name(),
+		"-no-bands", "-no-ebur128", "-ffmpeg", c.ffmpegBin, "full", in)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%w\n%s", err, out)
+	}
+	buf, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	var rep analizeReport
+	if err := json.Unmarshal(buf, &rep); err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
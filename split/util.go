@@ -9,6 +9,12 @@ import (
 	"strings"
 )
 
+// stemOut is a rendered stem file
+type stemOut struct {
+	name string // bass|drums|music|vocal
+	path string
+}
+
 func mustHave(bin string) error {
 	_, err := exec.LookPath(bin)
 	return err
@@ -20,6 +26,70 @@ func baseNoExt(p string) string {
 	return filepath.Join(dir, name)
 }
 
+// writeFileAtomic writes via a temp file in the same directory + rename,
+// creating the directory first if needed
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr == nil {
+		werr = cerr
+	}
+	if werr == nil {
+		werr = os.Chmod(tmp.Name(), 0644)
+	}
+	if werr == nil {
+		werr = os.Rename(tmp.Name(), path)
+	}
+	if werr != nil {
+		os.Remove(tmp.Name())
+	}
+	return werr
+}
+
+// renderAtomic has render write a temp file next to out, with out's
+// extension so ffmpeg picks the same muxer, and renames it into place only
+// on success: an interrupted run never leaves a truncated out that
+// upToDate would take for a finished one
+func renderAtomic(out string, render func(tmp string) error) error {
+	ext := filepath.Ext(out)
+	tmp, err := os.CreateTemp(filepath.Dir(out), "."+strings.TrimSuffix(filepath.Base(out), ext)+".tmp*"+ext)
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	err = render(tmp.Name())
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), out)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// upToDate reports whether out exists, is non-empty and is not older than in
+func upToDate(out, in string) bool {
+	o, err := os.Stat(out)
+	if err != nil || o.Size() == 0 {
+		return false
+	}
+	i, err := os.Stat(in)
+	if err != nil {
+		return false
+	}
+	return !o.ModTime().Before(i.ModTime())
+}
+
 func findSingleChildDir(root string) (string, error) {
 	f, err := os.ReadDir(root)
 	if err != nil {